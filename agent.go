@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/agent"
+	"kpasscli/src/cmd"
+	"kpasscli/src/credential"
+	"kpasscli/src/debug"
+)
+
+// fetchFromAgent gets the unlocked database from a running agent. It is a variable to replace it in tests.
+var fetchFromAgent = agent.Fetch
+
+// agentStartTimeout is the time to wait for a started agent to answer
+const agentStartTimeout = 10 * time.Second
+
+// needsItem reports whether the command requires an item.
+//
+// Parameters:
+//   - command: The subcommand, empty for the flat flags.
+//
+// Returns:
+//   - bool: True if an item is required.
+func needsItem(command string) bool {
+	switch command {
+	case cmd.CommandLs, cmd.CommandTree, cmd.CommandGenerate, cmd.CommandAgent, cmd.CommandLock, cmd.CommandExec, cmd.CommandRender, cmd.CommandBatch, cmd.CommandGitCredential, cmd.CommandDockerCredential, cmd.CommandAnsibleVault, cmd.CommandLookup, cmd.CommandTfExternal, cmd.CommandExportNetrc:
+		return false
+	}
+	return true
+}
+
+// usesAgent reports whether the database is fetched from a running agent.
+// The agent itself and the commands, which change the database file, always open the database.
+//
+// Parameters:
+//   - flags: The parsed command-line flags (Command, Args, NoAgent).
+//
+// Returns:
+//   - bool: True if a running agent is queried.
+func usesAgent(flags *cmd.Flags) bool {
+	if flags.NoAgent {
+		return false
+	}
+	switch flags.Command {
+	case cmd.CommandAgent, cmd.CommandAdd, cmd.CommandSet, cmd.CommandRm:
+		return false
+	case cmd.CommandGitCredential:
+		// store and erase change the database
+		return len(flags.Args) > 0 && flags.Args[0] == credential.GitGet
+	case cmd.CommandDockerCredential:
+		return len(flags.Args) > 0 && (flags.Args[0] == credential.DockerGet || flags.Args[0] == credential.DockerList)
+	}
+	return true
+}
+
+// readAgentPassword reads the password passed on stdin by the agent command.
+//
+// Parameters:
+//   - r: The reader, typically os.Stdin.
+//
+// Returns:
+//   - string: The password without the trailing newline.
+//   - error: Any error encountered while reading.
+func readAgentPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// runAgent serves the unlocked database on the agent socket. Without -foreground and -agent-serve
+// a detached agent process is started, which receives the password on stdin.
+//
+// Parameters:
+//   - flags: The parsed command-line flags (AgentSocket, IdleTimeout, Foreground, AgentServe).
+//   - dbPath: Path to the KeePass database file.
+//   - password: The resolved password.
+//   - keyFile: The resolved key file, may be empty.
+//   - db: The opened database.
+//   - openDatabase: Function to reopen the database, if the file changed.
+//   - getEnv: Function to read environment variables.
+//
+// Returns:
+//   - error: Any error encountered while starting or serving the agent.
+func runAgent(
+	flags *cmd.Flags,
+	dbPath string,
+	password string,
+	keyFile string,
+	db *gokeepasslib.Database,
+	openDatabase func(string, string, string) (*gokeepasslib.Database, error),
+	getEnv func(string) string,
+) error {
+	socket := agent.SocketPath(flags.AgentSocket, getEnv)
+	absPath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return err
+	}
+	if keyFile != "" {
+		if keyFile, err = filepath.Abs(keyFile); err != nil {
+			return err
+		}
+	}
+
+	if !flags.Foreground && !flags.AgentServe {
+		return startAgent(flags, socket, absPath, password, keyFile)
+	}
+
+	server := &agent.Server{
+		Socket:      socket,
+		DBPath:      absPath,
+		IdleTimeout: time.Duration(flags.IdleTimeout) * time.Second,
+		Open:        func() (*gokeepasslib.Database, error) { return openDatabase(absPath, password, keyFile) },
+	}
+	if err := server.Listen(); err != nil {
+		return fmt.Errorf("Error starting agent: %w", err)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.Close()
+	}()
+	if flags.Foreground {
+		fmt.Fprintf(os.Stderr, "Agent serving %s on %s\n", absPath, socket)
+	}
+	return server.Serve(db)
+}
+
+// startAgent starts the agent as detached process and waits until it answers.
+//
+// Parameters:
+//   - flags: The parsed command-line flags (IdleTimeout, NoPassword, DebugFlag).
+//   - socket: The path of the agent socket.
+//   - dbPath: The absolute path of the database.
+//   - password: The resolved password, passed on stdin.
+//   - keyFile: The absolute path of the key file, may be empty.
+//
+// Returns:
+//   - error: An error if the agent could not be started.
+func startAgent(flags *cmd.Flags, socket, dbPath, password, keyFile string) error {
+	if _, err := agent.Status(socket); err == nil {
+		return fmt.Errorf("agent is already running on %s", socket)
+	}
+	args := []string{cmd.CommandAgent, "-agent-serve", "-kdbpath", dbPath, "-socket", socket,
+		"-idle-timeout", strconv.Itoa(flags.IdleTimeout)}
+	if keyFile != "" {
+		args = append(args, "-keyfile", keyFile)
+	}
+	if flags.NoPassword {
+		args = append(args, "-no-password")
+	}
+	if debug.Enabled() {
+		args = append(args, "-debug")
+	}
+	if _, err := cmd.StartDetached(args, strings.NewReader(password+"\n")); err != nil {
+		return fmt.Errorf("Error starting agent: %w", err)
+	}
+
+	deadline := time.Now().Add(agentStartTimeout)
+	for time.Now().Before(deadline) {
+		if _, err := agent.Status(socket); err == nil {
+			fmt.Fprintf(os.Stderr, "Agent started for %s on %s\n", dbPath, socket)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("agent did not start within %s", agentStartTimeout)
+}
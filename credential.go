@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/cmd"
+	"kpasscli/src/config"
+	"kpasscli/src/credential"
+	"kpasscli/src/debug"
+	"kpasscli/src/inject"
+	"kpasscli/src/keepass"
+	"kpasscli/src/search"
+)
+
+// newCredentialStore creates the credential store of the credential helpers.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - dbPath: Path to the KeePass database file, written after changes.
+//   - cfg: The loaded configuration (Backup).
+//   - flags: The parsed command-line flags (Backup).
+//   - finder: The finder, it must support the search by URL.
+//   - group: The group of new entries.
+//
+// Returns:
+//   - *credential.Store: The credential store.
+//   - error: An error if the finder does not support the search by URL.
+func newCredentialStore(
+	db *gokeepasslib.Database,
+	dbPath string,
+	cfg *config.Config,
+	flags *cmd.Flags,
+	finder search.FinderInterface,
+	group string,
+) (*credential.Store, error) {
+	urlFinder, ok := finder.(credential.URLFinder)
+	if !ok {
+		return nil, fmt.Errorf("the finder does not support the search by URL")
+	}
+	return &credential.Store{
+		DB:     db,
+		Finder: urlFinder,
+		Group:  group,
+		Save:   func() error { return keepass.SaveDatabase(db, dbPath, flags.Backup || cfg.Backup) },
+	}, nil
+}
+
+// gitCredential answers a request of git with the git credential helper protocol.
+// The action (get, store or erase) is the first argument, the request is read from stdin.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - dbPath: Path to the KeePass database file.
+//   - cfg: The loaded configuration (GitCredentialGroup, GitCredentialErase, Backup).
+//   - flags: The parsed command-line flags (Args, Backup).
+//   - finder: The finder used to search the entries by URL.
+//
+// Returns:
+//   - error: Any error encountered while answering the request.
+func gitCredential(
+	db *gokeepasslib.Database,
+	dbPath string,
+	cfg *config.Config,
+	flags *cmd.Flags,
+	finder search.FinderInterface,
+) error {
+	if len(flags.Args) == 0 {
+		return fmt.Errorf("no action given, expected get, store or erase")
+	}
+	group := cfg.GitCredentialGroup
+	if group == "" {
+		group = credential.DefaultGitGroup
+	}
+	store, err := newCredentialStore(db, dbPath, cfg, flags, finder, group)
+	if err != nil {
+		return err
+	}
+	store.Erase = cfg.GitCredentialErase
+	if err := credential.RunGit(flags.Args[0], os.Stdin, os.Stdout, store); err != nil {
+		return fmt.Errorf("Error in git credential helper: %w", err)
+	}
+	return nil
+}
+
+// dockerCredential answers a request of docker with the docker credential helper protocol.
+// The action (get, store, erase or list) is the first argument, the request is read from stdin.
+// Errors are also written to stdout, where docker expects them.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - dbPath: Path to the KeePass database file.
+//   - cfg: The loaded configuration (DockerCredentialGroup, Backup).
+//   - flags: The parsed command-line flags (Args, Backup).
+//   - finder: The finder used to search the entries by URL.
+//
+// Returns:
+//   - error: Any error encountered while answering the request.
+func dockerCredential(
+	db *gokeepasslib.Database,
+	dbPath string,
+	cfg *config.Config,
+	flags *cmd.Flags,
+	finder search.FinderInterface,
+) error {
+	if len(flags.Args) == 0 {
+		return fmt.Errorf("no action given, expected get, store, erase or list")
+	}
+	group := cfg.DockerCredentialGroup
+	if group == "" {
+		group = credential.DefaultDockerGroup
+	}
+	store, err := newCredentialStore(db, dbPath, cfg, flags, finder, group)
+	if err != nil {
+		return err
+	}
+	// erase is only called by docker logout
+	store.Erase = true
+	if err := credential.RunDocker(flags.Args[0], os.Stdin, os.Stdout, store); err != nil {
+		fmt.Fprintln(os.Stdout, err)
+		return fmt.Errorf("Error in docker credential helper: %w", err)
+	}
+	return nil
+}
+
+// k8sCredential outputs the kubernetes ExecCredential of the item for an exec credential plugin of kubectl.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - flags: The parsed command-line flags (Item, FieldName).
+//   - finder: The finder used to search the entry.
+//   - getEnv: Function to get environment variables (KUBERNETES_EXEC_INFO).
+//
+// Returns:
+//   - error: Any error encountered while creating the credential.
+func k8sCredential(
+	db *gokeepasslib.Database,
+	flags *cmd.Flags,
+	finder search.FinderInterface,
+	getEnv func(string) string,
+) error {
+	result, err := findSingle(flags.Item, finder, false)
+	if err != nil {
+		return err
+	}
+	apiVersion := credential.K8sAPIVersionFromEnv(getEnv("KUBERNETES_EXEC_INFO"))
+	cred, err := credential.NewExecCredential(db, result, flags.FieldName, apiVersion, time.Now())
+	if err != nil {
+		return fmt.Errorf("Error creating exec credential: %w", err)
+	}
+	return json.NewEncoder(os.Stdout).Encode(cred)
+}
+
+// awsCredentialProcess outputs the AWS credentials of the item for the credential_process of the AWS SDKs.
+// The names of the fields are taken from the config, the defaults are used for unset names.
+//
+// Parameters:
+//   - cfg: The loaded configuration (AWSAccessKeyIDField, AWSSecretAccessKeyField, AWSSessionTokenField).
+//   - flags: The parsed command-line flags (Item).
+//   - finder: The finder used to search the entry.
+//
+// Returns:
+//   - error: Any error encountered while creating the credentials.
+func awsCredentialProcess(cfg *config.Config, flags *cmd.Flags, finder search.FinderInterface) error {
+	result, err := findSingle(flags.Item, finder, false)
+	if err != nil {
+		return err
+	}
+	fields := credential.DefaultAWSFields()
+	for _, f := range []struct{ configured, field *string }{
+		{&cfg.AWSAccessKeyIDField, &fields.AccessKeyID},
+		{&cfg.AWSSecretAccessKeyField, &fields.SecretAccessKey},
+		{&cfg.AWSSessionTokenField, &fields.SessionToken},
+	} {
+		if *f.configured != "" {
+			*f.field = *f.configured
+		}
+	}
+	creds, err := credential.NewAWSCredentials(result, fields, time.Now())
+	if err != nil {
+		return fmt.Errorf("Error creating AWS credentials: %w", err)
+	}
+	return json.NewEncoder(os.Stdout).Encode(creds)
+}
+
+// ansibleVault outputs the vault password of the vault id for ansible, which runs kpasscli as vault
+// password client. As ansible expects, the exit status is 2, if there is no entry for the vault id.
+//
+// Parameters:
+//   - cfg: The loaded configuration (AnsibleVaultGroup).
+//   - flags: The parsed command-line flags (VaultID, FieldName).
+//   - finder: The finder used to search the entry.
+//
+// Returns:
+//   - error: An *inject.ExitError with status 2 if the vault id is unknown, or any other error encountered.
+func ansibleVault(cfg *config.Config, flags *cmd.Flags, finder search.FinderInterface) error {
+	group := cfg.AnsibleVaultGroup
+	if group == "" {
+		group = credential.DefaultAnsibleVaultGroup
+	}
+	item := credential.AnsibleVaultItem(group, flags.VaultID)
+	results, err := finder.Find(item)
+	if err != nil || len(results) == 0 {
+		debug.Log("Vault password not found: %v", err)
+		fmt.Fprintf(os.Stderr, "kpasscli: no vault password for vault id '%s' found in %s\n", flags.VaultID, item)
+		return &inject.ExitError{Code: 2}
+	}
+	if len(results) > 1 {
+		return fmt.Errorf("multiple vault passwords found for %s", item)
+	}
+	result := results[0]
+	password, err := result.GetField(flags.FieldName)
+	if err != nil {
+		return fmt.Errorf("Error getting field: %w", err)
+	}
+	_, err = fmt.Fprintln(os.Stdout, password)
+	return err
+}
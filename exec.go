@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"kpasscli/src/cmd"
+	"kpasscli/src/debug"
+	"kpasscli/src/inject"
+	"kpasscli/src/search"
+)
+
+// resolveEnv resolves the -env references of the exec command to environment variables.
+//
+// Parameters:
+//   - refs: The references NAME=item:field.
+//   - finder: The finder used to search the entries.
+//
+// Returns:
+//   - []string: The environment variables as NAME=value.
+//   - []string: The resolved secret values.
+//   - error: An error if a reference is invalid, or its entry or field is not found.
+func resolveEnv(refs []string, finder search.FinderInterface) ([]string, []string, error) {
+	env := make([]string, 0, len(refs))
+	secrets := make([]string, 0, len(refs))
+	for _, s := range refs {
+		ref, err := inject.ParseEnvRef(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		result, err := findSingle(ref.Item, finder, false)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", ref.Name, err)
+		}
+		value, err := result.GetField(ref.Field)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: Error getting field: %w", ref.Name, err)
+		}
+		debug.Log("Injecting %s from %s:%s", ref.Name, result.Path, ref.Field)
+		env = append(env, ref.Name+"="+value)
+		secrets = append(secrets, value)
+	}
+	return env, secrets, nil
+}
+
+// execCommand runs the command flags.Args with the -env references injected into its environment.
+//
+// Parameters:
+//   - flags: The parsed command-line flags (Args, Env, Mask).
+//   - finder: The finder used to search the entries.
+//
+// Returns:
+//   - error: An *inject.ExitError with the exit status of the command, or any error encountered
+//     while resolving the references or starting the command.
+func execCommand(flags *cmd.Flags, finder search.FinderInterface) error {
+	if len(flags.Args) == 0 {
+		return fmt.Errorf("no command given, expected exec [options] -- <command> [args ...]")
+	}
+	env, secrets, err := resolveEnv(flags.Env, finder)
+	if err != nil {
+		return fmt.Errorf("Error resolving environment: %w", err)
+	}
+	command := &inject.Command{
+		Args:   flags.Args,
+		Env:    append(os.Environ(), env...),
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+	if flags.Mask {
+		command.Secrets = secrets
+	}
+	if err := command.Run(); err != nil {
+		if _, ok := err.(*inject.ExitError); ok {
+			return err
+		}
+		return fmt.Errorf("Error running command: %w", err)
+	}
+	return nil
+}
@@ -18,9 +18,8 @@ import (
 
 func Init() *cmd.Flags {
 	log.SetFlags(log.LstdFlags)
-	flags := cmd.ParseFlagsDefault()
 	flag.Usage = doc.ShowHelp
-	flag.Parse()
+	flags := cmd.ParseFlagsDefault()
 
 	// switch toggles
 	if flags.DebugFlag {
@@ -34,6 +33,30 @@ func Init() *cmd.Flags {
 		flags.Out = "clipboard"
 	}
 
+	// Handle the commands, which do not need the database
+	switch flags.Command {
+	case cmd.CommandHelp:
+		if len(flags.Args) > 0 {
+			doc.ShowCommandHelp(flags.Args[0])
+		} else {
+			doc.ShowHelp()
+		}
+		os.Exit(0)
+	case cmd.CommandConfig:
+		action := "print"
+		if len(flags.Args) > 0 {
+			action = flags.Args[0]
+		}
+		switch action {
+		case "print":
+			flags.PrintConfig = true
+		case "create":
+			flags.CreateConfig = true
+		default:
+			debug.ErrMsg(fmt.Errorf("unknown config action '%s', use print or create", action), "kpasscli config")
+		}
+	}
+
 	// Handle special flags and help messages
 	if flags.CreateConfig {
 		filename := "config.yaml"
@@ -52,13 +75,20 @@ func Init() *cmd.Flags {
 			os.Exit(1)
 		}
 		cfg.Print()
+		if flags.Command == cmd.CommandConfig {
+			os.Exit(0)
+		}
 	}
 	if flags.ShowMan {
 		doc.ShowMan()
 		os.Exit(0)
 	}
 	if flags.ShowHelp {
-		doc.ShowHelp()
+		if flags.Command != "" {
+			doc.ShowCommandHelp(flags.Command)
+		} else {
+			doc.ShowHelp()
+		}
 		os.Exit(0)
 	}
 	return flags
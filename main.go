@@ -1,31 +1,45 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 
 	"time"
 
 	"github.com/tobischo/gokeepasslib/v3"
 	"golang.design/x/clipboard"
 
+	"kpasscli/src/agent"
+	"kpasscli/src/batch"
 	"kpasscli/src/cmd"
 	"kpasscli/src/config"
 	"kpasscli/src/debug"
+	"kpasscli/src/generate"
+	"kpasscli/src/inject"
 	"kpasscli/src/keepass"
 	"kpasscli/src/output"
+	"kpasscli/src/picker"
 	"kpasscli/src/search"
 )
 
+// selectResult lets the user choose one of the paths in the terminal. It is a variable to replace it in tests.
+var selectResult = picker.SelectTerminal
+
+// isTerminal reports whether the picker can be shown. It is a variable to replace it in tests.
+var isTerminal = picker.IsTerminal
+
 // RunApp contains the main application logic and is testable.
 func RunApp(
 	flags *cmd.Flags,
 	loadConfig func(string) (*config.Config, error),
 	resolveDBPath func(string, *config.Config) string,
 	resolvePassword func(string, *config.Config, string, ...keepass.PasswordPromptFunc) (string, error),
-	openDatabase func(string, string) (*gokeepasslib.Database, error),
+	openDatabase func(string, string, string) (*gokeepasslib.Database, error),
 	newFinder func(*gokeepasslib.Database) search.FinderInterface,
 	newHandler func(output.OutputType, output.ClipboardService) output.Handler,
 	clipboardService output.ClipboardService,
@@ -47,10 +61,24 @@ func RunApp(
 
 	debug.Log("Starting kpasscli with item: %s", flags.Item)
 
-	if flags.Item == "" {
+	if flags.Item == "" && len(flags.Where) == 0 && needsItem(flags.Command) {
 		return fmt.Errorf("item parameter is required")
 	}
 
+	if flags.Command == cmd.CommandLock {
+		if err := agent.Lock(agent.SocketPath(flags.AgentSocket, getEnv)); err != nil {
+			return fmt.Errorf("Error locking agent: %w", err)
+		}
+		return nil
+	}
+
+	if flags.Command == cmd.CommandGenerate {
+		// the generator does not need a config file, it is only used for the output type
+		config, _ := loadConfig(flags.ConfigPath)
+		outputType := output.ResolveOutputType(flags.Out, config)
+		return generateSecret(flags, newHandler(outputType, clipboardService), outputType)
+	}
+
 	config, err := loadConfig(flags.ConfigPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: Could not load config file: %v\n", err)
@@ -65,73 +93,197 @@ func RunApp(
 		return fmt.Errorf("no KeePass database path provided")
 	}
 
-	kdbpasswordenv := getEnv("KPASSCLI_kdbpassword")
-	password, err := resolvePassword(flags.KdbPassword, config, kdbpasswordenv)
-	if err != nil {
-		return fmt.Errorf("Error getting password: %w", err)
+	keyFile := keepass.ResolveKeyFile(flags.KeyFile, config, getEnv("KPASSCLI_KEYFILE"))
+	debug.Log("Resolved key file: %s", keyFile)
+
+	var db *gokeepasslib.Database
+	if usesAgent(flags) {
+		db, err = fetchFromAgent(agent.SocketPath(flags.AgentSocket, getEnv), dbPath)
+		if err != nil {
+			debug.Log("Not using agent: %v", err)
+			db = nil
+		}
 	}
 
-	db, err := openDatabase(dbPath, password)
-	if err != nil {
-		return fmt.Errorf("Error opening database: %w", err)
+	password := ""
+	if db == nil {
+		if flags.NoPassword {
+			if keyFile == "" {
+				return fmt.Errorf("no key file provided, -no-password requires a key file")
+			}
+		} else if flags.AgentServe {
+			// the agent command passes the password on stdin
+			password, err = readAgentPassword(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("Error getting password: %w", err)
+			}
+		} else {
+			kdbpasswordenv := getEnv("KPASSCLI_kdbpassword")
+			password, err = resolvePassword(flags.KdbPassword, config, kdbpasswordenv)
+			if err != nil {
+				return fmt.Errorf("Error getting password: %w", err)
+			}
+		}
+
+		db, err = openDatabase(dbPath, password, keyFile)
+		if err != nil {
+			return fmt.Errorf("Error opening database: %w", err)
+		}
 	}
 
+	if flags.Command == cmd.CommandAgent {
+		return runAgent(flags, dbPath, password, keyFile, db, openDatabase, getEnv)
+	}
+
+	// the picker is only shown by the commands used by a person, not by render, exec and the credential helpers
+	interactive := !flags.NoInteractive && isTerminal()
+
 	finder := newFinder(db)
 	// If the Finder supports Options, set them (for real Finder)
 	if f, ok := finder.(*search.Finder); ok {
 		f.Options = search.SearchOptions{
 			CaseSensitive: flags.CaseSensitive,
 			ExactMatch:    flags.ExactMatch,
+			Regex:         flags.Regex,
+			Glob:          flags.Glob,
+			Where:         flags.Where,
+			Fuzzy:         flags.Fuzzy,
+			First:         flags.First,
+			Raw:           flags.Raw,
 		}
 	}
-	results, err := finder.Find(flags.Item)
-	if err != nil {
-		return fmt.Errorf("Error searching for item: %w", err)
-	}
 
-	if len(results) == 0 {
-		return fmt.Errorf("no items found")
+	outputType := output.ResolveOutputType(flags.Out, config)
+	handler := newHandler(outputType, clipboardService)
+
+	switch flags.Command {
+	case cmd.CommandLs, cmd.CommandTree:
+		return listGroup(db, flags, handler)
+	case cmd.CommandSearch:
+		return searchPaths(flags.Item, flags.PrintUUID, finder, handler)
+	case cmd.CommandAdd, cmd.CommandSet, cmd.CommandRm:
+		return modifyDatabase(db, dbPath, config, flags, finder, interactive)
+	case cmd.CommandExec:
+		return execCommand(flags, finder)
+	case cmd.CommandGitCredential:
+		return gitCredential(db, dbPath, config, flags, finder)
+	case cmd.CommandDockerCredential:
+		return dockerCredential(db, dbPath, config, flags, finder)
+	case cmd.CommandK8sCredential:
+		return k8sCredential(db, flags, finder, getEnv)
+	case cmd.CommandAWSCredentialProcess:
+		return awsCredentialProcess(config, flags, finder)
+	case cmd.CommandAnsibleVault:
+		return ansibleVault(config, flags, finder)
+	case cmd.CommandLookup:
+		if err := batch.Lookup(os.Stdin, os.Stdout, finder); err != nil {
+			return fmt.Errorf("Error in lookup: %w", err)
+		}
+		return nil
+	case cmd.CommandTfExternal:
+		if err := batch.External(os.Stdin, os.Stdout, finder); err != nil {
+			return fmt.Errorf("Error in terraform external data source: %w", err)
+		}
+		return nil
+	case cmd.CommandBatch:
+		failed, err := batch.Run(os.Stdin, os.Stdout, os.Stderr, finder)
+		if err != nil {
+			return fmt.Errorf("Error reading requests: %w", err)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d requests failed", failed)
+		}
+		return nil
+	case cmd.CommandExportNetrc:
+		if err := exportNetrc(db, config, flags, handler); err != nil {
+			return err
+		}
+		if len(flags.Args) == 0 {
+			startClipboardClearer(outputType, flags.ClearAfter)
+		}
+		return nil
+	case cmd.CommandRender:
+		if err := renderTemplate(db, flags, finder, handler); err != nil {
+			return err
+		}
+		startClipboardClearer(outputType, flags.ClearAfter)
+		return nil
 	}
 
-	if len(results) > 1 {
-		for _, result := range results {
-			fmt.Fprintf(os.Stderr, "- %s\n", result.Path)
-			debug.Log("Found item: %s", result.Path)
+	if flags.ShowAll {
+		if err := showAllFields(db, config, flags, finder, handler); err != nil {
+			return err
 		}
-		return fmt.Errorf("multiple items found")
+		startClipboardClearer(outputType, flags.ClearAfter)
+		return nil
 	}
 
-	outputType := output.ResolveOutputType(flags.Out, config)
-	handler := newHandler(outputType, clipboardService)
+	result, err := findSingle(flags.Item, finder, interactive)
+	if err != nil {
+		return err
+	}
 
-	var value string
-	// var token string
-	if flags.TotpFlag {
-		// totpSecret, err := results[0].GetField("otp")
-		// println("totpSecret:", totpSecret)
-		// if err != nil {
-		// 	return fmt.Errorf("TOTP secret not found: %w", err)
-		// }
-		// token, err := totp.GenerateCode(totpSecret, time.Now())
-		// if err != nil {
-		// 	return fmt.Errorf("Error generating TOTP token: %w", err)
-		// }
-		value, err = results[0].GetTotpToken("otp")
+	format := output.ResolveOutputFormat(flags.Format, config)
+	if !output.IsValidFormat(format) {
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+
+	var fields []output.Field
+	if flags.PrintUUID {
+		fields = []output.Field{{Name: "UUID", Value: search.FormatUUID(result.UUID)}}
+	} else if flags.TotpFlag {
+		totpCfg, err := result.TotpConfig("otp")
 		if err != nil {
 			return fmt.Errorf("Error getting field: %w", err)
 		}
+		token, expires, err := totpCfg.ValidCode(time.Duration(flags.TotpMinValidity) * time.Second)
+		if err != nil {
+			return fmt.Errorf("Error generating TOTP token: %w", err)
+		}
+		fields = []output.Field{{Name: "TOTP", Value: token}}
+		if totpCfg.Issuer != "" && format != output.FormatText {
+			fields = append(fields, output.Field{Name: "Issuer", Value: totpCfg.Issuer})
+		}
+		if flags.TotpExpiry {
+			fields = append(fields, totpExpiryFields(expires)...)
+		}
 	} else {
-		value, err = results[0].GetField(flags.FieldName)
+		fields, err = selectFields(result, flags.FieldName)
 		if err != nil {
 			return fmt.Errorf("Error getting field: %w", err)
 		}
 
 		if flags.PasswordTotp {
-			token, err := results[0].GetTotpToken("otp")
+			token, expires, err := result.GetTotpTokenValid("otp", time.Duration(flags.TotpMinValidity)*time.Second)
 			if err != nil {
 				return fmt.Errorf("Error generating TOTP token: %w", err)
 			}
-			value = value + token
+			appended := false
+			for i := range fields {
+				if fields[i].Name == "Password" || len(fields) == 1 {
+					fields[i].Value += token
+					appended = true
+				}
+			}
+			if !appended {
+				return fmt.Errorf("-password-totp requires the Password field")
+			}
+			if flags.TotpExpiry {
+				fields = append(fields, totpExpiryFields(expires)...)
+			}
+		}
+	}
+
+	// A single field in text format is output as plain value
+	value := fields[0].Value
+	if len(fields) > 1 || format != output.FormatText {
+		var buf bytes.Buffer
+		if err := output.WriteFields(&buf, fields, format); err != nil {
+			return fmt.Errorf("Error outputting value: %w", err)
+		}
+		value = buf.String()
+		if format != output.FormatRaw {
+			value = strings.TrimSuffix(value, "\n")
 		}
 	}
 
@@ -140,26 +292,390 @@ func RunApp(
 		return fmt.Errorf("Error outputting value: %w", err)
 	}
 
-	// If output is clipboard and ClearAfter is set, spawn background process
-	if outputType == output.ClipboardType && flags.ClearAfter > 0 {
-		exe, err := os.Executable()
+	startClipboardClearer(outputType, flags.ClearAfter)
+
+	return nil
+}
+
+// totpExpiryFields returns the validity of a TOTP token as output fields.
+//
+// Parameters:
+//   - expires: The time the token expires.
+//
+// Returns:
+//   - []output.Field: Remaining, the seconds the token stays valid, and Expires, the time in RFC 3339 format.
+func totpExpiryFields(expires time.Time) []output.Field {
+	remaining := expires.Unix() - time.Now().Unix()
+	if remaining < 0 {
+		remaining = 0
+	}
+	return []output.Field{
+		{Name: "Remaining", Value: strconv.FormatInt(remaining, 10)},
+		{Name: "Expires", Value: expires.Format(time.RFC3339)},
+	}
+}
+
+// selectFields returns the fields of the entry named in spec, a comma-separated list
+// of field names or "all" for all fields of the entry. The names are matched case-insensitively
+// and returned with the spelling of the entry.
+//
+// Parameters:
+//   - result: The found entry.
+//   - spec: The field list from the -fieldname flag.
+//
+// Returns:
+//   - []output.Field: The fields in the requested order.
+//   - error: An error if a field does not exist.
+func selectFields(result search.Result, spec string) ([]output.Field, error) {
+	if strings.EqualFold(strings.TrimSpace(spec), "all") {
+		fields := make([]output.Field, 0, len(result.Entry.Values))
+		for _, v := range result.Entry.Values {
+			fields = append(fields, output.Field{Name: v.Key, Value: v.Value.Content})
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("entry has no fields")
+		}
+		return fields, nil
+	}
+
+	var fields []output.Field
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, v := range result.Entry.Values {
+			if strings.EqualFold(v.Key, name) {
+				fields = append(fields, output.Field{Name: v.Key, Value: v.Value.Content})
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("field '%s' not found", name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no field name given")
+	}
+	return fields, nil
+}
+
+// newSecret generates a password or, if flags.Words is set, a diceware passphrase.
+//
+// Parameters:
+//   - flags: The parsed command-line flags with the generator rules.
+//
+// Returns:
+//   - string: The generated secret.
+//   - error: An error if the rules are invalid.
+func newSecret(flags *cmd.Flags) (string, error) {
+	if flags.Words > 0 {
+		return generate.Passphrase(flags.Words, flags.Separator)
+	}
+	return generate.Password(generate.Options{
+		Length:  flags.Length,
+		Classes: flags.Classes,
+		MinCounts: map[string]int{
+			generate.ClassLower:   flags.MinLower,
+			generate.ClassUpper:   flags.MinUpper,
+			generate.ClassDigits:  flags.MinDigits,
+			generate.ClassSymbols: flags.MinSymbols,
+		},
+		ExcludeSimilar: flags.ExcludeSimilar,
+	})
+}
+
+// generateSecret generates a password or passphrase and passes it to the output handler.
+//
+// Parameters:
+//   - flags: The parsed command-line flags with the generator rules.
+//   - handler: The output handler for the generated secret.
+//   - outputType: The resolved output type, used to clear the clipboard.
+//
+// Returns:
+//   - error: Any error encountered while generating or outputting.
+func generateSecret(flags *cmd.Flags, handler output.Handler, outputType output.OutputType) error {
+	secret, err := newSecret(flags)
+	if err != nil {
+		return fmt.Errorf("Error generating password: %w", err)
+	}
+	if err := handler.Output(secret); err != nil {
+		return fmt.Errorf("Error outputting value: %w", err)
+	}
+	startClipboardClearer(outputType, flags.ClearAfter)
+	return nil
+}
+
+// findSingle searches the item and returns the result, if exactly one entry is found.
+// If multiple entries are found, the user chooses one in interactive mode, otherwise their paths
+// are printed to stderr.
+//
+// Parameters:
+//   - item: The item to search for.
+//   - finder: The finder used to search the entry.
+//   - interactive: True to let the user choose one of multiple entries, false to fail.
+//
+// Returns:
+//   - search.Result: The found entry.
+//   - error: An error if the search fails, no or multiple entries are found or the selection is cancelled.
+func findSingle(item string, finder search.FinderInterface, interactive bool) (search.Result, error) {
+	results, err := finder.Find(item)
+	if err != nil {
+		return search.Result{}, fmt.Errorf("Error searching for item: %w", err)
+	}
+
+	if len(results) == 0 {
+		return search.Result{}, fmt.Errorf("no items found")
+	}
+
+	if len(results) > 1 && interactive {
+		paths := make([]string, 0, len(results))
+		for _, result := range results {
+			paths = append(paths, result.Path)
+		}
+		i, err := selectResult(paths)
 		if err != nil {
-			debug.Log("Failed to get executable path: %v", err)
-		} else {
-			debug.Log("Spawning background process to clear clipboard after %d seconds", flags.ClearAfter)
-			cmd := exec.Command(exe, "--clear-clipboard", "-ca", strconv.Itoa(flags.ClearAfter))
-			if err := cmd.Start(); err != nil {
-				debug.Log("Failed to start background process: %v", err)
-			} else {
-				// Detach process
-				cmd.Process.Release()
+			return search.Result{}, fmt.Errorf("Error selecting item: %w", err)
+		}
+		debug.Log("Selected item: %s", results[i].Path)
+		return results[i], nil
+	}
+
+	if len(results) > 1 {
+		for _, result := range results {
+			fmt.Fprintf(os.Stderr, "- %s\n", result.Path)
+			debug.Log("Found item: %s", result.Path)
+		}
+		return search.Result{}, fmt.Errorf("multiple items found")
+	}
+	return results[0], nil
+}
+
+// modifyDatabase adds (add), changes (set) or deletes (rm) the entry flags.Item
+// and saves the database.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - dbPath: Path to the KeePass database file.
+//   - cfg: The loaded configuration (Backup).
+//   - flags: The parsed command-line flags (Command, Item, Args, Backup, VerifyFlag).
+//   - finder: The finder used to search the entry for set and rm.
+//   - interactive: True to let the user choose one of multiple entries for set and rm.
+//
+// Returns:
+//   - error: Any error encountered while changing or saving the database.
+func modifyDatabase(
+	db *gokeepasslib.Database,
+	dbPath string,
+	cfg *config.Config,
+	flags *cmd.Flags,
+	finder search.FinderInterface,
+	interactive bool,
+) error {
+	values, err := keepass.ParseFieldValues(flags.Args)
+	if err != nil {
+		return err
+	}
+	if flags.Generate {
+		for _, v := range values {
+			if v.Key == "Password" {
+				return fmt.Errorf("-generate can not be used together with Password=")
 			}
 		}
+		password, err := newSecret(flags)
+		if err != nil {
+			return fmt.Errorf("Error generating password: %w", err)
+		}
+		values = append(values, keepass.FieldValue{Key: "Password", Value: password})
+	}
+
+	var message string
+	switch flags.Command {
+	case cmd.CommandAdd:
+		if _, err := keepass.AddEntry(db, flags.Item, values); err != nil {
+			return fmt.Errorf("Error adding item: %w", err)
+		}
+		message = "Added: " + flags.Item
+	case cmd.CommandSet:
+		if len(values) == 0 {
+			return fmt.Errorf("no field assignments given, expected Field=Value")
+		}
+		result, err := findSingle(flags.Item, finder, interactive)
+		if err != nil {
+			return err
+		}
+		group, index, err := keepass.LocateEntry(db, result.Entry.UUID)
+		if err != nil {
+			return fmt.Errorf("Error changing item: %w", err)
+		}
+		keepass.SetFields(db, &group.Entries[index], values)
+		message = "Changed: " + result.Path
+	case cmd.CommandRm:
+		if len(values) > 0 {
+			return fmt.Errorf("rm does not accept field assignments")
+		}
+		result, err := findSingle(flags.Item, finder, interactive)
+		if err != nil {
+			return err
+		}
+		permanent, err := keepass.DeleteEntry(db, result.Entry.UUID)
+		if err != nil {
+			return fmt.Errorf("Error deleting item: %w", err)
+		}
+		message = "Moved to recycle bin: " + result.Path
+		if permanent {
+			message = "Deleted: " + result.Path
+		}
+	}
+
+	if err := keepass.SaveDatabase(db, dbPath, flags.Backup || cfg.Backup); err != nil {
+		return fmt.Errorf("Error saving database: %w", err)
+	}
+	if flags.VerifyFlag {
+		fmt.Fprintln(os.Stderr, message)
+	}
+	debug.Log("%s", message)
+	return nil
+}
+
+// showAllFields renders all fields of the entry found for flags.Item in the resolved
+// output format and passes the result to the output handler.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - cfg: The loaded configuration.
+//   - flags: The parsed command-line flags (Item, Format, Reveal).
+//   - finder: The finder used to search the entry.
+//   - handler: The output handler for the rendered entry.
+//
+// Returns:
+//   - error: Any error encountered while searching, rendering or outputting.
+func showAllFields(
+	db *gokeepasslib.Database,
+	cfg *config.Config,
+	flags *cmd.Flags,
+	finder search.FinderInterface,
+	handler output.Handler,
+) error {
+	format := output.ResolveOutputFormat(flags.Format, cfg)
+	if !output.IsValidFormat(format) {
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+	if !output.SupportsShowAll(format) {
+		return fmt.Errorf("output format %s is not supported by -show-all, use text, json or yaml", format)
 	}
+	showConfig := *cfg
+	showConfig.OutputFormat = format
 
+	var buf bytes.Buffer
+	var writeErr error
+	err := keepass.GetAllFieldsWithFinder(db, &showConfig, flags.Item, finder, func(entry *gokeepasslib.Entry, c config.Config) {
+		writeErr = output.WriteAllFields(&buf, entry, c, flags.Reveal)
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Error showing item: %w", err)
+	}
+	if err := handler.Output(strings.TrimSuffix(buf.String(), "\n")); err != nil {
+		return fmt.Errorf("Error outputting value: %w", err)
+	}
 	return nil
 }
 
+// listGroup outputs the groups and entries of the group flags.Item,
+// as flat list for the ls command or as tree for the tree command.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - flags: The parsed command-line flags (Command, Item).
+//   - handler: The output handler for the list.
+//
+// Returns:
+//   - error: Any error encountered while looking up the group or outputting.
+func listGroup(db *gokeepasslib.Database, flags *cmd.Flags, handler output.Handler) error {
+	group, groupPath, err := search.FindGroup(db, flags.Item)
+	if err != nil {
+		return fmt.Errorf("Error listing group: %w", err)
+	}
+	debug.Log("Listing group: %s", groupPath)
+
+	var buf bytes.Buffer
+	if flags.Command == cmd.CommandTree {
+		err = output.WriteGroupTree(&buf, group)
+	} else {
+		err = output.WriteGroupList(&buf, group)
+	}
+	if err != nil {
+		return fmt.Errorf("Error listing group: %w", err)
+	}
+	if err := handler.Output(strings.TrimSuffix(buf.String(), "\n")); err != nil {
+		return fmt.Errorf("Error outputting value: %w", err)
+	}
+	return nil
+}
+
+// searchPaths outputs the paths of all entries matching the query, one per line.
+// With printUUID each line starts with the UUID of the entry and a tab.
+//
+// Parameters:
+//   - query: The search query.
+//   - printUUID: True to output the UUIDs with the paths.
+//   - finder: The finder used to search the entries.
+//   - handler: The output handler for the paths.
+//
+// Returns:
+//   - error: Any error encountered while searching or outputting, or if nothing was found.
+func searchPaths(query string, printUUID bool, finder search.FinderInterface, handler output.Handler) error {
+	results, err := finder.Find(query)
+	if err != nil {
+		return fmt.Errorf("Error searching for item: %w", err)
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no items found")
+	}
+	paths := make([]string, 0, len(results))
+	for _, result := range results {
+		if printUUID {
+			paths = append(paths, search.FormatUUID(result.UUID)+"\t"+result.Path)
+		} else {
+			paths = append(paths, result.Path)
+		}
+	}
+	if err := handler.Output(strings.Join(paths, "\n")); err != nil {
+		return fmt.Errorf("Error outputting value: %w", err)
+	}
+	return nil
+}
+
+// startClipboardClearer spawns a background process, which clears the clipboard
+// after clearAfter seconds, if the output type is clipboard.
+//
+// Parameters:
+//   - outputType: The resolved output type.
+//   - clearAfter: The delay in seconds, 0 disables clearing.
+func startClipboardClearer(outputType output.OutputType, clearAfter int) {
+	if outputType != output.ClipboardType || clearAfter <= 0 {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		debug.Log("Failed to get executable path: %v", err)
+		return
+	}
+	debug.Log("Spawning background process to clear clipboard after %d seconds", clearAfter)
+	cmd := exec.Command(exe, "--clear-clipboard", "-ca", strconv.Itoa(clearAfter))
+	if err := cmd.Start(); err != nil {
+		debug.Log("Failed to start background process: %v", err)
+		return
+	}
+	// Detach process
+	cmd.Process.Release()
+}
+
 func main() {
 	flags := Init()
 	err := RunApp(
@@ -175,6 +691,11 @@ func main() {
 		&output.RealClipboard{},
 		os.Getenv,
 	)
+	var exitErr *inject.ExitError
+	if errors.As(err, &exitErr) {
+		// the exit status of the command run by exec
+		os.Exit(exitErr.Code)
+	}
 	if err != nil {
 		debug.ErrMsg(err, "kpasscli")
 		os.Exit(1)
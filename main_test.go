@@ -1,20 +1,34 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tobischo/gokeepasslib/v3"
 	"golang.design/x/clipboard"
 
 	"kpasscli/src/cmd"
 	"kpasscli/src/config"
+	"kpasscli/src/inject"
 	"kpasscli/src/keepass"
 	"kpasscli/src/output"
 	"kpasscli/src/search"
 )
 
+// TestMain disables the interactive picker, the tests must not wait for input from a terminal.
+func TestMain(m *testing.M) {
+	isTerminal = func() bool { return false }
+	os.Exit(m.Run())
+}
+
 // MockClipboard implements ClipboardService for testing
 type MockClipboard struct {
 	InitFunc  func() error
@@ -127,8 +141,8 @@ func fakeResolvePassword(pw string, err error) func(string, *config.Config, stri
 	}
 }
 
-func fakeOpenDatabase(db *gokeepasslib.Database, err error) func(string, string) (*gokeepasslib.Database, error) {
-	return func(string, string) (*gokeepasslib.Database, error) {
+func fakeOpenDatabase(db *gokeepasslib.Database, err error) func(string, string, string) (*gokeepasslib.Database, error) {
+	return func(string, string, string) (*gokeepasslib.Database, error) {
 		return db, err
 	}
 }
@@ -504,13 +518,12 @@ func TestRunApp_WithTOTP_MissingSecret(t *testing.T) {
 		func(string) string { return "" },
 	)
 
-	if err != nil {
-		t.Errorf("expected success, got %v", err)
+	// An incomplete credential must not be output
+	if err == nil || !strings.HasPrefix(err.Error(), "Error generating TOTP token:") || !errors.Is(err, search.ErrNoTotp) {
+		t.Errorf("expected missing TOTP secret error, got %v", err)
 	}
-
-	// Verify that the output is just the password
-	if mockHandler.captured != "secret" {
-		t.Errorf("expected output to be 'secret', got %s", mockHandler.captured)
+	if mockHandler.captured != "" {
+		t.Errorf("expected no output, got %s", mockHandler.captured)
 	}
 }
 
@@ -594,3 +607,1107 @@ func TestRunApp_TotpFlag(t *testing.T) {
 		}
 	}
 }
+
+func TestRunApp_TotpFlag_OtpauthFormat(t *testing.T) {
+	flags := &cmd.Flags{Item: "foo", TotpFlag: true, Format: "json"}
+	fakeResults := []search.Result{
+		{
+			Path: "entry1",
+			Entry: &gokeepasslib.Entry{
+				Values: []gokeepasslib.ValueData{
+					{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://totp/ACME:bob?secret=JBSWY3DPEHPK3PXP&digits=8&algorithm=SHA256"}},
+				},
+			},
+		},
+	}
+	mockHandler := &fakeHandler{}
+
+	err := RunApp(
+		flags,
+		fakeLoadConfig(nil),
+		fakeResolveDBPath("db"),
+		fakeResolvePassword("pw", nil),
+		fakeOpenDatabase(nil, nil),
+		func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{results: fakeResults} },
+		func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+		&MockClipboard{},
+		func(string) string { return "" },
+	)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(mockHandler.captured), &got); err != nil {
+		t.Fatalf("expected JSON, got %s", mockHandler.captured)
+	}
+	if len(got["TOTP"]) != 8 || got["Issuer"] != "ACME" {
+		t.Errorf("expected 8 digit token and issuer ACME, got %v", got)
+	}
+}
+
+func TestRunApp_TotpExpiry(t *testing.T) {
+	flags := &cmd.Flags{Item: "foo", TotpFlag: true, TotpExpiry: true, TotpMinValidity: 2, Format: "json"}
+	fakeResults := []search.Result{
+		{
+			Path: "entry1",
+			Entry: &gokeepasslib.Entry{
+				Values: []gokeepasslib.ValueData{
+					{Key: "TimeOtp-Secret-Base32", Value: gokeepasslib.V{Content: "JBSWY3DPEHPK3PXP"}},
+				},
+			},
+		},
+	}
+	mockHandler := &fakeHandler{}
+
+	err := RunApp(
+		flags,
+		fakeLoadConfig(nil),
+		fakeResolveDBPath("db"),
+		fakeResolvePassword("pw", nil),
+		fakeOpenDatabase(nil, nil),
+		func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{results: fakeResults} },
+		func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+		&MockClipboard{},
+		func(string) string { return "" },
+	)
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(mockHandler.captured), &got); err != nil {
+		t.Fatalf("expected JSON, got %s", mockHandler.captured)
+	}
+	remaining, err := strconv.Atoi(got["Remaining"])
+	if len(got["TOTP"]) != 6 || err != nil || remaining < 1 || remaining > 30 {
+		t.Errorf("expected token and remaining seconds, got %v", got)
+	}
+	if expires, err := time.Parse(time.RFC3339, got["Expires"]); err != nil || expires.Unix()%30 != 0 {
+		t.Errorf("expected expiry at the end of a period, got %v", got)
+	}
+}
+
+// writeKeyFileDatabase creates a fixture database with the entry "/Root/Account",
+// protected by the given password and key file, and returns its path.
+func writeKeyFileDatabase(t *testing.T, password string, keyFile string) string {
+	t.Helper()
+	credentials, err := keepass.NewCredentials(password, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: "Account"}},
+		gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: "secret"}},
+	)
+	root := gokeepasslib.NewGroup()
+	root.Name = "Root"
+	root.Entries = append(root.Entries, entry)
+	db := gokeepasslib.NewDatabase()
+	db.Credentials = credentials
+	db.Content.Root.Groups = []gokeepasslib.Group{root}
+	if err := db.LockProtectedEntries(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keyfile.kdbx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := gokeepasslib.NewEncoder(f).Encode(db); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunApp_KeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "db.key")
+	if err := os.WriteFile(keyFile, []byte("arbitrary key file content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := writeKeyFileDatabase(t, "pw", keyFile)
+
+	for _, tc := range []struct {
+		name   string
+		flags  *cmd.Flags
+		envKey string
+	}{
+		{"flag", &cmd.Flags{Item: "/Root/Account", FieldName: "Password", KeyFile: keyFile}, ""},
+		{"env", &cmd.Flags{Item: "/Root/Account", FieldName: "Password"}, keyFile},
+	} {
+		mockHandler := &fakeHandler{}
+		err := RunApp(
+			tc.flags,
+			fakeLoadConfig(nil),
+			fakeResolveDBPath(dbPath),
+			fakeResolvePassword("pw", nil),
+			keepass.OpenDatabase,
+			func(db *gokeepasslib.Database) search.FinderInterface { return search.NewFinder(db) },
+			func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+			&MockClipboard{},
+			func(name string) string {
+				if name == "KPASSCLI_KEYFILE" {
+					return tc.envKey
+				}
+				return ""
+			},
+		)
+		if err != nil {
+			t.Errorf("%s: expected success, got %v", tc.name, err)
+		}
+		if mockHandler.captured != "secret" {
+			t.Errorf("%s: expected output 'secret', got '%s'", tc.name, mockHandler.captured)
+		}
+	}
+}
+
+func TestRunApp_KeyFileOnly(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "db.key")
+	if err := os.WriteFile(keyFile, []byte("arbitrary key file content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := writeKeyFileDatabase(t, "", keyFile)
+
+	mockHandler := &fakeHandler{}
+	flags := &cmd.Flags{Item: "Account", FieldName: "Password", KeyFile: keyFile, NoPassword: true}
+	err := RunApp(
+		flags,
+		fakeLoadConfig(nil),
+		fakeResolveDBPath(dbPath),
+		fakeResolvePassword("", errors.New("password must not be resolved")),
+		keepass.OpenDatabase,
+		func(db *gokeepasslib.Database) search.FinderInterface { return search.NewFinder(db) },
+		func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+		&MockClipboard{},
+		func(string) string { return "" },
+	)
+	if err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+	if mockHandler.captured != "secret" {
+		t.Errorf("expected output 'secret', got '%s'", mockHandler.captured)
+	}
+}
+
+func TestRunApp_NoPasswordWithoutKeyFile(t *testing.T) {
+	flags := &cmd.Flags{Item: "foo", NoPassword: true}
+	err := RunApp(
+		flags,
+		fakeLoadConfig(nil),
+		fakeResolveDBPath("db"),
+		fakeResolvePassword("pw", nil),
+		fakeOpenDatabase(nil, nil),
+		func(db *gokeepasslib.Database) search.FinderInterface { return search.NewFinder(db) },
+		fakeNewHandler(nil),
+		&MockClipboard{},
+		func(string) string { return "" },
+	)
+	if err == nil || err.Error() != "no key file provided, -no-password requires a key file" {
+		t.Errorf("expected missing key file error, got %v", err)
+	}
+}
+
+// showAllResults returns a single search result with a protected password and a custom field.
+func showAllResults() []search.Result {
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: "Account"}},
+		gokeepasslib.ValueData{Key: "UserName", Value: gokeepasslib.V{Content: "tester"}},
+		gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: "secret"}},
+		gokeepasslib.ValueData{Key: "Custom", Value: gokeepasslib.V{Content: "CustomValue"}},
+	)
+	return []search.Result{{Path: "/Root/Account", Entry: &entry}}
+}
+
+func TestRunApp_ShowAll(t *testing.T) {
+	tests := []struct {
+		name   string
+		flags  *cmd.Flags
+		want   []string
+		hidden string
+	}{
+		{"text", &cmd.Flags{Item: "Account", ShowAll: true}, []string{"Username: tester", "Password: ********", "Custom: CustomValue"}, "secret"},
+		{"json", &cmd.Flags{Item: "Account", ShowAll: true, Format: "json"}, []string{`"username": "tester"`, `"password": "********"`}, "secret"},
+		{"yaml reveal", &cmd.Flags{Item: "Account", ShowAll: true, Format: "yaml", Reveal: true}, []string{"username: tester", "password: secret"}, ""},
+	}
+	for _, tc := range tests {
+		mockHandler := &fakeHandler{}
+		err := RunApp(
+			tc.flags,
+			fakeLoadConfig(nil),
+			fakeResolveDBPath("db"),
+			fakeResolvePassword("pw", nil),
+			fakeOpenDatabase(nil, nil),
+			func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{results: showAllResults()} },
+			func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+			&MockClipboard{},
+			func(string) string { return "" },
+		)
+		if err != nil {
+			t.Errorf("%s: expected success, got %v", tc.name, err)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(mockHandler.captured, want) {
+				t.Errorf("%s: expected %q in output, got:\n%s", tc.name, want, mockHandler.captured)
+			}
+		}
+		if tc.hidden != "" && strings.Contains(mockHandler.captured, tc.hidden) {
+			t.Errorf("%s: protected value %q must be masked", tc.name, tc.hidden)
+		}
+	}
+}
+
+func TestRunApp_ShowAll_ConfigFormat(t *testing.T) {
+	mockHandler := &fakeHandler{}
+	err := RunApp(
+		&cmd.Flags{Item: "Account", ShowAll: true},
+		func(string) (*config.Config, error) { return &config.Config{OutputFormat: "json"}, nil },
+		fakeResolveDBPath("db"),
+		fakeResolvePassword("pw", nil),
+		fakeOpenDatabase(nil, nil),
+		func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{results: showAllResults()} },
+		func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+		&MockClipboard{},
+		func(string) string { return "" },
+	)
+	if err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+	if !strings.HasPrefix(mockHandler.captured, "{") {
+		t.Errorf("expected JSON output from config output_format, got:\n%s", mockHandler.captured)
+	}
+}
+
+func TestRunApp_ShowAll_Errors(t *testing.T) {
+	multiple := append(showAllResults(), showAllResults()...)
+	tests := []struct {
+		name    string
+		flags   *cmd.Flags
+		results []search.Result
+		wantErr string
+	}{
+		{"invalid format", &cmd.Flags{Item: "Account", ShowAll: true, Format: "xml"}, showAllResults(), "unknown output format: xml"},
+		{"unsupported format", &cmd.Flags{Item: "Account", ShowAll: true, Format: "shell"}, showAllResults(), "output format shell is not supported by -show-all"},
+		{"not found", &cmd.Flags{Item: "Account", ShowAll: true}, nil, "Error showing item: entry not found: Account"},
+		{"multiple", &cmd.Flags{Item: "Account", ShowAll: true}, multiple, "Error showing item: multiple entries found"},
+	}
+	for _, tc := range tests {
+		err := RunApp(
+			tc.flags,
+			fakeLoadConfig(nil),
+			fakeResolveDBPath("db"),
+			fakeResolvePassword("pw", nil),
+			fakeOpenDatabase(nil, nil),
+			func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{results: tc.results} },
+			fakeNewHandler(nil),
+			&MockClipboard{},
+			func(string) string { return "" },
+		)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+func TestRunApp_MultipleFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags *cmd.Flags
+		cfg   *config.Config
+		want  string
+	}{
+		{"single text", &cmd.Flags{Item: "Account", FieldName: "username"}, nil, "tester"},
+		{"text", &cmd.Flags{Item: "Account", FieldName: "UserName,Password"}, nil, "UserName: tester\nPassword: secret"},
+		{"json", &cmd.Flags{Item: "Account", FieldName: "username, password", Format: "json"}, nil,
+			"{\n  \"UserName\": \"tester\",\n  \"Password\": \"secret\"\n}"},
+		{"single json", &cmd.Flags{Item: "Account", FieldName: "Password", Format: "json"}, nil, "{\n  \"Password\": \"secret\"\n}"},
+		{"shell", &cmd.Flags{Item: "Account", FieldName: "UserName,Password", Format: "shell"}, nil,
+			"export USERNAME='tester'\nexport PASSWORD='secret'"},
+		{"raw", &cmd.Flags{Item: "Account", FieldName: "UserName,Password", Format: "raw"}, nil, "tester\x00secret\x00"},
+		{"all dotenv", &cmd.Flags{Item: "Account", FieldName: "all", Format: "dotenv"}, nil,
+			"TITLE=\"Account\"\nUSERNAME=\"tester\"\nPASSWORD=\"secret\"\nCUSTOM=\"CustomValue\""},
+		{"config format", &cmd.Flags{Item: "Account", FieldName: "UserName"}, &config.Config{OutputFormat: "env"}, "USERNAME=\"tester\""},
+	}
+	for _, tc := range tests {
+		mockHandler := &fakeHandler{}
+		cfg := tc.cfg
+		if cfg == nil {
+			cfg = &config.Config{}
+		}
+		err := RunApp(
+			tc.flags,
+			func(string) (*config.Config, error) { return cfg, nil },
+			fakeResolveDBPath("db"),
+			fakeResolvePassword("pw", nil),
+			fakeOpenDatabase(nil, nil),
+			func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{results: showAllResults()} },
+			func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+			&MockClipboard{},
+			func(string) string { return "" },
+		)
+		if err != nil {
+			t.Errorf("%s: expected success, got %v", tc.name, err)
+			continue
+		}
+		if mockHandler.captured != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, mockHandler.captured, tc.want)
+		}
+	}
+}
+
+func TestRunApp_MultipleFields_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   *cmd.Flags
+		wantErr string
+	}{
+		{"missing field", &cmd.Flags{Item: "Account", FieldName: "UserName,Missing"}, "Error getting field: field 'Missing' not found"},
+		{"empty list", &cmd.Flags{Item: "Account", FieldName: " , "}, "Error getting field: no field name given"},
+		{"invalid format", &cmd.Flags{Item: "Account", FieldName: "UserName", Format: "xml"}, "unknown output format: xml"},
+	}
+	for _, tc := range tests {
+		err := RunApp(
+			tc.flags,
+			fakeLoadConfig(nil),
+			fakeResolveDBPath("db"),
+			fakeResolvePassword("pw", nil),
+			fakeOpenDatabase(nil, nil),
+			func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{results: showAllResults()} },
+			fakeNewHandler(nil),
+			&MockClipboard{},
+			func(string) string { return "" },
+		)
+		if err == nil || err.Error() != tc.wantErr {
+			t.Errorf("%s: expected error %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+// listTestDatabase returns a database with the entry /Root/Banking/Account and the entry /Root/Router.
+func listTestDatabase() *gokeepasslib.Database {
+	entry := func(title string) gokeepasslib.Entry {
+		return gokeepasslib.Entry{Values: []gokeepasslib.ValueData{{Key: "Title", Value: gokeepasslib.V{Content: title}}}}
+	}
+	db := &gokeepasslib.Database{Content: &gokeepasslib.DBContent{}}
+	db.Content.Root = &gokeepasslib.RootData{Groups: []gokeepasslib.Group{{
+		Name:    "Root",
+		Groups:  []gokeepasslib.Group{{Name: "Banking", Entries: []gokeepasslib.Entry{entry("Account")}}},
+		Entries: []gokeepasslib.Entry{entry("Router")},
+	}}}
+	return db
+}
+
+func TestRunApp_ListCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   *cmd.Flags
+		want    string
+		wantErr string
+	}{
+		{"ls root", &cmd.Flags{Command: cmd.CommandLs}, "Banking/\nRouter", ""},
+		{"ls group", &cmd.Flags{Command: cmd.CommandLs, Item: "/Root/Banking"}, "Account", ""},
+		{"tree", &cmd.Flags{Command: cmd.CommandTree}, "Root/\n├── Banking/\n│   └── Account\n└── Router", ""},
+		{"ls missing", &cmd.Flags{Command: cmd.CommandLs, Item: "Missing"}, "", "Error listing group: group not found: Missing"},
+	}
+	for _, tc := range tests {
+		mockHandler := &fakeHandler{}
+		err := RunApp(
+			tc.flags,
+			fakeLoadConfig(nil),
+			fakeResolveDBPath("db"),
+			fakeResolvePassword("pw", nil),
+			fakeOpenDatabase(listTestDatabase(), nil),
+			func(db *gokeepasslib.Database) search.FinderInterface { return search.NewFinder(db) },
+			func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+			&MockClipboard{},
+			func(string) string { return "" },
+		)
+		if tc.wantErr != "" {
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("%s: expected error %q, got %v", tc.name, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected success, got %v", tc.name, err)
+		}
+		if mockHandler.captured != tc.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tc.name, mockHandler.captured, tc.want)
+		}
+	}
+}
+
+func TestRunApp_SearchCommand(t *testing.T) {
+	results := []search.Result{{Path: "/Root/Account"}, {Path: "/Root/Banking/Account"}}
+	mockHandler := &fakeHandler{}
+	err := RunApp(
+		&cmd.Flags{Command: cmd.CommandSearch, Item: "Account"},
+		fakeLoadConfig(nil),
+		fakeResolveDBPath("db"),
+		fakeResolvePassword("pw", nil),
+		fakeOpenDatabase(nil, nil),
+		func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{results: results} },
+		func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+		&MockClipboard{},
+		func(string) string { return "" },
+	)
+	if err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+	if want := "/Root/Account\n/Root/Banking/Account"; mockHandler.captured != want {
+		t.Errorf("got %q, want %q", mockHandler.captured, want)
+	}
+
+	err = RunApp(
+		&cmd.Flags{Command: cmd.CommandSearch, Item: "Account"},
+		fakeLoadConfig(nil),
+		fakeResolveDBPath("db"),
+		fakeResolvePassword("pw", nil),
+		fakeOpenDatabase(nil, nil),
+		func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{} },
+		fakeNewHandler(nil),
+		&MockClipboard{},
+		func(string) string { return "" },
+	)
+	if err == nil || err.Error() != "no items found" {
+		t.Errorf("expected 'no items found', got %v", err)
+	}
+}
+
+// runOnDatabase runs RunApp with the real database functions on the fixture database at dbPath,
+// protected by the password "pw", and returns the output and error.
+func runOnDatabase(flags *cmd.Flags, dbPath string) (string, error) {
+	mockHandler := &fakeHandler{}
+	err := RunApp(
+		flags,
+		fakeLoadConfig(nil),
+		fakeResolveDBPath(dbPath),
+		fakeResolvePassword("pw", nil),
+		keepass.OpenDatabase,
+		func(db *gokeepasslib.Database) search.FinderInterface { return search.NewFinder(db) },
+		func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+		&MockClipboard{},
+		func(string) string { return "" },
+	)
+	return mockHandler.captured, err
+}
+
+func TestRunApp_WriteCommands(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+
+	add := &cmd.Flags{Command: cmd.CommandAdd, Item: "/Root/Mail/Work", Args: []string{"UserName=tester", "Password=mailpw"}, Backup: true}
+	if _, err := runOnDatabase(add, dbPath); err != nil {
+		t.Fatalf("add: expected success, got %v", err)
+	}
+	if _, err := os.Stat(dbPath + ".bak"); err != nil {
+		t.Errorf("add: expected backup file, got %v", err)
+	}
+	if got, err := runOnDatabase(&cmd.Flags{Item: "/Root/Mail/Work", FieldName: "UserName"}, dbPath); err != nil || got != "tester" {
+		t.Errorf("add: expected UserName 'tester', got '%s' (err: %v)", got, err)
+	}
+	if _, err := runOnDatabase(add, dbPath); err == nil || !strings.Contains(err.Error(), "entry already exists") {
+		t.Errorf("add: expected error for existing entry, got %v", err)
+	}
+
+	set := &cmd.Flags{Command: cmd.CommandSet, Item: "Work", Args: []string{"Password=newpw"}}
+	if _, err := runOnDatabase(set, dbPath); err != nil {
+		t.Fatalf("set: expected success, got %v", err)
+	}
+	if got, err := runOnDatabase(&cmd.Flags{Item: "Work", FieldName: "Password"}, dbPath); err != nil || got != "newpw" {
+		t.Errorf("set: expected Password 'newpw', got '%s' (err: %v)", got, err)
+	}
+	if _, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandSet, Item: "Work"}, dbPath); err == nil {
+		t.Error("set: expected error without field assignments")
+	}
+	if _, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandSet, Item: "Work", Args: []string{"Password"}}, dbPath); err == nil {
+		t.Error("set: expected error for invalid field assignment")
+	}
+
+	if _, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandRm, Item: "Work"}, dbPath); err != nil {
+		t.Fatalf("rm: expected success, got %v", err)
+	}
+	if _, err := runOnDatabase(&cmd.Flags{Item: "Work", FieldName: "Password"}, dbPath); err == nil || err.Error() != "no items found" {
+		t.Errorf("rm: expected 'no items found', got %v", err)
+	}
+	if _, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandRm, Item: "Missing"}, dbPath); err == nil {
+		t.Error("rm: expected error for missing entry")
+	}
+}
+
+func TestRunApp_Generate(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   *cmd.Flags
+		check   func(string) bool
+		wantErr bool
+	}{
+		{"password", &cmd.Flags{Command: cmd.CommandGenerate, Length: 24, Classes: "digits"},
+			func(s string) bool { return len(s) == 24 && strings.Trim(s, "0123456789") == "" }, false},
+		{"passphrase", &cmd.Flags{Command: cmd.CommandGenerate, Words: 5, Separator: " "},
+			func(s string) bool { return len(strings.Split(s, " ")) == 5 }, false},
+		{"invalid rules", &cmd.Flags{Command: cmd.CommandGenerate, Length: 2, Classes: "digits", MinDigits: 3}, nil, true},
+	}
+	for _, tc := range tests {
+		mockHandler := &fakeHandler{}
+		err := RunApp(
+			tc.flags,
+			fakeLoadConfig(nil),
+			fakeResolveDBPath(""),
+			fakeResolvePassword("", errors.New("password must not be resolved")),
+			fakeOpenDatabase(nil, errors.New("database must not be opened")),
+			func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{} },
+			func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+			&MockClipboard{},
+			func(string) string { return "" },
+		)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected success, got %v", tc.name, err)
+		}
+		if !tc.check(mockHandler.captured) {
+			t.Errorf("%s: unexpected output '%s'", tc.name, mockHandler.captured)
+		}
+	}
+}
+
+func TestRunApp_WriteCommands_Generate(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+
+	set := &cmd.Flags{Command: cmd.CommandSet, Item: "Account", Generate: true, Length: 32, Classes: "lower,upper"}
+	if _, err := runOnDatabase(set, dbPath); err != nil {
+		t.Fatalf("set: expected success, got %v", err)
+	}
+	got, err := runOnDatabase(&cmd.Flags{Item: "Account", FieldName: "Password"}, dbPath)
+	if err != nil || len(got) != 32 || got == "secret" {
+		t.Errorf("set: expected generated password of length 32, got '%s' (err: %v)", got, err)
+	}
+
+	add := &cmd.Flags{Command: cmd.CommandAdd, Item: "New", Args: []string{"Password=x"}, Generate: true, Length: 10}
+	if _, err := runOnDatabase(add, dbPath); err == nil {
+		t.Error("add: expected error for -generate together with Password=")
+	}
+}
+
+func TestRunApp_Agent(t *testing.T) {
+	defer func(orig func(string, string) (*gokeepasslib.Database, error)) { fetchFromAgent = orig }(fetchFromAgent)
+	fetched := 0
+	fetchFromAgent = func(socket, dbPath string) (*gokeepasslib.Database, error) {
+		fetched++
+		return listTestDatabase(), nil
+	}
+
+	run := func(flags *cmd.Flags) (string, error) {
+		mockHandler := &fakeHandler{}
+		err := RunApp(
+			flags,
+			fakeLoadConfig(nil),
+			fakeResolveDBPath("db"),
+			fakeResolvePassword("", errors.New("password must not be resolved")),
+			fakeOpenDatabase(nil, errors.New("database must not be opened")),
+			func(db *gokeepasslib.Database) search.FinderInterface { return search.NewFinder(db) },
+			func(output.OutputType, output.ClipboardService) output.Handler { return mockHandler },
+			&MockClipboard{},
+			func(string) string { return "" },
+		)
+		return mockHandler.captured, err
+	}
+
+	if got, err := run(&cmd.Flags{Command: cmd.CommandLs}); err != nil || got != "Banking/\nRouter" {
+		t.Errorf("expected database of the agent, got '%s' (err: %v)", got, err)
+	}
+	if _, err := run(&cmd.Flags{Command: cmd.CommandLs, NoAgent: true}); err == nil || !strings.Contains(err.Error(), "password must not be resolved") {
+		t.Errorf("expected -no-agent to open the database directly, got %v", err)
+	}
+	if _, err := run(&cmd.Flags{Command: cmd.CommandRm, Item: "Router"}); err == nil || !strings.Contains(err.Error(), "password must not be resolved") {
+		t.Errorf("expected rm to open the database directly, got %v", err)
+	}
+	if fetched != 1 {
+		t.Errorf("expected agent to be queried once, got %d", fetched)
+	}
+}
+
+func TestRunApp_AgentNotRunning(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	err := RunApp(
+		&cmd.Flags{Command: cmd.CommandLock, AgentSocket: socket},
+		fakeLoadConfig(nil),
+		fakeResolveDBPath("db"),
+		fakeResolvePassword("pw", nil),
+		fakeOpenDatabase(nil, nil),
+		func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{} },
+		fakeNewHandler(nil),
+		&MockClipboard{},
+		func(string) string { return "" },
+	)
+	if err == nil || !strings.Contains(err.Error(), "agent is not running") {
+		t.Errorf("expected 'agent is not running', got %v", err)
+	}
+
+	// without a running agent the database is opened directly
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	if got, err := runOnDatabase(&cmd.Flags{Item: "Account", FieldName: "Password", AgentSocket: socket}, dbPath); err != nil || got != "secret" {
+		t.Errorf("expected fallback to direct opening, got '%s' (err: %v)", got, err)
+	}
+}
+
+func TestReadAgentPassword(t *testing.T) {
+	for input, want := range map[string]string{"pw\n": "pw", "pw": "pw", "": "", "p w\nrest": "p w"} {
+		if got, err := readAgentPassword(strings.NewReader(input)); err != nil || got != want {
+			t.Errorf("%q: got %q (err: %v), want %q", input, got, err, want)
+		}
+	}
+}
+
+func TestRunApp_Exec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	run := func(flags *cmd.Flags) error {
+		flags.Command = cmd.CommandExec
+		flags.NoAgent = true
+		return RunApp(
+			flags,
+			fakeLoadConfig(nil),
+			fakeResolveDBPath("db"),
+			fakeResolvePassword("pw", nil),
+			fakeOpenDatabase(nil, nil),
+			func(db *gokeepasslib.Database) search.FinderInterface { return &FakeFinder{results: showAllResults()} },
+			fakeNewHandler(nil),
+			&MockClipboard{},
+			func(string) string { return "" },
+		)
+	}
+
+	err := run(&cmd.Flags{
+		Env:  []string{"DB_PASS=Account", "DB_USER=/Root/Account:username"},
+		Args: []string{"sh", "-c", `test "$DB_PASS" = secret && test "$DB_USER" = tester`},
+	})
+	if err != nil {
+		t.Errorf("expected the variables to be set, got %v", err)
+	}
+
+	err = run(&cmd.Flags{Args: []string{"sh", "-c", "exit 7"}})
+	var exitErr *inject.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 7 {
+		t.Errorf("expected exit status 7, got %v", err)
+	}
+
+	tests := []struct {
+		flags   *cmd.Flags
+		wantErr string
+	}{
+		{&cmd.Flags{}, "no command given"},
+		{&cmd.Flags{Env: []string{"DB_PASS"}, Args: []string{"true"}}, "Error resolving environment: invalid environment reference"},
+		{&cmd.Flags{Env: []string{"X=Account:Missing"}, Args: []string{"true"}}, "Error resolving environment: X: Error getting field"},
+	}
+	for _, tc := range tests {
+		if err := run(tc.flags); err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+			t.Errorf("expected error %q, got %v", tc.wantErr, err)
+		}
+	}
+}
+
+func TestRunApp_Render(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	dir := t.TempDir()
+	in := filepath.Join(dir, "app.conf.tmpl")
+	if err := os.WriteFile(in, []byte("password={{ kp \"/Root/Account\" \"Password\" }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandRender, In: in, NoAgent: true}, dbPath)
+	if err != nil || got != "password=secret" {
+		t.Errorf("expected rendered output, got %q (err: %v)", got, err)
+	}
+
+	out := filepath.Join(dir, "app.conf")
+	if _, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandRender, In: in, Out: out, NoAgent: true}, dbPath); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || string(data) != "password=secret\n" {
+		t.Errorf("expected rendered file, got %q (err: %v)", data, err)
+	}
+
+	if err := os.WriteFile(in, []byte("{{ kp \"Account\" \"Missing\" }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandRender, In: in, Out: out, NoAgent: true}, dbPath)
+	if err == nil || !strings.HasPrefix(err.Error(), "Error rendering template:") {
+		t.Errorf("expected rendering error, got %v", err)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "password=secret\n" {
+		t.Errorf("output file must not be changed on error, got %q", data)
+	}
+}
+
+// withStdio runs fn with stdin reading input and stdout and stderr written to a file.
+// It returns the written output.
+func withStdio(t *testing.T, input string, fn func()) string {
+	t.Helper()
+	dir := t.TempDir()
+	in := filepath.Join(dir, "stdin")
+	if err := os.WriteFile(in, []byte(input), 0600); err != nil {
+		t.Fatal(err)
+	}
+	inFile, err := os.Open(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inFile.Close()
+	outFile, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outFile.Close()
+
+	stdin, stdout, stderr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = inFile, outFile, outFile
+	defer func() { os.Stdin, os.Stdout, os.Stderr = stdin, stdout, stderr }()
+	fn()
+	data, err := os.ReadFile(outFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRunApp_Batch(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	var err error
+	out := withStdio(t, "/Root/Account\nMissing\n{\"id\": 7, \"item\": \"Account\", \"field\": \"Title\"}\n", func() {
+		_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandBatch, NoAgent: true}, dbPath)
+	})
+	if err == nil || err.Error() != "1 requests failed" {
+		t.Errorf("expected one failed request, got %v", err)
+	}
+	for _, want := range []string{"secret\n", "line 2: Missing: no items found", `{"id":7,"item":"Account","field":"Title","value":"Account"}`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunApp_GitCredential(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	gitCredential := func(action, request string) (string, error) {
+		var err error
+		out := withStdio(t, request, func() {
+			_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandGitCredential, Args: []string{action}, NoAgent: true}, dbPath)
+		})
+		return out, err
+	}
+
+	if out, err := gitCredential("get", "protocol=https\nhost=git.example.com\n"); err != nil || out != "" {
+		t.Errorf("expected no credentials, got %q (err: %v)", out, err)
+	}
+	if _, err := gitCredential("store", "protocol=https\nhost=git.example.com\nusername=tester\npassword=gitpw\n"); err != nil {
+		t.Fatalf("store: expected success, got %v", err)
+	}
+	out, err := gitCredential("get", "protocol=https\nhost=git.example.com\npath=org/repo.git\n")
+	if err != nil || out != "username=tester\npassword=gitpw\n" {
+		t.Errorf("expected stored credentials, got %q (err: %v)", out, err)
+	}
+	got, err := runOnDatabase(&cmd.Flags{Item: "/Root/Git/tester@git.example.com", FieldName: "URL", NoAgent: true}, dbPath)
+	if err != nil || got != "https://git.example.com" {
+		t.Errorf("expected entry in group Git, got %q (err: %v)", got, err)
+	}
+	_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandGitCredential, NoAgent: true}, dbPath)
+	if err == nil || !strings.Contains(err.Error(), "no action given") {
+		t.Errorf("expected error without action, got %v", err)
+	}
+}
+
+func TestRunApp_DockerCredential(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	dockerCredential := func(action, request string) (string, error) {
+		var err error
+		out := withStdio(t, request, func() {
+			_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandDockerCredential, Args: []string{action}, NoAgent: true}, dbPath)
+		})
+		return out, err
+	}
+
+	out, err := dockerCredential("get", "registry.example.com\n")
+	if err == nil || out != "credentials not found in native keychain\n" {
+		t.Errorf("expected not found message on stdout, got %q (err: %v)", out, err)
+	}
+	if _, err := dockerCredential("store", `{"ServerURL":"registry.example.com","Username":"tester","Secret":"regpw"}`); err != nil {
+		t.Fatalf("store: expected success, got %v", err)
+	}
+	out, err = dockerCredential("get", "registry.example.com\n")
+	if err != nil || out != `{"ServerURL":"registry.example.com","Username":"tester","Secret":"regpw"}`+"\n" {
+		t.Errorf("expected stored credentials, got %q (err: %v)", out, err)
+	}
+	if out, err := dockerCredential("list", ""); err != nil || out != `{"registry.example.com":"tester"}`+"\n" {
+		t.Errorf("expected listed registry, got %q (err: %v)", out, err)
+	}
+	if _, err := dockerCredential("erase", "registry.example.com\n"); err != nil {
+		t.Fatalf("erase: expected success, got %v", err)
+	}
+	if out, _ := dockerCredential("list", ""); out != "{}\n" {
+		t.Errorf("expected no registries after erase, got %q", out)
+	}
+}
+
+func TestRunApp_K8sCredential(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	var err error
+	out := withStdio(t, "", func() {
+		_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandK8sCredential, Item: "Account", FieldName: "Password", NoAgent: true}, dbPath)
+	})
+	want := `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"secret"}}` + "\n"
+	if err != nil || !strings.HasSuffix(out, want) {
+		t.Errorf("expected ExecCredential %q, got %q (err: %v)", want, out, err)
+	}
+	_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandK8sCredential, Item: "Account", FieldName: "Token", NoAgent: true}, dbPath)
+	if err == nil || !strings.Contains(err.Error(), "Error creating exec credential") {
+		t.Errorf("expected error for missing token field, got %v", err)
+	}
+}
+
+func TestRunApp_AWSCredentialProcess(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	add := &cmd.Flags{Command: cmd.CommandAdd, Item: "/Root/AWS/prod", Args: []string{"AccessKeyId=AKIAEXAMPLE", "SecretAccessKey=secretkey"}}
+	if _, err := runOnDatabase(add, dbPath); err != nil {
+		t.Fatalf("add: expected success, got %v", err)
+	}
+	var err error
+	out := withStdio(t, "", func() {
+		_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandAWSCredentialProcess, Item: "/Root/AWS/prod", NoAgent: true}, dbPath)
+	})
+	want := `{"Version":1,"AccessKeyId":"AKIAEXAMPLE","SecretAccessKey":"secretkey"}` + "\n"
+	if err != nil || !strings.HasSuffix(out, want) {
+		t.Errorf("expected credentials %q, got %q (err: %v)", want, out, err)
+	}
+	_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandAWSCredentialProcess, Item: "Account", NoAgent: true}, dbPath)
+	if err == nil || !strings.Contains(err.Error(), "Error creating AWS credentials") {
+		t.Errorf("expected error for entry without credentials, got %v", err)
+	}
+}
+
+func TestRunApp_AnsibleVault(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	add := &cmd.Flags{Command: cmd.CommandAdd, Item: "/Root/Ansible/prod", Args: []string{"Password=vaultpw"}}
+	if _, err := runOnDatabase(add, dbPath); err != nil {
+		t.Fatalf("add: expected success, got %v", err)
+	}
+	var err error
+	out := withStdio(t, "", func() {
+		_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandAnsibleVault, VaultID: "prod", FieldName: "Password", NoAgent: true}, dbPath)
+	})
+	if err != nil || !strings.HasSuffix(out, "vaultpw\n") {
+		t.Errorf("expected vault password, got %q (err: %v)", out, err)
+	}
+	withStdio(t, "", func() {
+		_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandAnsibleVault, VaultID: "dev", FieldName: "Password", NoAgent: true}, dbPath)
+	})
+	var exitErr *inject.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Errorf("expected exit status 2 for unknown vault id, got %v", err)
+	}
+}
+
+func TestRunApp_Lookup(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	var err error
+	out := withStdio(t, `[{"item": "Account"}, {"item": "Account", "field": "Title"}]`, func() {
+		_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandLookup, NoAgent: true}, dbPath)
+	})
+	if want := `{"Account":{"Password":"secret","Title":"Account"}}` + "\n"; err != nil || !strings.HasSuffix(out, want) {
+		t.Errorf("expected %q, got %q (err: %v)", want, out, err)
+	}
+}
+
+func TestRunApp_TfExternal(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	var err error
+	out := withStdio(t, `{"password": "Account", "title": "Account:Title"}`, func() {
+		_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandTfExternal, NoAgent: true}, dbPath)
+	})
+	if want := `{"password":"secret","title":"Account"}` + "\n"; err != nil || !strings.HasSuffix(out, want) {
+		t.Errorf("expected %q, got %q (err: %v)", want, out, err)
+	}
+	withStdio(t, `{"x": "Missing"}`, func() {
+		_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandTfExternal, NoAgent: true}, dbPath)
+	})
+	if err == nil || !strings.Contains(err.Error(), "x: Missing: no items found") {
+		t.Errorf("expected error for missing item, got %v", err)
+	}
+}
+
+func TestRunApp_ExportNetrc(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	add := &cmd.Flags{Command: cmd.CommandAdd, Item: "/Root/Servers/Repo", Args: []string{"UserName=deployer", "Password=repopw", "URL=https://repo.example.com"}}
+	if _, err := runOnDatabase(add, dbPath); err != nil {
+		t.Fatalf("add: expected success, got %v", err)
+	}
+	want := "machine repo.example.com\n  login deployer\n  password repopw"
+	got, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandExportNetrc, NoAgent: true}, dbPath)
+	if err != nil || got != want {
+		t.Errorf("expected netrc %q, got %q (err: %v)", want, got, err)
+	}
+
+	pathFile := filepath.Join(t.TempDir(), "path")
+	script := `test "$(cat "$NETRC")" = "$WANT" && ls -l "$NETRC" | grep -q '^-rw-------' && echo "$NETRC" > ` + pathFile
+	t.Setenv("WANT", want)
+	_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandExportNetrc, Item: "Servers", Args: []string{"sh", "-c", script}, NoAgent: true}, dbPath)
+	if err != nil {
+		t.Fatalf("expected the command to read the netrc file, got %v", err)
+	}
+	data, err := os.ReadFile(pathFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(strings.TrimSpace(string(data))); !os.IsNotExist(err) {
+		t.Errorf("expected the netrc file to be removed, got %v", err)
+	}
+	_, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandExportNetrc, Args: []string{"sh", "-c", "exit 3"}, NoAgent: true}, dbPath)
+	var exitErr *inject.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Errorf("expected exit status 3, got %v", err)
+	}
+}
+
+func TestRunApp_Where(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	for _, item := range []string{"/Root/Ops/Deploy", "/Root/Dev/Deploy"} {
+		add := &cmd.Flags{Command: cmd.CommandAdd, Item: item, Args: []string{"UserName=svc-" + strings.ToLower(item[6:9]), "Password=" + item[6:9] + "pw"}}
+		if _, err := runOnDatabase(add, dbPath); err != nil {
+			t.Fatalf("add: expected success, got %v", err)
+		}
+	}
+	got, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandSearch, Where: []string{"UserName~svc-"}, NoAgent: true}, dbPath)
+	if err != nil || got != "/Root/Ops/Deploy\n/Root/Dev/Deploy" {
+		t.Errorf("search: got %q, %v", got, err)
+	}
+	got, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandGet, Item: "Deploy", FieldName: "Password", Where: []string{"UserName=svc-dev"}, NoAgent: true}, dbPath)
+	if err != nil || !strings.HasSuffix(got, "Devpw") {
+		t.Errorf("get: got %q, %v", got, err)
+	}
+	if _, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandGet, FieldName: "Password", Where: []string{"UserName"}, NoAgent: true}, dbPath); err == nil {
+		t.Error("get: expected error for invalid condition")
+	}
+}
+
+func TestRunApp_First(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	add := &cmd.Flags{Command: cmd.CommandAdd, Item: "/Root/Old/Account old", Args: []string{"Password=oldpw"}}
+	if _, err := runOnDatabase(add, dbPath); err != nil {
+		t.Fatalf("add: expected success, got %v", err)
+	}
+	get := &cmd.Flags{Command: cmd.CommandGet, Item: "Account", FieldName: "Password", NoAgent: true}
+	if _, err := runOnDatabase(get, dbPath); err == nil || err.Error() != "multiple items found" {
+		t.Errorf("get: expected multiple items, got %v", err)
+	}
+	get.First = true
+	if got, err := runOnDatabase(get, dbPath); err != nil || !strings.HasSuffix(got, "secret") {
+		t.Errorf("get -first: got %q, %v", got, err)
+	}
+	get.Item, get.Fuzzy = "acntold", true
+	if got, err := runOnDatabase(get, dbPath); err != nil || !strings.HasSuffix(got, "oldpw") {
+		t.Errorf("get -fuzzy -first: got %q, %v", got, err)
+	}
+}
+
+func TestRunApp_Interactive(t *testing.T) {
+	defer func(orig func() bool) { isTerminal = orig }(isTerminal)
+	defer func(orig func([]string) (int, error)) { selectResult = orig }(selectResult)
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	add := &cmd.Flags{Command: cmd.CommandAdd, Item: "/Root/Old/Account", Args: []string{"Password=oldpw"}}
+	if _, err := runOnDatabase(add, dbPath); err != nil {
+		t.Fatalf("add: expected success, got %v", err)
+	}
+	isTerminal = func() bool { return true }
+	var offered []string
+	selectResult = func(paths []string) (int, error) {
+		offered = paths
+		return 1, nil
+	}
+	get := &cmd.Flags{Command: cmd.CommandGet, Item: "Account", FieldName: "Password", NoAgent: true}
+	got, err := runOnDatabase(get, dbPath)
+	if err != nil || !strings.HasSuffix(got, "oldpw") {
+		t.Errorf("get: got %q, %v", got, err)
+	}
+	if strings.Join(offered, ",") != "/Root/Account,/Root/Old/Account" {
+		t.Errorf("unexpected paths offered: %v", offered)
+	}
+
+	selectResult = func([]string) (int, error) { return 0, errors.New("selection cancelled") }
+	if _, err := runOnDatabase(get, dbPath); err == nil || err.Error() != "Error selecting item: selection cancelled" {
+		t.Errorf("expected cancelled selection, got %v", err)
+	}
+
+	get.NoInteractive = true
+	if _, err := runOnDatabase(get, dbPath); err == nil || err.Error() != "multiple items found" {
+		t.Errorf("expected multiple items found with -no-interactive, got %v", err)
+	}
+}
+
+func TestRunApp_RenderAmbiguousInTerminal(t *testing.T) {
+	defer func(orig func() bool) { isTerminal = orig }(isTerminal)
+	defer func(orig func([]string) (int, error)) { selectResult = orig }(selectResult)
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	add := &cmd.Flags{Command: cmd.CommandAdd, Item: "/Root/Old/Account", Args: []string{"Password=oldpw"}}
+	if _, err := runOnDatabase(add, dbPath); err != nil {
+		t.Fatalf("add: expected success, got %v", err)
+	}
+	isTerminal = func() bool { return true }
+	selectResult = func([]string) (int, error) {
+		t.Error("render must not show the picker")
+		return 0, nil
+	}
+	in := filepath.Join(t.TempDir(), "app.conf.tmpl")
+	if err := os.WriteFile(in, []byte("{{ kp \"Account\" \"Password\" }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandRender, In: in, NoAgent: true}, dbPath)
+	if err == nil || !strings.Contains(err.Error(), "multiple items found") {
+		t.Errorf("expected multiple items found, got %v", err)
+	}
+}
+
+func TestRunApp_PrintUUID(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	id, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandGet, Item: "/Root/Account", PrintUUID: true, NoAgent: true}, dbPath)
+	if err != nil || len(id) < 32 {
+		t.Fatalf("get -print-uuid: got %q, %v", id, err)
+	}
+	id = id[len(id)-32:]
+	move := &cmd.Flags{Command: cmd.CommandSet, Item: "/Root/Account", Args: []string{"Title=Renamed"}}
+	if _, err := runOnDatabase(move, dbPath); err != nil {
+		t.Fatalf("set: expected success, got %v", err)
+	}
+	got, err := runOnDatabase(&cmd.Flags{Command: cmd.CommandGet, Item: "uuid:" + id, FieldName: "Password", NoAgent: true}, dbPath)
+	if err != nil || !strings.HasSuffix(got, "secret") {
+		t.Errorf("get uuid: got %q, %v", got, err)
+	}
+	got, err = runOnDatabase(&cmd.Flags{Command: cmd.CommandSearch, Item: "Renamed", PrintUUID: true, NoAgent: true}, dbPath)
+	if err != nil || got != id+"\t/Root/Renamed" {
+		t.Errorf("search -print-uuid: got %q, %v", got, err)
+	}
+}
+
+func TestRunApp_Placeholders(t *testing.T) {
+	dbPath := writeKeyFileDatabase(t, "pw", "")
+	add := &cmd.Flags{Command: cmd.CommandAdd, Item: "/Root/Alias", Args: []string{"Password={REF:P@T:Account}", "UserName={TITLE}"}}
+	if _, err := runOnDatabase(add, dbPath); err != nil {
+		t.Fatalf("add: expected success, got %v", err)
+	}
+	get := &cmd.Flags{Command: cmd.CommandGet, Item: "Alias", FieldName: "UserName,Password", Format: "dotenv", NoAgent: true}
+	if got, err := runOnDatabase(get, dbPath); err != nil || !strings.HasSuffix(got, "USERNAME=\"Alias\"\nPASSWORD=\"secret\"") {
+		t.Errorf("get: got %q, %v", got, err)
+	}
+	get.Raw = true
+	if got, err := runOnDatabase(get, dbPath); err != nil || !strings.HasSuffix(got, "USERNAME=\"{TITLE}\"\nPASSWORD=\"{REF:P@T:Account}\"") {
+		t.Errorf("get -raw: got %q, %v", got, err)
+	}
+}
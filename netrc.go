@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/cmd"
+	"kpasscli/src/config"
+	"kpasscli/src/credential"
+	"kpasscli/src/debug"
+	"kpasscli/src/inject"
+	"kpasscli/src/output"
+)
+
+// exportNetrc builds a .netrc file from the entries of the group and outputs it, or, if a command is given,
+// writes it to a temporary file readable only by the user, runs the command with NETRC set to the file and
+// removes the file after the command finished.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - cfg: The loaded configuration (NetrcGroup).
+//   - flags: The parsed command-line flags (Item as group, Args as command).
+//   - handler: The output handler of the .netrc file, if no command is given.
+//
+// Returns:
+//   - error: An *inject.ExitError with the exit status of the command, or any error encountered.
+func exportNetrc(db *gokeepasslib.Database, cfg *config.Config, flags *cmd.Flags, handler output.Handler) error {
+	group := flags.Item
+	if group == "" {
+		group = cfg.NetrcGroup
+	}
+	entries, err := credential.NetrcEntries(db, group)
+	if err != nil {
+		return fmt.Errorf("Error exporting netrc: %w", err)
+	}
+	debug.Log("Exporting %d netrc entries of group '%s'", len(entries), group)
+	netrc := credential.FormatNetrc(entries)
+	if len(flags.Args) == 0 {
+		if err := handler.Output(strings.TrimSuffix(netrc, "\n")); err != nil {
+			return fmt.Errorf("Error outputting value: %w", err)
+		}
+		return nil
+	}
+
+	// CreateTemp creates the file with mode 0600
+	file, err := os.CreateTemp("", "kpasscli-netrc-*")
+	if err != nil {
+		return fmt.Errorf("Error creating netrc file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(netrc); err != nil {
+		file.Close()
+		return fmt.Errorf("Error writing netrc file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("Error writing netrc file: %w", err)
+	}
+	command := &inject.Command{
+		Args:   flags.Args,
+		Env:    append(os.Environ(), "NETRC="+file.Name()),
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+	if err := command.Run(); err != nil {
+		if _, ok := err.(*inject.ExitError); ok {
+			return err
+		}
+		return fmt.Errorf("Error running command: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/cmd"
+	"kpasscli/src/debug"
+	"kpasscli/src/output"
+	"kpasscli/src/render"
+	"kpasscli/src/search"
+)
+
+// renderTemplate renders the template flags.In, or stdin if it is empty or "-", and writes the
+// result to the file flags.Out with permissions 0600. If flags.Out is empty or an output type
+// (stdout/clipboard), the result is passed to the output handler.
+//
+// Parameters:
+//   - db: The opened KeePass database, used for attachments.
+//   - flags: The parsed command-line flags (In, Out).
+//   - finder: The finder used to search the entries.
+//   - handler: The output handler, if no output file is given.
+//
+// Returns:
+//   - error: Any error encountered while reading, rendering or writing, including unresolved references.
+func renderTemplate(db *gokeepasslib.Database, flags *cmd.Flags, finder search.FinderInterface, handler output.Handler) error {
+	name := flags.In
+	var text []byte
+	var err error
+	if name == "" || name == "-" {
+		name = "stdin"
+		text, err = io.ReadAll(os.Stdin)
+	} else {
+		text, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading template: %w", err)
+	}
+
+	resolver := &render.Resolver{
+		Find: func(item string) (search.Result, error) { return findSingle(item, finder, false) },
+		DB:   db,
+	}
+	data, err := render.Render(name, string(text), resolver)
+	if err != nil {
+		return fmt.Errorf("Error rendering template: %w", err)
+	}
+
+	if flags.Out == "" || output.IsValidType(flags.Out) {
+		if err := handler.Output(strings.TrimSuffix(string(data), "\n")); err != nil {
+			return fmt.Errorf("Error outputting value: %w", err)
+		}
+		return nil
+	}
+	if err := render.WriteFile(flags.Out, data); err != nil {
+		return fmt.Errorf("Error writing %s: %w", flags.Out, err)
+	}
+	debug.Log("Rendered %s to %s", name, flags.Out)
+	return nil
+}
@@ -0,0 +1,215 @@
+// Package agent keeps an unlocked KeePass database in a background process, which is queried
+// by kpasscli over a user-only Unix socket, so the password is only asked once per session.
+package agent
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/debug"
+)
+
+// Operations of the agent protocol
+const (
+	OpFetch  = "fetch"
+	OpStatus = "status"
+	OpLock   = "lock"
+)
+
+// dialTimeout is the timeout to connect to the agent
+const dialTimeout = 2 * time.Second
+
+// callTimeout is the timeout of a complete request to the agent
+const callTimeout = 30 * time.Second
+
+// ErrNotRunning is returned if no agent listens on the socket.
+var ErrNotRunning = errors.New("agent is not running")
+
+// Request is sent by the client to the agent.
+type Request struct {
+	// Op is the operation: OpFetch, OpStatus or OpLock
+	Op string
+	// Path is the absolute path of the database for OpFetch
+	Path string
+}
+
+// Response is sent by the agent to the client.
+type Response struct {
+	// Error is the error message, empty on success
+	Error string
+	// Path is the absolute path of the database held by the agent
+	Path string
+	// IdleTimeout is the time without requests after which the agent locks
+	IdleTimeout time.Duration
+	// Database is the unlocked database for OpFetch, without credentials
+	Database *gokeepasslib.Database
+}
+
+// SocketPath returns the path of the agent socket.
+// The precedence is: flag, environment variable KPASSCLI_AGENT_SOCK, $XDG_RUNTIME_DIR/kpasscli/agent.sock,
+// <tmp>/kpasscli-<uid>/agent.sock.
+//
+// Parameters:
+//   - flagPath: The socket path from the -socket flag, may be empty.
+//   - getEnv: Function to read environment variables.
+//
+// Returns:
+//   - string: The socket path.
+func SocketPath(flagPath string, getEnv func(string) string) string {
+	if flagPath != "" {
+		return flagPath
+	}
+	if env := getEnv("KPASSCLI_AGENT_SOCK"); env != "" {
+		return env
+	}
+	if runtimeDir := getEnv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "kpasscli", "agent.sock")
+	}
+	return filepath.Join(os.TempDir(), "kpasscli-"+strconv.Itoa(os.Getuid()), "agent.sock")
+}
+
+// checkSocketDir checks, that only the current user can access the directory of the socket:
+// it must be a directory, not a symbolic link, owned by the current user with mode 0700.
+// Otherwise another user could replace the socket by the socket of their own agent.
+//
+// Parameters:
+//   - dir: The directory of the socket.
+//
+// Returns:
+//   - error: An error if the directory can be accessed by other users.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("agent socket directory %s is not a directory", dir)
+	}
+	if err := checkOwner(dir, info); err != nil {
+		return fmt.Errorf("insecure agent socket directory: %w", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0700 {
+		return fmt.Errorf("insecure agent socket directory %s: mode %04o, expected 0700", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// checkSocket checks, that the socket and its directory belong to the current user.
+//
+// Parameters:
+//   - socket: The path of the agent socket.
+//
+// Returns:
+//   - error: An error if the socket or its directory can be accessed or was created by other users.
+func checkSocket(socket string) error {
+	if err := checkSocketDir(filepath.Dir(socket)); err != nil {
+		return err
+	}
+	info, err := os.Lstat(socket)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("agent socket %s is not a socket", socket)
+	}
+	if err := checkOwner(socket, info); err != nil {
+		return fmt.Errorf("insecure agent socket: %w", err)
+	}
+	return nil
+}
+
+// call sends a request to the agent and returns its response.
+//
+// Parameters:
+//   - socket: The path of the agent socket.
+//   - req: The request.
+//
+// Returns:
+//   - *Response: The response of the agent.
+//   - error: ErrNotRunning if no agent listens, an error if the socket does not belong to the current user,
+//     or the error of the agent.
+func call(socket string, req Request) (*Response, error) {
+	if _, err := os.Stat(socket); err != nil {
+		return nil, ErrNotRunning
+	}
+	if err := checkSocket(socket); err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", socket, dialTimeout)
+	if err != nil {
+		debug.Log("Agent not reachable at %s: %v", socket, err)
+		return nil, ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(callTimeout))
+
+	if err := gob.NewEncoder(conn).Encode(&req); err != nil {
+		return nil, fmt.Errorf("failed to send request to agent: %w", err)
+	}
+	var resp Response
+	if err := gob.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response of agent: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// Fetch gets the unlocked database from the agent.
+//
+// Parameters:
+//   - socket: The path of the agent socket.
+//   - dbPath: The path of the database, the agent must hold the same database.
+//
+// Returns:
+//   - *gokeepasslib.Database: The unlocked database.
+//   - error: ErrNotRunning if no agent listens, or an error if the agent holds another database.
+func Fetch(socket string, dbPath string) (*gokeepasslib.Database, error) {
+	absPath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := call(socket, Request{Op: OpFetch, Path: absPath})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Database == nil {
+		return nil, fmt.Errorf("agent returned no database")
+	}
+	debug.Log("Fetched database %s from agent", resp.Path)
+	return resp.Database, nil
+}
+
+// Status returns the state of the agent.
+//
+// Parameters:
+//   - socket: The path of the agent socket.
+//
+// Returns:
+//   - *Response: The database path and idle timeout of the agent.
+//   - error: ErrNotRunning if no agent listens.
+func Status(socket string) (*Response, error) {
+	return call(socket, Request{Op: OpStatus})
+}
+
+// Lock tells the agent to drop the database and exit.
+//
+// Parameters:
+//   - socket: The path of the agent socket.
+//
+// Returns:
+//   - error: ErrNotRunning if no agent listens.
+func Lock(socket string) error {
+	_, err := call(socket, Request{Op: OpLock})
+	return err
+}
@@ -0,0 +1,224 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+// newTestDatabase returns an unlocked database with the entry /Root/Account and the given password.
+func newTestDatabase(password string) *gokeepasslib.Database {
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: "Account"}},
+		gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: password, Protected: wrappers.NewBoolWrapper(true)}},
+	)
+	root := gokeepasslib.NewGroup()
+	root.Name = "Root"
+	root.Entries = append(root.Entries, entry)
+	db := gokeepasslib.NewDatabase()
+	db.Credentials = gokeepasslib.NewPasswordCredentials("pw")
+	db.Content.Root.Groups = []gokeepasslib.Group{root}
+	return db
+}
+
+// startTestServer starts an agent for a database file in a temporary directory.
+// The returned counter is incremented on each call of Open.
+func startTestServer(t *testing.T, idleTimeout time.Duration) (*Server, *int, chan error) {
+	t.Helper()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.kdbx")
+	if err := os.WriteFile(dbPath, []byte("database"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	opened := 0
+	server := &Server{
+		Socket:      filepath.Join(dir, "agent", "agent.sock"),
+		DBPath:      dbPath,
+		IdleTimeout: idleTimeout,
+		Open: func() (*gokeepasslib.Database, error) {
+			opened++
+			return newTestDatabase("secret"), nil
+		},
+	}
+	if err := server.Listen(); err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(nil) }()
+	t.Cleanup(server.Close)
+	return server, &opened, done
+}
+
+func TestSocketPath(t *testing.T) {
+	env := map[string]string{"KPASSCLI_AGENT_SOCK": "/env.sock", "XDG_RUNTIME_DIR": "/run/user/1000"}
+	getEnv := func(name string) string { return env[name] }
+	if got := SocketPath("/flag.sock", getEnv); got != "/flag.sock" {
+		t.Errorf("flag socket not used: %s", got)
+	}
+	if got := SocketPath("", getEnv); got != "/env.sock" {
+		t.Errorf("env socket not used: %s", got)
+	}
+	delete(env, "KPASSCLI_AGENT_SOCK")
+	if got := SocketPath("", getEnv); got != filepath.Join("/run/user/1000", "kpasscli", "agent.sock") {
+		t.Errorf("runtime dir not used: %s", got)
+	}
+	delete(env, "XDG_RUNTIME_DIR")
+	if got := SocketPath("", getEnv); filepath.Base(got) != "agent.sock" {
+		t.Errorf("unexpected default socket: %s", got)
+	}
+}
+
+func TestServer_Fetch(t *testing.T) {
+	server, opened, _ := startTestServer(t, 0)
+
+	info, err := os.Stat(server.Socket)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected socket with mode 0600, got %v (err: %v)", info.Mode().Perm(), err)
+	}
+
+	db, err := Fetch(server.Socket, server.DBPath)
+	if err != nil {
+		t.Fatalf("unexpected error fetching database: %v", err)
+	}
+	if got := db.Content.Root.Groups[0].Entries[0].GetPassword(); got != "secret" {
+		t.Errorf("expected unlocked password 'secret', got '%s'", got)
+	}
+	if db.Credentials != nil {
+		t.Error("expected credentials not to be sent by the agent")
+	}
+
+	if _, err := Fetch(server.Socket, filepath.Join(filepath.Dir(server.DBPath), "other.kdbx")); err == nil {
+		t.Error("expected error fetching another database")
+	}
+
+	// a changed database file is reopened
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(server.DBPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Fetch(server.Socket, server.DBPath); err != nil {
+		t.Fatalf("unexpected error fetching changed database: %v", err)
+	}
+	if *opened != 2 {
+		t.Errorf("expected database opened twice, got %d", *opened)
+	}
+}
+
+func TestServer_Lock(t *testing.T) {
+	server, _, done := startTestServer(t, 0)
+	if _, err := Status(server.Socket); err != nil {
+		t.Fatalf("unexpected error getting status: %v", err)
+	}
+	if err := Lock(server.Socket); err != nil {
+		t.Fatalf("unexpected error locking: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error of Serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent did not stop after lock")
+	}
+	if _, err := os.Stat(server.Socket); !os.IsNotExist(err) {
+		t.Error("expected socket to be removed")
+	}
+	if err := Lock(server.Socket); err != ErrNotRunning {
+		t.Errorf("expected ErrNotRunning, got %v", err)
+	}
+}
+
+func TestServer_IdleTimeout(t *testing.T) {
+	_, _, done := startTestServer(t, 100*time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent did not lock after the idle timeout")
+	}
+}
+
+func TestServer_ListenTwice(t *testing.T) {
+	server, _, _ := startTestServer(t, 0)
+	second := &Server{Socket: server.Socket, DBPath: server.DBPath}
+	if err := second.Listen(); err == nil {
+		t.Error("expected error starting a second agent on the same socket")
+	}
+}
+
+func TestServer_StaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "agent.sock")
+	if err := os.WriteFile(socket, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{Socket: socket, DBPath: filepath.Join(dir, "test.kdbx")}
+	if err := server.Listen(); err != nil {
+		t.Fatalf("expected stale socket to be replaced, got %v", err)
+	}
+	server.listener.Close()
+}
+
+func TestServer_InsecureSocketDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions only")
+	}
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{Socket: filepath.Join(dir, "agent.sock"), DBPath: filepath.Join(dir, "test.kdbx")}
+	if err := server.Listen(); err == nil {
+		server.listener.Close()
+		t.Fatal("expected error for a socket directory accessible by other users")
+	}
+
+	// a symbolic link to a private directory is not accepted either
+	private := filepath.Join(dir, "private")
+	if err := os.Mkdir(private, 0700); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(private, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSocketDir(link); err == nil {
+		t.Error("expected error for a symbolic link as socket directory")
+	}
+}
+
+func TestClient_InsecureSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions only")
+	}
+	server, _, _ := startTestServer(t, 0)
+	dir := filepath.Dir(server.Socket)
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0700)
+	if _, err := Fetch(server.Socket, server.DBPath); err == nil || errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected insecure socket directory error, got %v", err)
+	}
+
+	// a file in place of the socket
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	fake := filepath.Join(dir, "fake.sock")
+	if err := os.WriteFile(fake, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Status(fake); err == nil || errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected error for a file instead of a socket, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package agent
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkOwner checks, that the file is owned by the current user.
+//
+// Parameters:
+//   - path: The path of the file.
+//   - info: The file info of path from os.Lstat.
+//
+// Returns:
+//   - error: An error if the file is owned by another user.
+func checkOwner(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("can not determine the owner of %s", path)
+	}
+	if int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d, not by the current user", path, stat.Uid)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package agent
+
+import (
+	"encoding/gob"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/debug"
+)
+
+// Server holds the unlocked database and answers the requests on the agent socket.
+type Server struct {
+	// Socket is the path of the agent socket
+	Socket string
+	// DBPath is the absolute path of the database
+	DBPath string
+	// IdleTimeout is the time without requests after which the agent locks, 0 disables it
+	IdleTimeout time.Duration
+	// Open opens the database, it is called again if the database file changed
+	Open func() (*gokeepasslib.Database, error)
+
+	mu        sync.Mutex
+	db        *gokeepasslib.Database
+	modTime   time.Time
+	listener  net.Listener
+	timer     *time.Timer
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Listen creates the socket with permissions for the current user only.
+// The directory of the socket is created with mode 0700, an existing directory must be owned by
+// the current user and have mode 0700, so no other user can connect or replace the socket.
+// A stale socket of a terminated agent is removed.
+//
+// Returns:
+//   - error: An error if another agent is running, the directory is insecure or the socket can not be created.
+func (s *Server) Listen() error {
+	dir := filepath.Dir(s.Socket)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// the socket is created with the umask, it is only reachable through the checked directory
+	if err := checkSocketDir(dir); err != nil {
+		return err
+	}
+	if _, err := os.Stat(s.Socket); err == nil {
+		if _, err := Status(s.Socket); err == nil {
+			return fmt.Errorf("agent is already running on %s", s.Socket)
+		}
+		debug.Log("Removing stale agent socket %s", s.Socket)
+		os.Remove(s.Socket)
+	}
+	listener, err := net.Listen("unix", s.Socket)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(s.Socket, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+	s.listener = listener
+	s.done = make(chan struct{})
+	return nil
+}
+
+// Serve answers requests until the agent is locked by OpLock, the idle timeout or Close.
+// Listen must be called before.
+//
+// Parameters:
+//   - db: The already opened database, nil to open it with Open.
+//
+// Returns:
+//   - error: An error if the database can not be opened.
+func (s *Server) Serve(db *gokeepasslib.Database) error {
+	s.mu.Lock()
+	if db != nil {
+		s.db = db
+		if info, err := os.Stat(s.DBPath); err == nil {
+			s.modTime = info.ModTime()
+		}
+	} else if err := s.reload(); err != nil {
+		s.mu.Unlock()
+		s.Close()
+		return err
+	}
+	if s.IdleTimeout > 0 {
+		s.timer = time.AfterFunc(s.IdleTimeout, func() {
+			debug.Log("Agent idle timeout reached")
+			s.Close()
+		})
+	}
+	s.mu.Unlock()
+	debug.Log("Agent serving %s on %s", s.DBPath, s.Socket)
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close locks the agent: the database is dropped, the socket removed and Serve returns.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.listener.Close()
+		os.Remove(s.Socket)
+		s.mu.Lock()
+		s.db = nil
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		s.mu.Unlock()
+		debug.Log("Agent locked")
+	})
+}
+
+// reload opens the database, if it was not opened yet or the file changed. s.mu must be held.
+//
+// Returns:
+//   - error: Any error encountered while opening the database.
+func (s *Server) reload() error {
+	info, err := os.Stat(s.DBPath)
+	if err != nil {
+		return err
+	}
+	if s.db != nil && info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+	debug.Log("Agent opening database %s", s.DBPath)
+	db, err := s.Open()
+	if err != nil {
+		return err
+	}
+	s.db = db
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// handle answers one request.
+//
+// Parameters:
+//   - conn: The client connection.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(callTimeout))
+
+	var req Request
+	if err := gob.NewDecoder(conn).Decode(&req); err != nil {
+		debug.Log("Agent failed to read request: %v", err)
+		return
+	}
+	resp := s.respond(req)
+	if err := gob.NewEncoder(conn).Encode(resp); err != nil {
+		debug.Log("Agent failed to send response: %v", err)
+	}
+	if req.Op == OpLock {
+		s.Close()
+	}
+}
+
+// respond builds the response of a request and resets the idle timer.
+//
+// Parameters:
+//   - req: The request of the client.
+//
+// Returns:
+//   - *Response: The response for the client.
+func (s *Server) respond(req Request) *Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Reset(s.IdleTimeout)
+	}
+	resp := &Response{Path: s.DBPath, IdleTimeout: s.IdleTimeout}
+
+	switch req.Op {
+	case OpStatus, OpLock:
+	case OpFetch:
+		if req.Path != s.DBPath {
+			resp.Error = fmt.Sprintf("agent holds the database %s, not %s", s.DBPath, req.Path)
+			break
+		}
+		if s.db == nil {
+			resp.Error = "agent is locked"
+			break
+		}
+		if err := s.reload(); err != nil {
+			resp.Error = fmt.Sprintf("agent failed to reopen the database: %v", err)
+			break
+		}
+		// the credentials and the raw data never leave the agent
+		db := *s.db
+		content := *s.db.Content
+		content.RawData = nil
+		db.Content = &content
+		db.Credentials = nil
+		resp.Database = &db
+	default:
+		resp.Error = fmt.Sprintf("unknown agent operation: %s", req.Op)
+	}
+	return resp
+}
@@ -0,0 +1,187 @@
+// Package batch answers many item/field requests read from a stream with one opened database.
+//
+// Each line is a request, either plain text "item<TAB>field" or a JSON object
+// {"id": ..., "item": "...", "field": "..."}. The field defaults to Password.
+// Plain requests are answered by the value on one line, JSON requests by a JSON object with
+// the value or the error of the request. Empty lines are ignored.
+// Lookup answers a JSON array of requests at once by one JSON object, External the query of a
+// terraform external data source.
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"kpasscli/src/search"
+)
+
+// DefaultField is the field of a request without field name.
+const DefaultField = "Password"
+
+// maxLineSize is the maximum length of a request line
+const maxLineSize = 1024 * 1024
+
+// Request is a JSON request.
+type Request struct {
+	// ID is returned unchanged in the response, e.g. to match responses to requests
+	ID json.RawMessage `json:"id,omitempty"`
+	// Item is the entry as for the get command
+	Item string `json:"item"`
+	// Field is the field of the entry, default DefaultField
+	Field string `json:"field,omitempty"`
+}
+
+// Response is the answer to a JSON request.
+type Response struct {
+	ID    json.RawMessage `json:"id,omitempty"`
+	Item  string          `json:"item"`
+	Field string          `json:"field"`
+	// Value is the value of the field, missing on error
+	Value *string `json:"value,omitempty"`
+	// Error is the error message, missing on success
+	Error string `json:"error,omitempty"`
+}
+
+// Run answers the requests read from r line by line and writes the responses to w.
+// The response of each request is flushed before the next request is read, so kpasscli
+// can be used as co-process. The errors of plain requests are written to errW and answered
+// by an empty line.
+//
+// Parameters:
+//   - r: The reader of the requests, typically stdin.
+//   - w: The writer of the responses, typically stdout.
+//   - errW: The writer of the errors of plain requests, typically stderr.
+//   - finder: The finder used to search the entries.
+//
+// Returns:
+//   - int: The number of failed requests.
+//   - error: An error if reading or writing failed.
+func Run(r io.Reader, w io.Writer, errW io.Writer, finder search.FinderInterface) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	out := bufio.NewWriter(w)
+	failed := 0
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		var err error
+		var ok bool
+		if strings.HasPrefix(strings.TrimSpace(text), "{") {
+			ok, err = answerJSON(out, text, finder)
+		} else {
+			ok, err = answerPlain(out, errW, text, line, finder)
+		}
+		if err != nil {
+			return failed, err
+		}
+		if !ok {
+			failed++
+		}
+		if err := out.Flush(); err != nil {
+			return failed, err
+		}
+	}
+	return failed, scanner.Err()
+}
+
+// lookup returns the value of the field of the entry, which must be found exactly once.
+//
+// Parameters:
+//   - finder: The finder used to search the entries.
+//   - item: The entry as for the get command.
+//   - field: The field of the entry.
+//
+// Returns:
+//   - string: The value of the field.
+//   - error: An error if the item matches none or multiple entries or the field is not found.
+func lookup(finder search.FinderInterface, item, field string) (string, error) {
+	if item == "" {
+		return "", fmt.Errorf("item is required")
+	}
+	results, err := finder.Find(item)
+	if err != nil {
+		return "", fmt.Errorf("Error searching for item: %w", err)
+	}
+	if len(results) == 0 {
+		return "", fmt.Errorf("no items found")
+	}
+	if len(results) > 1 {
+		paths := make([]string, 0, len(results))
+		for _, result := range results {
+			paths = append(paths, result.Path)
+		}
+		return "", fmt.Errorf("multiple items found: %s", strings.Join(paths, ", "))
+	}
+	return results[0].GetField(field)
+}
+
+// answerPlain answers a plain request "item<TAB>field" by the value on one line.
+// Values with line breaks are rejected, because they would break the line-by-line answers.
+//
+// Parameters:
+//   - w: The writer of the responses.
+//   - errW: The writer of the errors.
+//   - text: The request line.
+//   - line: The line number, used in error messages.
+//   - finder: The finder used to search the entries.
+//
+// Returns:
+//   - bool: True if the request was answered successfully.
+//   - error: An error if writing failed.
+func answerPlain(w io.Writer, errW io.Writer, text string, line int, finder search.FinderInterface) (bool, error) {
+	item, field, _ := strings.Cut(text, "\t")
+	if field == "" {
+		field = DefaultField
+	}
+	value, err := lookup(finder, item, field)
+	if err == nil && strings.ContainsAny(value, "\r\n") {
+		err = fmt.Errorf("value of field '%s' contains a line break, use a JSON request", field)
+	}
+	if err != nil {
+		fmt.Fprintf(errW, "line %d: %s: %v\n", line, item, err)
+		_, werr := io.WriteString(w, "\n")
+		return false, werr
+	}
+	_, err = io.WriteString(w, value+"\n")
+	return true, err
+}
+
+// answerJSON answers a JSON request by a JSON object on one line.
+//
+// Parameters:
+//   - w: The writer of the responses.
+//   - text: The request line.
+//   - finder: The finder used to search the entries.
+//
+// Returns:
+//   - bool: True if the request was answered successfully.
+//   - error: An error if writing failed.
+func answerJSON(w io.Writer, text string, finder search.FinderInterface) (bool, error) {
+	var req Request
+	resp := Response{}
+	if err := json.Unmarshal([]byte(text), &req); err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		if req.Field == "" {
+			req.Field = DefaultField
+		}
+		resp.ID, resp.Item, resp.Field = req.ID, req.Item, req.Field
+		value, err := lookup(finder, req.Item, req.Field)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Value = &value
+		}
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return false, err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return resp.Error == "", err
+}
@@ -0,0 +1,93 @@
+package batch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/search"
+)
+
+type fakeFinder struct{}
+
+func (f *fakeFinder) Find(item string) ([]search.Result, error) {
+	entry := func(values ...string) *gokeepasslib.Entry {
+		e := &gokeepasslib.Entry{}
+		for i := 0; i < len(values); i += 2 {
+			e.Values = append(e.Values, gokeepasslib.ValueData{Key: values[i], Value: gokeepasslib.V{Content: values[i+1]}})
+		}
+		return e
+	}
+	switch item {
+	case "DB":
+		return []search.Result{{Path: "/Root/DB", Entry: entry("UserName", "dbuser", "Password", "dbpass", "Notes", "a\nb")}}, nil
+	case "Dup":
+		return []search.Result{{Path: "/Root/A/Dup", Entry: entry()}, {Path: "/Root/B/Dup", Entry: entry()}}, nil
+	case "Broken":
+		return nil, fmt.Errorf("broken")
+	}
+	return nil, nil
+}
+
+func TestRun_Plain(t *testing.T) {
+	in := "DB\tUserName\nDB\n\nMissing\tPassword\nDup\nDB\tNotes\r\nDB\tpassword\n"
+	var out, errOut strings.Builder
+	failed, err := Run(strings.NewReader(in), &out, &errOut, &fakeFinder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 3 {
+		t.Errorf("expected 3 failed requests, got %d", failed)
+	}
+	if want := "dbuser\ndbpass\n\n\n\ndbpass\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+	for _, want := range []string{"line 4: Missing: no items found", "line 5: Dup: multiple items found: /Root/A/Dup, /Root/B/Dup",
+		"line 6: DB: value of field 'Notes' contains a line break"} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("expected %q in errors, got:\n%s", want, errOut.String())
+		}
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	in := `{"id": 1, "item": "DB", "field": "UserName"}
+{"item": "DB", "field": "Notes"}
+{"id": "x", "item": "Missing"}
+{"item": "Broken"}
+{"field": "Password"}
+{"item": 
+`
+	var out, errOut strings.Builder
+	failed, err := Run(strings.NewReader(in), &out, &errOut, &fakeFinder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 4 {
+		t.Errorf("expected 4 failed requests, got %d", failed)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	want := []string{
+		`{"id":1,"item":"DB","field":"UserName","value":"dbuser"}`,
+		`{"item":"DB","field":"Notes","value":"a\nb"}`,
+		`{"id":"x","item":"Missing","field":"Password","error":"no items found"}`,
+		`{"item":"Broken","field":"Password","error":"Error searching for item: broken"}`,
+		`{"item":"","field":"Password","error":"item is required"}`,
+	}
+	if len(lines) != len(want)+1 {
+		t.Fatalf("expected %d responses, got:\n%s", len(want)+1, out.String())
+	}
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("response %d: got %s, want %s", i+1, lines[i], w)
+		}
+	}
+	if !strings.HasPrefix(lines[len(want)], `{"item":"","field":"","error":"invalid request:`) {
+		t.Errorf("expected invalid request error, got %s", lines[len(want)])
+	}
+	if errOut.Len() != 0 {
+		t.Errorf("JSON errors must not be written to stderr, got %q", errOut.String())
+	}
+}
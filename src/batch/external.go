@@ -0,0 +1,46 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"kpasscli/src/search"
+)
+
+// External answers the query of a terraform external data source read from r as JSON object, which maps
+// names to references item[:field], by the JSON object, which maps the names to the values of the fields.
+// The references are parsed by search.ParseRef, the field defaults to Password.
+//
+// Parameters:
+//   - r: The reader of the query, typically stdin.
+//   - w: The writer of the result, typically stdout.
+//   - finder: The finder used to search the entries.
+//
+// Returns:
+//   - error: An error if the query is invalid or any reference failed, nothing is written then.
+func External(r io.Reader, w io.Writer, finder search.FinderInterface) error {
+	var query map[string]string
+	if err := json.NewDecoder(r).Decode(&query); err != nil {
+		return fmt.Errorf("invalid query, expected a JSON object of strings: %w", err)
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make(map[string]string, len(query))
+	for _, name := range names {
+		item, field, err := search.ParseRef(query[name], DefaultField)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		value, err := lookup(finder, item, field)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", name, query[name], err)
+		}
+		result[name] = value
+	}
+	return json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,31 @@
+package batch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExternal(t *testing.T) {
+	var out strings.Builder
+	in := `{"db_user": "DB:UserName", "db_pass": "DB", "notes": "DB:Notes"}`
+	if err := External(strings.NewReader(in), &out, &fakeFinder{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"db_pass":"dbpass","db_user":"dbuser","notes":"a\nb"}` + "\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	tests := map[string]string{
+		`{"a": "DB", "b": "Missing:Password"}`: "b: Missing:Password: no items found",
+		`{"a": "DB:Title"}`:                    "field 'Title' not found",
+		`{"a": 1}`:                             "invalid query",
+		`{"a": "DB:"}`:                         `a: invalid reference "DB:"`,
+	}
+	for in, want := range tests {
+		out.Reset()
+		err := External(strings.NewReader(in), &out, &fakeFinder{})
+		if err == nil || !strings.Contains(err.Error(), want) || out.Len() != 0 {
+			t.Errorf("%s: expected error %q and no output, got %v, %q", in, want, err, out.String())
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"kpasscli/src/search"
+)
+
+// Lookup answers a list of requests read from r as JSON array [{"item": "...", "field": "..."}, ...]
+// by one JSON object, which maps the items to their fields and values:
+// {"item": {"field": "value", ...}, ...}. The field defaults to Password.
+//
+// Parameters:
+//   - r: The reader of the requests, typically stdin.
+//   - w: The writer of the answer, typically stdout.
+//   - finder: The finder used to search the entries.
+//
+// Returns:
+//   - error: An error if the requests are invalid or any request failed, nothing is written then.
+func Lookup(r io.Reader, w io.Writer, finder search.FinderInterface) error {
+	var requests []Request
+	if err := json.NewDecoder(r).Decode(&requests); err != nil {
+		return fmt.Errorf("invalid requests: %w", err)
+	}
+	values := map[string]map[string]string{}
+	for i, req := range requests {
+		if req.Field == "" {
+			req.Field = DefaultField
+		}
+		value, err := lookup(finder, req.Item, req.Field)
+		if err != nil {
+			return fmt.Errorf("request %d: %s: %w", i+1, req.Item, err)
+		}
+		if values[req.Item] == nil {
+			values[req.Item] = map[string]string{}
+		}
+		values[req.Item][req.Field] = value
+	}
+	return json.NewEncoder(w).Encode(values)
+}
@@ -0,0 +1,30 @@
+package batch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	var out strings.Builder
+	in := `[{"item": "DB", "field": "UserName"}, {"item": "DB"}, {"item": "DB", "field": "Notes"}]`
+	if err := Lookup(strings.NewReader(in), &out, &fakeFinder{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"DB":{"Notes":"a\nb","Password":"dbpass","UserName":"dbuser"}}` + "\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	tests := map[string]string{
+		`[{"item": "DB"}, {"item": "Missing"}]`: "request 2: Missing: no items found",
+		`[{"item": "Dup"}]`:                     "multiple items found",
+		`{"item": "DB"}`:                        "invalid requests",
+	}
+	for in, want := range tests {
+		out.Reset()
+		err := Lookup(strings.NewReader(in), &out, &fakeFinder{})
+		if err == nil || !strings.Contains(err.Error(), want) || out.Len() != 0 {
+			t.Errorf("%s: expected error %q and no output, got %v, %q", in, want, err, out.String())
+		}
+	}
+}
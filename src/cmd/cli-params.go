@@ -2,51 +2,356 @@ package cmd
 
 import (
 	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"kpasscli/src/doc"
 )
 
-// parses command-line flags, and supports the following flags:
+// parses the command line, which is either a subcommand with its arguments and options
+// or the flat (legacy) flags, which are an alias for the get command.
+// The options are defined in doc.Options, the subcommands in doc.Commands:
+//
+// get <item> [field]: Output a field of an entry (default: Password)
+// show <item>: Show all fields of an entry
+// ls [group]: List the groups and entries of a group
+// tree [group]: Show the tree of groups and entries below a group
+// search <query>: List the paths of all entries matching the query
+// totp <item>: Output the current TOTP token of an entry
+// clip <item> [field]: Copy a field of an entry to the clipboard
+// add <item> [field=value ...]: Add a new entry
+// set <item> <field=value> ...: Set fields of an entry
+// rm <item>: Move an entry to the recycle bin
+// generate: Generate a random password or diceware passphrase
+// agent: Start the unlock agent, which holds the unlocked database
+// lock: Lock the unlock agent
+// exec -- <command> [args ...]: Run a command with secrets injected into its environment
+// render [template]: Render a template with secret references
+// batch: Answer item/field requests read line by line from stdin
+// git-credential get|store|erase: Git credential helper, entries are matched by their URL field
+// docker-credential get|store|erase|list: Docker credential helper, also run as docker-credential-kpasscli
+// k8s-credential <item> [field]: Output the ExecCredential of an entry for kubectl
+// aws-credential-process <item>: Output the AWS credentials of an entry for credential_process
+// ansible-vault [--vault-id id]: Ansible vault password client, also run as <name>-client
+// lookup: Answer a JSON list of item/field requests by one JSON object
+// tf-external: Terraform external data source, resolves a JSON map of name to item:field
+// export-netrc [group] [-- command [args ...]]: Output a .netrc file, or run a command with it
+// config [print|create]: Print the detected config or create an example config file
+// help [command]: Show the help of kpasscli or of a command
 //
 // -kdbpath | -p: Path to KeePass database file
 // -kdbpassword | -w: Password file or executable to get password
-// -item | -i: Item to search for
+// -keyfile | -k: Key file to unlock the database, alone or together with the password
+// -no-password | -np: Open the database with the key file only, do not ask for a password
+// -item | -i: Item to search for, a path, a name or uuid:<UUID>
 // -fieldname | -f: Field name to retrieve (default: "Password")
 // -out | -o: Output type (clipboard/stdout)
-// -case-sensitive | -c: Enable case-sensitive search
+// -show-all | -a: Show all fields of the item
+// -reveal | -r: Reveal protected values when showing all fields
+// -format | -fmt: Output format of the fields (text/json/yaml/dotenv/shell/raw)
+// -case-sensitive | -cs: Enable case-sensitive search
 // -exact-match | -e: Enable exact match search
+// -regex | -re: Match titles and group names against regular expressions
+// -glob | -gl: Match titles and group names against shell globs, ** matches any number of groups
+// -where | -wh Field=value: Only match entries whose field has the value, Field~value matches a part (repeatable)
+// -fuzzy | -fz: Match the characters of the query in order (like fzf) and rank the results
+// -first | -fi: Use the best ranked match, if its score clearly leads the second best
+// -print-uuid | -pu: Output the UUID of the found entry instead of a field
+// -raw | -rw: Do not resolve KeePass placeholders like {REF:P@I:...} or {USERNAME} in field values
+// -backup | -b: Keep a copy of the previous database file as <database>.bak when saving
+// -generate | -g: Generate the password of the entry (add/set)
+// -length | -l n: Length of the generated password (default: 20)
+// -classes | -cl list: Character classes of the generated password (default: lower,upper,digits,symbols)
+// -min-lower, -min-upper, -min-digits, -min-symbols n: Minimum number of characters per class
+// -exclude-similar | -xs: Exclude look-alike characters from the generated password
+// -words | -wd n: Generate a diceware passphrase with n words
+// -separator | -sep string: Separator between the words of the passphrase (default: -)
+// -socket | -sk path: Path to the agent socket
+// -no-agent | -na: Do not query a running agent
+// -no-interactive | -ni: Fail if the search finds multiple entries, instead of showing a selection list
+// -idle-timeout | -it seconds: Lock the agent after N seconds without requests (default: 900)
+// -foreground | -fg: Run the agent in the foreground
+// -env | -ev NAME=item:field: Environment variable of exec set to a field of an entry (repeatable)
+// -mask | -ms: Mask the injected secrets in the output of the exec command
+// -in path: Template file of render (default: stdin)
+// -vault-id | -vi id: Vault id of ansible-vault
+// -totp-min-validity | -tv n: Wait for the next TOTP token, if the current one expires in less than N seconds
+// -totp-expiry | -te: Output the remaining seconds and the expiry time of the TOTP token
 // -man | -m: Show manual page
 // -help | -h: Show help message
 // -debug | -d: Enable debug logging
-// -config | -c path: Path to configuration file (default: ~/.config/kpasscli/config.yaml)
+// -config | -cf path: Path to configuration file (default: ~/.config/kpasscli/config.yaml)
 // -verify | -v: Enable verify messages
 // -create-config | -cc: Create an example config file
 // -print-config | -pc: print the current detected config to stdout
 
+// Subcommands of kpasscli
+const (
+	CommandGet                  = "get"
+	CommandShow                 = "show"
+	CommandLs                   = "ls"
+	CommandTree                 = "tree"
+	CommandSearch               = "search"
+	CommandTotp                 = "totp"
+	CommandClip                 = "clip"
+	CommandAdd                  = "add"
+	CommandSet                  = "set"
+	CommandRm                   = "rm"
+	CommandGenerate             = "generate"
+	CommandAgent                = "agent"
+	CommandLock                 = "lock"
+	CommandExec                 = "exec"
+	CommandRender               = "render"
+	CommandBatch                = "batch"
+	CommandGitCredential        = "git-credential"
+	CommandDockerCredential     = "docker-credential"
+	CommandK8sCredential        = "k8s-credential"
+	CommandAWSCredentialProcess = "aws-credential-process"
+	CommandAnsibleVault         = "ansible-vault"
+	CommandLookup               = "lookup"
+	CommandTfExternal           = "tf-external"
+	CommandExportNetrc          = "export-netrc"
+	CommandConfig               = "config"
+	CommandHelp                 = "help"
+)
+
+// Name prefix of docker credential helpers and name suffix of ansible vault password clients, see commandLine
+const (
+	dockerHelperPrefix = "docker-credential-"
+	vaultClientSuffix  = "-client"
+)
+
 type Flags struct {
-	KdbPath        string
-	KdbPassword    string
-	Item           string
-	FieldName      string
-	Out            string
-	ConfigPath     string
-	ClearAfter     int
-	CaseSensitive  bool
-	ExactMatch     bool
-	ShowMan        bool
-	ShowHelp       bool
-	DebugFlag      bool
-	VerifyFlag     bool
-	CreateConfig   bool
-	PrintConfig    bool
-	ShowAll        bool
-	PasswordTotp   bool
-	TotpFlag       bool
-	ClearClipboard bool
-	Clipboard      bool
+	// Command is the subcommand, empty for the flat (legacy) flags, which behave like CommandGet
+	Command string
+	// Args are the positional arguments after the subcommand
+	Args            []string
+	KdbPath         string
+	KdbPassword     string
+	KeyFile         string
+	NoPassword      bool
+	Item            string
+	FieldName       string
+	Out             string
+	ConfigPath      string
+	ClearAfter      int
+	CaseSensitive   bool
+	ExactMatch      bool
+	Regex           bool
+	Glob            bool
+	ShowMan         bool
+	ShowHelp        bool
+	DebugFlag       bool
+	VerifyFlag      bool
+	CreateConfig    bool
+	PrintConfig     bool
+	ShowAll         bool
+	Reveal          bool
+	Format          string
+	PasswordTotp    bool
+	TotpFlag        bool
+	TotpMinValidity int
+	TotpExpiry      bool
+	ClearClipboard  bool
+	Clipboard       bool
+	Backup          bool
+	Generate        bool
+	Length          int
+	Classes         string
+	MinLower        int
+	MinUpper        int
+	MinDigits       int
+	MinSymbols      int
+	ExcludeSimilar  bool
+	Words           int
+	Separator       string
+	AgentSocket     string
+	NoAgent         bool
+	NoInteractive   bool
+	IdleTimeout     int
+	Foreground      bool
+	AgentServe      bool
+	Env             []string
+	Where           []string
+	Fuzzy           bool
+	First           bool
+	PrintUUID       bool
+	Raw             bool
+	Mask            bool
+	VaultID         string
+	In              string
+}
+
+// stringList is a flag.Value collecting the values of a repeatable option.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// targets returns the destinations of all options, keyed by the long option name of doc.Options.
+//
+// Returns:
+//   - map[string]interface{}: Pointers to the Flags fields (*string, *bool, *int or *[]string).
+func (flags *Flags) targets() map[string]interface{} {
+	return map[string]interface{}{
+		"kdbpath":           &flags.KdbPath,
+		"kdbpassword":       &flags.KdbPassword,
+		"keyfile":           &flags.KeyFile,
+		"no-password":       &flags.NoPassword,
+		"config":            &flags.ConfigPath,
+		"item":              &flags.Item,
+		"fieldname":         &flags.FieldName,
+		"show-all":          &flags.ShowAll,
+		"reveal":            &flags.Reveal,
+		"format":            &flags.Format,
+		"out":               &flags.Out,
+		"clipboard":         &flags.Clipboard,
+		"password-totp":     &flags.PasswordTotp,
+		"totp":              &flags.TotpFlag,
+		"totp-min-validity": &flags.TotpMinValidity,
+		"totp-expiry":       &flags.TotpExpiry,
+		"clear-after":       &flags.ClearAfter,
+		"case-sensitive":    &flags.CaseSensitive,
+		"exact-match":       &flags.ExactMatch,
+		"regex":             &flags.Regex,
+		"glob":              &flags.Glob,
+		"where":             &flags.Where,
+		"fuzzy":             &flags.Fuzzy,
+		"first":             &flags.First,
+		"print-uuid":        &flags.PrintUUID,
+		"raw":               &flags.Raw,
+		"create-config":     &flags.CreateConfig,
+		"print-config":      &flags.PrintConfig,
+		"verify":            &flags.VerifyFlag,
+		"debug":             &flags.DebugFlag,
+		"man":               &flags.ShowMan,
+		"help":              &flags.ShowHelp,
+		"clear-clipboard":   &flags.ClearClipboard,
+		"backup":            &flags.Backup,
+		"generate":          &flags.Generate,
+		"length":            &flags.Length,
+		"classes":           &flags.Classes,
+		"min-lower":         &flags.MinLower,
+		"min-upper":         &flags.MinUpper,
+		"min-digits":        &flags.MinDigits,
+		"min-symbols":       &flags.MinSymbols,
+		"exclude-similar":   &flags.ExcludeSimilar,
+		"words":             &flags.Words,
+		"separator":         &flags.Separator,
+		"socket":            &flags.AgentSocket,
+		"no-agent":          &flags.NoAgent,
+		"no-interactive":    &flags.NoInteractive,
+		"idle-timeout":      &flags.IdleTimeout,
+		"foreground":        &flags.Foreground,
+		"agent-serve":       &flags.AgentServe,
+		"env":               &flags.Env,
+		"mask":              &flags.Mask,
+		"in":                &flags.In,
+		"vault-id":          &flags.VaultID,
+	}
+}
+
+// registerFlags defines the named options of doc.Options with their shorthands on the FlagSet.
+//
+// Parameters:
+//   - fs: The FlagSet to define the flags on.
+//   - flags: The Flags struct receiving the values.
+//   - names: The long names of the options to define.
+func registerFlags(fs *flag.FlagSet, flags *Flags, names []string) {
+	targets := flags.targets()
+	for _, name := range names {
+		o, ok := doc.LookupOption(name)
+		if !ok {
+			continue
+		}
+		flagNames := []string{o.Name}
+		if o.Short != "" {
+			flagNames = append(flagNames, o.Short)
+		}
+		for i, n := range flagNames {
+			usage := o.Usage
+			if i > 0 {
+				usage += " (shorthand)"
+			}
+			switch p := targets[name].(type) {
+			case *string:
+				fs.StringVar(p, n, o.Default, usage)
+			case *bool:
+				fs.BoolVar(p, n, o.Default == "true", usage)
+			case *int:
+				def, _ := strconv.Atoi(o.Default)
+				fs.IntVar(p, n, def, usage)
+			case *[]string:
+				fs.Var((*stringList)(p), n, usage)
+			}
+		}
+	}
+}
+
+// splitCommand separates the subcommand from its arguments.
+// If the first argument is not a known subcommand, the flat (legacy) flags are used,
+// which are an alias for the get command.
+//
+// Parameters:
+//   - args: The command-line arguments (typically os.Args[1:]).
+//
+// Returns:
+//   - string: The subcommand, empty for the flat flags.
+//   - []string: The remaining arguments.
+func splitCommand(args []string) (string, []string) {
+	if len(args) > 0 {
+		if _, ok := doc.LookupCommand(args[0]); ok {
+			return args[0], args[1:]
+		}
+	}
+	return "", args
+}
+
+// parseInterspersed parses flags and positional arguments in any order,
+// e.g. "get Account -f UserName". Everything after "--" is positional.
+//
+// Parameters:
+//   - fs: The FlagSet with the defined flags.
+//   - args: The arguments to parse.
+//
+// Returns:
+//   - []string: The positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return append(positional, fs.Args()...)
+		}
+		rest := fs.Args()
+		consumed := len(args) - len(rest)
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...)
+		}
+		if len(rest) == 0 {
+			return positional
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
 }
 
-// ParseFlags parses command-line flags from the provided FlagSet and arguments.
+// ParseFlags parses the command line from the provided FlagSet and arguments.
+//
+// The first argument may be a subcommand (see doc.Commands), which only accepts its own and the
+// global options. Without a subcommand all options are accepted and the get command is used.
+// The positional arguments of get, show, totp and clip are the item and optional field name,
+// of ls and tree the group, of search the query and of render the template. For add, set and rm the first positional
+// argument is the item and Args keeps the field assignments. For exec the options end at the
+// first positional argument or "--" and Args is the command to run. For export-netrc the group is
+// the positional argument before "--" and Args is the command after it.
 //
 // Parameters:
 //   - fs: The FlagSet to define and parse flags on.
@@ -56,69 +361,71 @@ type Flags struct {
 //   - *Flags: The parsed Flags struct with all options set.
 //
 // For production, use ParseFlagsDefault().
-func parseFlags(fs *flag.FlagSet, args []string) *Flags {
+func ParseFlags(fs *flag.FlagSet, args []string) *Flags {
 	flags := &Flags{}
-	fs.StringVar(&flags.KdbPath, "kdbpath", "", "Path to KeePass database file")
-	fs.StringVar(&flags.KdbPath, "p", "", "Path to KeePass database file (shorthand)")
-
-	fs.StringVar(&flags.KdbPassword, "kdbpassword", "", "Password file or executable to get password")
-	fs.StringVar(&flags.KdbPassword, "w", "", "Password file or executable to get password (shorthand)")
-
-	fs.StringVar(&flags.Item, "item", "", "Item to search for")
-	fs.StringVar(&flags.Item, "i", "", "Item to search for (shorthand)")
-
-	fs.StringVar(&flags.FieldName, "fieldname", "Password", "Field name to retrieve")
-	fs.StringVar(&flags.FieldName, "f", "Password", "Field name to retrieve (shorthand)")
-
-	fs.StringVar(&flags.Out, "out", "", "Output type (clipboard/stdout)")
-	fs.StringVar(&flags.Out, "o", "", "Output type (clipboard/stdout) (shorthand)")
-
-	fs.IntVar(&flags.ClearAfter, "clear-after", 20, "Clear clipboard after N seconds")
-	fs.IntVar(&flags.ClearAfter, "ca", 20, "Clear clipboard after N seconds (shorthand)")
-
-	fs.BoolVar(&flags.Clipboard, "clipboard", false, "Output to clipboard")
-	fs.BoolVar(&flags.Clipboard, "c", false, "Output to clipboard (shorthand)")
-
-	fs.BoolVar(&flags.CaseSensitive, "case-sensitive", false, "Enable case-sensitive search")
-	fs.BoolVar(&flags.CaseSensitive, "cs", false, "Enable case-sensitive search (shorthand)")
-
-	fs.BoolVar(&flags.ExactMatch, "exact-match", false, "Enable exact match search")
-	fs.BoolVar(&flags.ExactMatch, "e", false, "Enable exact match search (shorthand)")
-
-	flag.BoolVar(&flags.ShowAll, "show-all", false, "Show all entries of an item.")
-	flag.BoolVar(&flags.ShowAll, "a", false, "Show all entries of an item. (shorthand)")
-
-	fs.BoolVar(&flags.ShowMan, "man", false, "Show manual page")
-	fs.BoolVar(&flags.ShowMan, "m", false, "Show manual page (shorthand)")
-
-	fs.BoolVar(&flags.ShowHelp, "help", false, "Show help message")
-	fs.BoolVar(&flags.ShowHelp, "h", false, "Show help message (shorthand)")
+	command, args := splitCommand(args)
+	registerFlags(fs, flags, doc.OptionNames(command))
+	if command == "" {
+		fs.Usage = doc.ShowHelp
+		fs.Parse(args) // Parse the flags from the provided args. This is implemented to test the ParseFlags function.
+		flags.Args = fs.Args()
+	} else if command == CommandExportNetrc {
+		// the options of the command after "--" must not be parsed
+		fs.Usage = func() { doc.ShowCommandHelp(command) }
+		own, child := args, []string(nil)
+		for i, arg := range args {
+			if arg == "--" {
+				own, child = args[:i], args[i+1:]
+				break
+			}
+		}
+		positional := parseInterspersed(fs, own)
+		if len(positional) > 0 {
+			flags.Item = positional[0]
+		}
+		flags.Args = child
+	} else if command == CommandExec {
+		// the options of the executed command must not be parsed
+		fs.Usage = func() { doc.ShowCommandHelp(command) }
+		fs.Parse(args)
+		flags.Args = fs.Args()
+	} else {
+		fs.Usage = func() { doc.ShowCommandHelp(command) }
+		flags.Args = parseInterspersed(fs, args)
+	}
+	flags.Command = command
 
-	fs.BoolVar(&flags.VerifyFlag, "verify", false, "Enable verify message")
-	fs.BoolVar(&flags.VerifyFlag, "v", false, "Enable verify message (shorthand)")
-
-	fs.BoolVar(&flags.DebugFlag, "debug", false, "Enable debug logging")
-	fs.BoolVar(&flags.DebugFlag, "d", false, "Enable debug logging (shorthand)")
-
-	fs.BoolVar(&flags.CreateConfig, "create-config", false, "Create an example config file")
-	fs.BoolVar(&flags.CreateConfig, "cc", false, "Create an example config file (shorthand)")
-
-	fs.BoolVar(&flags.PrintConfig, "print-config", false, "Print current configuration")
-	fs.BoolVar(&flags.PrintConfig, "pc", false, "Print current configuration (shorthand)")
-
-	fs.StringVar(&flags.ConfigPath, "config", "~/.config/kpasscli/config.yaml", "Path to configuration file")
-	fs.StringVar(&flags.ConfigPath, "cf", "~/.config/kpasscli/config.yaml", "Path to configuration file (shorthand)")
-
-	fs.BoolVar(&flags.PasswordTotp, "password-totp", false, "Append TOTP to password")
-	fs.BoolVar(&flags.PasswordTotp, "pt", false, "Append TOTP to password (shorthand)")
-
-	fs.BoolVar(&flags.TotpFlag, "totp", false, "Output TOTP token")
-	fs.BoolVar(&flags.TotpFlag, "t", false, "Output TOTP token (shorthand)")
-
-	fs.BoolVar(&flags.ClearClipboard, "clear-clipboard", false, "Clear clipboard (internal use)")
-
-	fs.Usage = doc.ShowHelp
-	fs.Parse(args) // Parse the flags from the provided args. This is implemented to test the ParseFlags function.
+	switch command {
+	case CommandGet, CommandShow, CommandTotp, CommandClip, CommandK8sCredential:
+		if flags.Item == "" && len(flags.Args) > 0 {
+			flags.Item = flags.Args[0]
+		}
+		if len(flags.Args) > 1 && command != CommandShow && command != CommandTotp {
+			flags.FieldName = flags.Args[1]
+		}
+	case CommandLs, CommandTree, CommandSearch, CommandAWSCredentialProcess:
+		if len(flags.Args) > 0 {
+			flags.Item = flags.Args[0]
+		}
+	case CommandRender:
+		if flags.In == "" && len(flags.Args) > 0 {
+			flags.In = flags.Args[0]
+		}
+	case CommandAdd, CommandSet, CommandRm:
+		// the remaining arguments are the field assignments
+		if flags.Item == "" && len(flags.Args) > 0 {
+			flags.Item = flags.Args[0]
+			flags.Args = flags.Args[1:]
+		}
+	}
+	switch command {
+	case CommandShow:
+		flags.ShowAll = true
+	case CommandTotp:
+		flags.TotpFlag = true
+	case CommandClip:
+		flags.Clipboard = true
+	}
 
 	return flags
 }
@@ -131,6 +438,28 @@ func parseFlags(fs *flag.FlagSet, args []string) *Flags {
 // Returns:
 //   - *Flags: The parsed Flags struct with all options set.
 func ParseFlagsDefault() *Flags {
-	flags := parseFlags(flag.CommandLine, nil)
+	flags := ParseFlags(flag.CommandLine, commandLine(os.Args[0], os.Args[1:]))
 	return flags
 }
+
+// commandLine returns the arguments to parse. If kpasscli is called by the name of a docker
+// credential helper (docker-credential-kpasscli), the arguments are those of the docker-credential command,
+// if it is called by the name of an ansible vault password client (e.g. kpasscli-vault-client), those of
+// the ansible-vault command.
+//
+// Parameters:
+//   - name: The name kpasscli is called by (os.Args[0]).
+//   - args: The command-line arguments without the name.
+//
+// Returns:
+//   - []string: The arguments to parse.
+func commandLine(name string, args []string) []string {
+	base := strings.TrimSuffix(filepath.Base(name), ".exe")
+	if strings.HasPrefix(base, dockerHelperPrefix) {
+		return append([]string{CommandDockerCredential}, args...)
+	}
+	if strings.HasSuffix(strings.TrimSuffix(base, ".py"), vaultClientSuffix) {
+		return append([]string{CommandAnsibleVault}, args...)
+	}
+	return args
+}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"flag"
 	"testing"
+
+	"kpasscli/src/doc"
 )
 
 func TestParseFlags_NoArgs(t *testing.T) {
@@ -48,7 +50,7 @@ func TestParseFlags_LongFlags(t *testing.T) {
 
 func TestParseFlags_ShortFlags(t *testing.T) {
 	fs := flag.NewFlagSet("test", flag.ContinueOnError)
-	args := []string{"-p", "db.kdbx", "-w", "pw.txt", "-i", "Entry", "-f", "UserName", "-o", "stdout", "-c", "cfg.yaml", "-cs", "-e", "-m", "-h", "-d", "-v", "-cc", "-pc"}
+	args := []string{"-p", "db.kdbx", "-w", "pw.txt", "-i", "Entry", "-f", "UserName", "-o", "stdout", "-cf", "cfg.yaml", "-cs", "-e", "-m", "-h", "-d", "-v", "-cc", "-pc"}
 	flags := ParseFlags(fs, args)
 	if flags.KdbPath != "db.kdbx" {
 		t.Errorf("expected KdbPath 'db.kdbx', got '%v'", flags.KdbPath)
@@ -72,3 +74,174 @@ func TestParseFlags_ShortFlags(t *testing.T) {
 		t.Error("expected all bool flags to be true")
 	}
 }
+
+func TestParseFlags_KeyFile(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := ParseFlags(fs, []string{"-keyfile", "db.keyx", "-np"})
+	if flags.KeyFile != "db.keyx" {
+		t.Errorf("expected KeyFile 'db.keyx', got '%v'", flags.KeyFile)
+	}
+	if !flags.NoPassword {
+		t.Error("expected NoPassword to be true")
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags = ParseFlags(fs, []string{"-k", "db.key"})
+	if flags.KeyFile != "db.key" {
+		t.Errorf("expected KeyFile 'db.key', got '%v'", flags.KeyFile)
+	}
+}
+
+func TestParseFlags_Commands(t *testing.T) {
+	tests := []struct {
+		args      []string
+		command   string
+		item      string
+		fieldName string
+	}{
+		{[]string{"get", "Account"}, CommandGet, "Account", "Password"},
+		{[]string{"get", "Account", "UserName"}, CommandGet, "Account", "UserName"},
+		{[]string{"get", "-p", "db.kdbx", "Account", "-f", "URL"}, CommandGet, "Account", "URL"},
+		{[]string{"get", "--", "-Account"}, CommandGet, "-Account", "Password"},
+		{[]string{"clip", "/Root/Account", "UserName"}, CommandClip, "/Root/Account", "UserName"},
+		{[]string{"ls", "/Root/Banking"}, CommandLs, "/Root/Banking", ""},
+		{[]string{"tree"}, CommandTree, "", ""},
+		{[]string{"search", "Acc"}, CommandSearch, "Acc", ""},
+		{[]string{"-i", "Account"}, "", "Account", "Password"},
+	}
+	for _, tc := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := ParseFlags(fs, tc.args)
+		if flags.Command != tc.command || flags.Item != tc.item || flags.FieldName != tc.fieldName {
+			t.Errorf("%v: got command %q, item %q, field %q", tc.args, flags.Command, flags.Item, flags.FieldName)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if flags := ParseFlags(fs, []string{"show", "Account", "-r"}); !flags.ShowAll || !flags.Reveal {
+		t.Error("expected show to set ShowAll and Reveal")
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	if flags := ParseFlags(fs, []string{"totp", "Account"}); !flags.TotpFlag {
+		t.Error("expected totp to set TotpFlag")
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	if flags := ParseFlags(fs, []string{"clip", "Account"}); !flags.Clipboard {
+		t.Error("expected clip to set Clipboard")
+	}
+}
+
+func TestFlags_TargetsCoverOptions(t *testing.T) {
+	targets := (&Flags{}).targets()
+	for _, o := range doc.Options {
+		if _, ok := targets[o.Name]; !ok {
+			t.Errorf("option %q has no Flags field", o.Name)
+		}
+	}
+}
+
+func TestParseFlags_WriteCommands(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := ParseFlags(fs, []string{"set", "Account", "UserName=tester", "-b", "URL=https://example.com"})
+	if flags.Command != CommandSet || flags.Item != "Account" || !flags.Backup {
+		t.Errorf("unexpected flags: %+v", flags)
+	}
+	if len(flags.Args) != 2 || flags.Args[0] != "UserName=tester" || flags.Args[1] != "URL=https://example.com" {
+		t.Errorf("expected field assignments in Args, got %v", flags.Args)
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags = ParseFlags(fs, []string{"add", "-i", "/Root/New", "Notes=n"})
+	if flags.Item != "/Root/New" || len(flags.Args) != 1 || flags.Args[0] != "Notes=n" {
+		t.Errorf("unexpected flags: %+v", flags)
+	}
+}
+
+func TestParseFlags_Exec(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := ParseFlags(fs, []string{"exec", "--env", "DB_PASS=/Prod/DB:Password", "-ev", "DB_USER=DB:UserName", "-ms",
+		"--", "./deploy.sh", "-v", "--env", "x"})
+	if flags.Command != CommandExec || !flags.Mask || flags.VerifyFlag {
+		t.Errorf("unexpected flags: %+v", flags)
+	}
+	if len(flags.Env) != 2 || flags.Env[0] != "DB_PASS=/Prod/DB:Password" || flags.Env[1] != "DB_USER=DB:UserName" {
+		t.Errorf("unexpected env references: %v", flags.Env)
+	}
+	if len(flags.Args) != 4 || flags.Args[0] != "./deploy.sh" || flags.Args[1] != "-v" {
+		t.Errorf("expected the command in Args, got %v", flags.Args)
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags = ParseFlags(fs, []string{"exec", "-env", "A=B", "env", "-v"})
+	if len(flags.Args) != 2 || flags.Args[0] != "env" || flags.VerifyFlag {
+		t.Errorf("options after the command must not be parsed: %+v", flags)
+	}
+}
+
+func TestCommandLine_Helpers(t *testing.T) {
+	args := commandLine("/usr/local/bin/docker-credential-kpasscli", []string{"get"})
+	if len(args) != 2 || args[0] != CommandDockerCredential || args[1] != "get" {
+		t.Errorf("expected docker-credential command, got %v", args)
+	}
+	if args := commandLine("docker-credential-kpasscli.exe", []string{"list"}); len(args) != 2 || args[0] != CommandDockerCredential {
+		t.Errorf("expected docker-credential command for the exe, got %v", args)
+	}
+	if args := commandLine("/home/u/bin/kpasscli-vault-client", []string{"--vault-id", "prod"}); len(args) != 3 || args[0] != CommandAnsibleVault {
+		t.Errorf("expected ansible-vault command, got %v", args)
+	}
+	if args := commandLine("kpasscli", []string{"get", "Account"}); len(args) != 2 || args[0] != "get" {
+		t.Errorf("expected unchanged arguments, got %v", args)
+	}
+	flags := ParseFlags(flag.NewFlagSet("test", flag.ContinueOnError), commandLine("docker-credential-kpasscli", []string{"store"}))
+	if flags.Command != CommandDockerCredential || len(flags.Args) != 1 || flags.Args[0] != "store" {
+		t.Errorf("unexpected flags: %+v", flags)
+	}
+}
+
+func TestParseFlags_VaultID(t *testing.T) {
+	flags := ParseFlags(flag.NewFlagSet("test", flag.ContinueOnError), commandLine("kpasscli-vault-client", []string{"--vault-id", "prod"}))
+	if flags.Command != CommandAnsibleVault || flags.VaultID != "prod" || flags.FieldName != "Password" {
+		t.Errorf("unexpected flags: %+v", flags)
+	}
+}
+
+func TestParseFlags_ExportNetrc(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := ParseFlags(fs, []string{"export-netrc", "Servers", "-d", "--", "curl", "-v", "--netrc"})
+	if flags.Command != CommandExportNetrc || flags.Item != "Servers" || !flags.DebugFlag {
+		t.Errorf("unexpected flags: %+v", flags)
+	}
+	if len(flags.Args) != 3 || flags.Args[0] != "curl" || flags.VerifyFlag {
+		t.Errorf("expected the command in Args, got %v", flags.Args)
+	}
+	flags = ParseFlags(flag.NewFlagSet("test", flag.ContinueOnError), []string{"export-netrc"})
+	if flags.Item != "" || len(flags.Args) != 0 {
+		t.Errorf("expected no group and no command: %+v", flags)
+	}
+}
+
+func TestParseFlags_PatternSearch(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := ParseFlags(fs, []string{"search", "-re", "^db-[0-9]$"})
+	if !flags.Regex || flags.Glob || flags.Item != "^db-[0-9]$" {
+		t.Errorf("unexpected flags: regex=%v glob=%v item=%q", flags.Regex, flags.Glob, flags.Item)
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags = ParseFlags(fs, []string{"get", "Prod/**/db-*", "-gl"})
+	if !flags.Glob || flags.Regex || flags.Item != "Prod/**/db-*" {
+		t.Errorf("unexpected flags: regex=%v glob=%v item=%q", flags.Regex, flags.Glob, flags.Item)
+	}
+}
+
+func TestParseFlags_Where(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := ParseFlags(fs, []string{"search", "-where", "UserName=svc-deploy", "-wh", "URL~gitlab"})
+	if flags.Item != "" || len(flags.Where) != 2 || flags.Where[0] != "UserName=svc-deploy" || flags.Where[1] != "URL~gitlab" {
+		t.Errorf("unexpected flags: item=%q where=%v", flags.Item, flags.Where)
+	}
+}
+
+func TestParseFlags_TotpValidity(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := ParseFlags(fs, []string{"totp", "VPN", "-totp-min-validity", "5", "-te"})
+	if !flags.TotpFlag || flags.Item != "VPN" || flags.TotpMinValidity != 5 || !flags.TotpExpiry {
+		t.Errorf("unexpected flags: totp=%v item=%q min-validity=%d expiry=%v", flags.TotpFlag, flags.Item, flags.TotpMinValidity, flags.TotpExpiry)
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"os/exec"
+
+	"kpasscli/src/debug"
+)
+
+// StartDetached starts the current kpasscli executable with the given arguments as a process,
+// which is detached from the terminal and keeps running after the main process exits.
+//
+// Parameters:
+//   - args: The arguments of the new process.
+//   - stdin: The standard input of the new process, e.g. to pass a password without using the command line.
+//
+// Returns:
+//   - *exec.Cmd: The started process.
+//   - error: Any error encountered while starting the process.
+func StartDetached(args []string, stdin io.Reader) (*exec.Cmd, error) {
+	executablePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(executablePath, args...)
+	cmd.Stdin = stdin
+	setDetachedProcessAttributes(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	debug.Log("Detached process started (PID: %d): %v", cmd.Process.Pid, args)
+	return cmd, nil
+}
@@ -18,8 +18,29 @@ type Config struct {
 	DefaultOutput      string `yaml:"default_output"`
 	PasswordFile       string `yaml:"password_file"`
 	PasswordExecutable string `yaml:"password_executable"`
-	ConfigfilePath     string `yaml:"configfile_path"`
-	OutputFormat       string `yaml:"output_format"`
+	// KeyFile is the path to a key file used together with or instead of the password
+	KeyFile        string `yaml:"key_file"`
+	ConfigfilePath string `yaml:"configfile_path"`
+	// OutputFormat specifies the default output format (text/json/yaml/dotenv/shell/raw)
+	OutputFormat string `yaml:"output_format"`
+	// Backup keeps a copy of the previous database file as <database>.bak when saving
+	Backup bool `yaml:"backup"`
+	// GitCredentialGroup is the group, in which git-credential store creates new entries (default: Git)
+	GitCredentialGroup string `yaml:"git_credential_group"`
+	// GitCredentialErase allows git-credential erase to remove entries with rejected passwords
+	GitCredentialErase bool `yaml:"git_credential_erase"`
+	// DockerCredentialGroup is the group of the registry entries of docker-credential (default: Registries)
+	DockerCredentialGroup string `yaml:"docker_credential_group"`
+	// AWSAccessKeyIDField is the field of the access key id of aws-credential-process (default: AccessKeyId)
+	AWSAccessKeyIDField string `yaml:"aws_access_key_id_field"`
+	// AWSSecretAccessKeyField is the field of the secret access key of aws-credential-process (default: SecretAccessKey)
+	AWSSecretAccessKeyField string `yaml:"aws_secret_access_key_field"`
+	// AWSSessionTokenField is the field of the optional session token of aws-credential-process (default: SessionToken)
+	AWSSessionTokenField string `yaml:"aws_session_token_field"`
+	// AnsibleVaultGroup is the group of the vault passwords of ansible-vault (default: Ansible)
+	AnsibleVaultGroup string `yaml:"ansible_vault_group"`
+	// NetrcGroup is the group exported by export-netrc (default: the root group)
+	NetrcGroup string `yaml:"netrc_group"`
 }
 
 // Load reads and parses the configuration file from the given path.
@@ -50,7 +71,6 @@ func Load(configPath string) (*Config, error) {
 	// config.ConfigfilePath = configPath
 	y, _ := yaml.Marshal(config)
 	debug.Log("Loaded config: %v\n", string(y))
-	config.OutputFormat = "text"
 	return &config, nil
 }
 
@@ -80,7 +100,18 @@ func (c *Config) Print() {
 	fmt.Fprintf(os.Stderr, "------------------------------------------\n")
 	fmt.Fprintf(os.Stderr, "Database Path: %s\n", c.DatabasePath)
 	fmt.Fprintf(os.Stderr, "Default Output: %s\n", c.DefaultOutput)
+	fmt.Fprintf(os.Stderr, "Output Format: %s\n", c.OutputFormat)
 	fmt.Fprintf(os.Stderr, "Password File: %s\n", c.PasswordFile)
 	fmt.Fprintf(os.Stderr, "Password Executable: %s\n", c.PasswordExecutable)
+	fmt.Fprintf(os.Stderr, "Key File: %s\n", c.KeyFile)
+	fmt.Fprintf(os.Stderr, "Backup: %t\n", c.Backup)
+	fmt.Fprintf(os.Stderr, "Git Credential Group: %s\n", c.GitCredentialGroup)
+	fmt.Fprintf(os.Stderr, "Git Credential Erase: %t\n", c.GitCredentialErase)
+	fmt.Fprintf(os.Stderr, "Docker Credential Group: %s\n", c.DockerCredentialGroup)
+	fmt.Fprintf(os.Stderr, "AWS Access Key ID Field: %s\n", c.AWSAccessKeyIDField)
+	fmt.Fprintf(os.Stderr, "AWS Secret Access Key Field: %s\n", c.AWSSecretAccessKeyField)
+	fmt.Fprintf(os.Stderr, "AWS Session Token Field: %s\n", c.AWSSessionTokenField)
+	fmt.Fprintf(os.Stderr, "Ansible Vault Group: %s\n", c.AnsibleVaultGroup)
+	fmt.Fprintf(os.Stderr, "Netrc Group: %s\n", c.NetrcGroup)
 	fmt.Fprintf(os.Stderr, "------------------------------------------\n")
 }
@@ -22,7 +22,7 @@ func TestLoad_ValidFile(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer os.Remove(f.Name())
-	f.WriteString("database_path: test.kdbx\n")
+	f.WriteString("database_path: test.kdbx\nkey_file: test.keyx\n")
 	f.Close()
 	cfg, err := Load(f.Name())
 	if err != nil {
@@ -31,6 +31,9 @@ func TestLoad_ValidFile(t *testing.T) {
 	if cfg.DatabasePath != "test.kdbx" {
 		t.Errorf("expected database_path to be test.kdbx, got %v", cfg.DatabasePath)
 	}
+	if cfg.KeyFile != "test.keyx" {
+		t.Errorf("expected key_file to be test.keyx, got %v", cfg.KeyFile)
+	}
 }
 
 func TestCreateExampleConfig(t *testing.T) {
@@ -0,0 +1,25 @@
+package credential
+
+import "strings"
+
+// DefaultAnsibleVaultGroup is the group of the vault passwords, if no group is configured.
+const DefaultAnsibleVaultGroup = "Ansible"
+
+// DefaultVaultID is the vault id of the vault password, if ansible passes none.
+const DefaultVaultID = "default"
+
+// AnsibleVaultItem returns the item of the vault password of the vault id: the entry titled by
+// the vault id in the group.
+//
+// Parameters:
+//   - group: The group of the vault passwords.
+//   - vaultID: The vault id passed by ansible, empty for the default vault id.
+//
+// Returns:
+//   - string: The item, e.g. "Ansible/prod".
+func AnsibleVaultItem(group, vaultID string) string {
+	if vaultID == "" {
+		vaultID = DefaultVaultID
+	}
+	return strings.TrimSuffix(group, "/") + "/" + strings.ReplaceAll(vaultID, "/", "_")
+}
@@ -0,0 +1,16 @@
+package credential
+
+import "testing"
+
+func TestAnsibleVaultItem(t *testing.T) {
+	tests := []struct{ group, vaultID, want string }{
+		{"Ansible", "prod", "Ansible/prod"},
+		{"/Root/Ansible/", "", "/Root/Ansible/default"},
+		{"Ansible", "a/b", "Ansible/a_b"},
+	}
+	for _, tt := range tests {
+		if got := AnsibleVaultItem(tt.group, tt.vaultID); got != tt.want {
+			t.Errorf("AnsibleVaultItem(%q, %q) = %q, want %q", tt.group, tt.vaultID, got, tt.want)
+		}
+	}
+}
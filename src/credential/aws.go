@@ -0,0 +1,68 @@
+package credential
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kpasscli/src/search"
+)
+
+// AWSFields are the names of the fields holding the AWS credentials.
+type AWSFields struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// DefaultAWSFields returns the default field names AccessKeyId, SecretAccessKey and SessionToken.
+//
+// Returns:
+//   - AWSFields: The default field names.
+func DefaultAWSFields() AWSFields {
+	return AWSFields{AccessKeyID: "AccessKeyId", SecretAccessKey: "SecretAccessKey", SessionToken: "SessionToken"}
+}
+
+// AWSCredentials is the document of the AWS credential_process.
+type AWSCredentials struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	Expiration      string `json:"Expiration,omitempty"`
+}
+
+// NewAWSCredentials creates the credential_process document of the entry. The session token is
+// optional, the expiry time of the entry becomes the Expiration.
+//
+// Parameters:
+//   - result: The entry.
+//   - fields: The names of the fields holding the credentials.
+//   - now: The current time, expired entries are rejected.
+//
+// Returns:
+//   - *AWSCredentials: The credentials with Version 1.
+//   - error: An error if the entry expired or the access key id or secret access key is missing.
+func NewAWSCredentials(result search.Result, fields AWSFields, now time.Time) (*AWSCredentials, error) {
+	expiration, err := expirationTime(result, now)
+	if err != nil {
+		return nil, err
+	}
+	creds := &AWSCredentials{Version: 1, Expiration: expiration}
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{{fields.AccessKeyID, &creds.AccessKeyID}, {fields.SecretAccessKey, &creds.SecretAccessKey}} {
+		value, err := result.GetField(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if *f.value = strings.TrimSpace(value); *f.value == "" {
+			return nil, fmt.Errorf("field '%s' of %s is empty", f.name, result.Path)
+		}
+	}
+	if token, err := result.GetField(fields.SessionToken); err == nil {
+		creds.SessionToken = strings.TrimSpace(token)
+	}
+	return creds, nil
+}
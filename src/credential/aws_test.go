@@ -0,0 +1,53 @@
+package credential
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+
+	"kpasscli/src/search"
+)
+
+func TestNewAWSCredentials(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := gokeepasslib.NewEntry()
+	entry.Values = []gokeepasslib.ValueData{
+		{Key: "Title", Value: gokeepasslib.V{Content: "prod"}},
+		{Key: "AccessKeyId", Value: gokeepasslib.V{Content: "AKIAEXAMPLE"}},
+		{Key: "SecretAccessKey", Value: gokeepasslib.V{Content: "secretkey\n"}},
+	}
+	result := search.Result{Path: "/Root/prod", Entry: &entry}
+
+	creds, err := NewAWSCredentials(result, DefaultAWSFields(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(creds)
+	if want := `{"Version":1,"AccessKeyId":"AKIAEXAMPLE","SecretAccessKey":"secretkey"}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: "Token", Value: gokeepasslib.V{Content: "session"}})
+	entry.Times.Expires = w.NewBoolWrapper(true)
+	entry.Times.ExpiryTime = &w.TimeWrapper{Time: now.Add(time.Hour)}
+	fields := DefaultAWSFields()
+	fields.SessionToken = "Token"
+	creds, err = NewAWSCredentials(result, fields, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.SessionToken != "session" || creds.Expiration != "2026-01-01T13:00:00Z" {
+		t.Errorf("expected session token and expiration, got %+v", creds)
+	}
+	if _, err := NewAWSCredentials(result, fields, now.Add(time.Hour)); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected error for expired entry, got %v", err)
+	}
+	fields.SecretAccessKey = "Missing"
+	if _, err := NewAWSCredentials(result, fields, now); err == nil {
+		t.Error("expected error for missing secret access key")
+	}
+}
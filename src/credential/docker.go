@@ -0,0 +1,219 @@
+package credential
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/debug"
+	"kpasscli/src/keepass"
+	"kpasscli/src/search"
+)
+
+// Actions of the docker credential helper protocol
+const (
+	DockerGet   = "get"
+	DockerStore = "store"
+	DockerErase = "erase"
+	DockerList  = "list"
+)
+
+// DefaultDockerGroup is the group of the registry entries, if no group is configured.
+const DefaultDockerGroup = "Registries"
+
+// ErrDockerNotFound is the error message docker expects, if no credentials are found.
+var ErrDockerNotFound = errors.New("credentials not found in native keychain")
+
+// DockerCredentials is the payload of the docker credential helper protocol.
+type DockerCredentials struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// dockerLookup returns the entry of the registry: the entry, whose URL matches the server URL best,
+// or the entry in the Group, whose title is the host of the server URL. The placeholders of the entry are resolved.
+//
+// Parameters:
+//   - s: The credential store.
+//   - serverURL: The server URL of the registry.
+//
+// Returns:
+//   - search.Result: The found entry.
+//   - error: ErrNotFound, or an error if multiple entries match equally well.
+func dockerLookup(s *Store, serverURL string) (search.Result, error) {
+	result, err := s.Lookup(serverURL, "")
+	if !errors.Is(err, ErrNotFound) {
+		return result, err
+	}
+	u, err := search.ParseURL(serverURL)
+	if err != nil {
+		return search.Result{}, err
+	}
+	group, groupPath, err := search.FindGroup(s.DB, s.Group)
+	if err != nil {
+		return search.Result{}, ErrNotFound
+	}
+	for i := range group.Entries {
+		if strings.EqualFold(group.Entries[i].GetTitle(), u.Host) {
+			entry := group.Entries[i]
+			if err := search.ResolvePlaceholders(s.DB, &entry); err != nil {
+				return search.Result{}, err
+			}
+			return search.NewResult(groupPath+"/"+entry.GetTitle(), &entry), nil
+		}
+	}
+	return search.Result{}, ErrNotFound
+}
+
+// dockerList returns the server URLs and user names of the entries with URL in the Group and its subgroups,
+// except the recycle bin. Entries, whose placeholders cannot be resolved, are skipped with a warning.
+//
+// Parameters:
+//   - s: The credential store.
+//
+// Returns:
+//   - map[string]string: The user names keyed by server URL.
+func dockerList(s *Store) map[string]string {
+	list := map[string]string{}
+	group, groupPath, err := search.FindGroup(s.DB, s.Group)
+	if err != nil {
+		return list
+	}
+	meta := s.DB.Content.Meta
+	skipRecycleBin := meta != nil && meta.RecycleBinEnabled.Bool
+	var walk func(g *gokeepasslib.Group, path string)
+	walk = func(g *gokeepasslib.Group, path string) {
+		if skipRecycleBin && meta.RecycleBinUUID.Compare(g.UUID) {
+			return
+		}
+		for i := range g.Entries {
+			entry := g.Entries[i]
+			result := search.NewResult(path+"/"+entry.GetTitle(), &entry)
+			if err := search.ResolvePlaceholders(s.DB, result.Entry); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping %s, %v\n", result.Path, err)
+				continue
+			}
+			if url, _ := result.GetField("URL"); url != "" {
+				list[url], _ = result.GetField("UserName")
+			}
+		}
+		for i := range g.Groups {
+			walk(&g.Groups[i], path+"/"+g.Groups[i].Name)
+		}
+	}
+	walk(group, groupPath)
+	return list
+}
+
+// RunDocker answers a docker credential helper request read from r.
+// get reads the server URL and writes the credentials as JSON, store reads the credentials as JSON,
+// erase reads the server URL and removes the entry, list writes the server URLs and user names of the
+// entries in the Group as JSON object.
+//
+// Parameters:
+//   - action: The action get, store, erase or list.
+//   - r: The reader of the request, typically stdin.
+//   - w: The writer of the response, typically stdout.
+//   - s: The credential store, erase is ignored unless Erase is set.
+//
+// Returns:
+//   - error: ErrDockerNotFound, or any error encountered while answering the request.
+func RunDocker(action string, r io.Reader, w io.Writer, s *Store) error {
+	switch action {
+	case DockerGet:
+		serverURL, err := readServerURL(r)
+		if err != nil {
+			return err
+		}
+		result, err := dockerLookup(s, serverURL)
+		if errors.Is(err, ErrNotFound) {
+			return ErrDockerNotFound
+		}
+		if err != nil {
+			return err
+		}
+		username, _ := result.GetField("UserName")
+		secret, err := result.GetField("Password")
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(w).Encode(DockerCredentials{ServerURL: serverURL, Username: username, Secret: secret})
+	case DockerStore:
+		var creds DockerCredentials
+		if err := json.NewDecoder(r).Decode(&creds); err != nil {
+			return fmt.Errorf("invalid credentials: %w", err)
+		}
+		if creds.ServerURL == "" {
+			return fmt.Errorf("credentials have no ServerURL")
+		}
+		result, err := dockerLookup(s, creds.ServerURL)
+		if errors.Is(err, ErrNotFound) {
+			u, err := search.ParseURL(creds.ServerURL)
+			if err != nil {
+				return err
+			}
+			return s.add(creds.ServerURL, creds.Username, creds.Secret, u.Host)
+		}
+		if err != nil {
+			return err
+		}
+		var values []keepass.FieldValue
+		if current, _ := result.GetField("UserName"); current != creds.Username {
+			values = append(values, keepass.FieldValue{Key: "UserName", Value: creds.Username})
+		}
+		if current, _ := result.GetField("Password"); current != creds.Secret {
+			values = append(values, keepass.FieldValue{Key: "Password", Value: creds.Secret})
+		}
+		if current, _ := result.GetField("URL"); current == "" {
+			// the entry was found by its title
+			values = append(values, keepass.FieldValue{Key: "URL", Value: creds.ServerURL})
+		}
+		return s.update(creds.ServerURL, result, values)
+	case DockerErase:
+		serverURL, err := readServerURL(r)
+		if err != nil {
+			return err
+		}
+		if !s.Erase {
+			debug.Log("Erasing credentials is disabled, ignoring erase of %s", serverURL)
+			return nil
+		}
+		result, err := dockerLookup(s, serverURL)
+		if errors.Is(err, ErrNotFound) {
+			return ErrDockerNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return s.delete(serverURL, result)
+	case DockerList:
+		return json.NewEncoder(w).Encode(dockerList(s))
+	}
+	return fmt.Errorf("unknown docker credential action: %s", action)
+}
+
+// readServerURL reads the server URL of a get or erase request.
+//
+// Parameters:
+//   - r: The reader of the request.
+//
+// Returns:
+//   - string: The server URL.
+//   - error: An error if reading failed or the request is empty.
+func readServerURL(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	serverURL := strings.TrimSpace(string(data))
+	if serverURL == "" {
+		return "", fmt.Errorf("no server URL given")
+	}
+	return serverURL, nil
+}
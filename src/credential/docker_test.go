@@ -0,0 +1,141 @@
+package credential
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+
+	"kpasscli/src/search"
+)
+
+func runDocker(t *testing.T, s *Store, action, request string) string {
+	t.Helper()
+	var out strings.Builder
+	if err := RunDocker(action, strings.NewReader(request), &out, s); err != nil {
+		t.Fatalf("%s: %v", action, err)
+	}
+	return out.String()
+}
+
+// dockerStore returns the test store with the group Registries, which contains the entry
+// registry.example.com without URL.
+func dockerStore(saves *int) *Store {
+	s := testStore(saves)
+	s.Group = DefaultDockerGroup
+	entry := gokeepasslib.NewEntry()
+	entry.Values = []gokeepasslib.ValueData{
+		{Key: "Title", Value: gokeepasslib.V{Content: "registry.example.com"}},
+		{Key: "UserName", Value: gokeepasslib.V{Content: "robot"}},
+		{Key: "Password", Value: gokeepasslib.V{Content: "robotpw"}},
+	}
+	registries := gokeepasslib.NewGroup()
+	registries.Name = DefaultDockerGroup
+	registries.Entries = []gokeepasslib.Entry{entry}
+	root := &s.DB.Content.Root.Groups[0]
+	root.Groups = append(root.Groups, registries)
+	return s
+}
+
+func TestRunDocker_Get(t *testing.T) {
+	saves := 0
+	s := dockerStore(&saves)
+	tests := []struct {
+		request string
+		want    string
+	}{
+		{"https://github.com\n", `{"ServerURL":"https://github.com","Username":"alice","Secret":"alicepw"}` + "\n"},
+		{"registry.example.com", `{"ServerURL":"registry.example.com","Username":"robot","Secret":"robotpw"}` + "\n"},
+		{"https://Registry.example.com/v2/", `{"ServerURL":"https://Registry.example.com/v2/","Username":"robot","Secret":"robotpw"}` + "\n"},
+	}
+	for _, tt := range tests {
+		if got := runDocker(t, s, DockerGet, tt.request); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.request, got, tt.want)
+		}
+	}
+	err := RunDocker(DockerGet, strings.NewReader("quay.io"), &strings.Builder{}, s)
+	if !errors.Is(err, ErrDockerNotFound) || err.Error() != "credentials not found in native keychain" {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	if err := RunDocker(DockerGet, strings.NewReader("\n"), &strings.Builder{}, s); err == nil {
+		t.Error("expected error for empty server URL")
+	}
+	if err := RunDocker("version", strings.NewReader(""), &strings.Builder{}, s); err == nil {
+		t.Error("expected error for unknown action")
+	}
+	if saves != 0 {
+		t.Errorf("get must not save the database")
+	}
+}
+
+func TestRunDocker_List(t *testing.T) {
+	saves := 0
+	s := dockerStore(&saves)
+	registries := &s.DB.Content.Root.Groups[0].Groups[len(s.DB.Content.Root.Groups[0].Groups)-1]
+	entry := func(title, user, url string) gokeepasslib.Entry {
+		e := gokeepasslib.NewEntry()
+		e.Values = []gokeepasslib.ValueData{
+			{Key: "Title", Value: gokeepasslib.V{Content: title}},
+			{Key: "UserName", Value: gokeepasslib.V{Content: user}},
+			{Key: "URL", Value: gokeepasslib.V{Content: url}},
+		}
+		return e
+	}
+	registries.Entries = append(registries.Entries,
+		entry("quay.io", "{REF:U@T:registry.example.com}", "https://{TITLE}"),
+		entry("Broken", "bob", "https://broken.example.com/{URL}"),
+	)
+	bin := gokeepasslib.NewGroup()
+	bin.Name = "Recycle Bin"
+	bin.Entries = []gokeepasslib.Entry{entry("Deleted", "eve", "https://deleted.example.com")}
+	registries.Groups = append(registries.Groups, bin)
+	s.DB.Content.Meta.RecycleBinEnabled = w.NewBoolWrapper(true)
+	s.DB.Content.Meta.RecycleBinUUID = bin.UUID
+
+	want := `{"https://quay.io":"robot"}` + "\n"
+	if got := runDocker(t, s, DockerList, ""); got != want {
+		t.Errorf("list: got %q, want %q", got, want)
+	}
+}
+
+func TestRunDocker_StoreListErase(t *testing.T) {
+	saves := 0
+	s := dockerStore(&saves)
+
+	runDocker(t, s, DockerStore, `{"ServerURL":"https://ghcr.io","Username":"bob","Secret":"ghcrtoken"}`)
+	results, _ := search.NewFinder(s.DB).Find("/Root/Registries/ghcr.io")
+	if len(results) != 1 {
+		t.Fatalf("expected new entry in group Registries")
+	}
+	runDocker(t, s, DockerStore, `{"ServerURL":"registry.example.com","Username":"robot","Secret":"newpw"}`)
+	if got := runDocker(t, s, DockerGet, "registry.example.com"); !strings.Contains(got, `"Secret":"newpw"`) {
+		t.Errorf("expected updated secret, got %q", got)
+	}
+	runDocker(t, s, DockerStore, `{"ServerURL":"registry.example.com","Username":"robot","Secret":"newpw"}`)
+	if saves != 2 {
+		t.Errorf("expected 2 saves, got %d", saves)
+	}
+	if err := RunDocker(DockerStore, strings.NewReader(`{"Username":"x"}`), &strings.Builder{}, s); err == nil {
+		t.Error("expected error for credentials without ServerURL")
+	}
+
+	want := `{"https://ghcr.io":"bob","registry.example.com":"robot"}` + "\n"
+	if got := runDocker(t, s, DockerList, ""); got != want {
+		t.Errorf("list: got %q, want %q", got, want)
+	}
+
+	runDocker(t, s, DockerErase, "https://ghcr.io")
+	if saves != 2 {
+		t.Errorf("erase must be ignored, if it is not enabled")
+	}
+	s.Erase = true
+	runDocker(t, s, DockerErase, "https://ghcr.io")
+	if err := RunDocker(DockerGet, strings.NewReader("https://ghcr.io"), &strings.Builder{}, s); !errors.Is(err, ErrDockerNotFound) {
+		t.Errorf("expected the entry to be erased, got %v", err)
+	}
+	if err := RunDocker(DockerErase, strings.NewReader("quay.io"), &strings.Builder{}, s); !errors.Is(err, ErrDockerNotFound) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package credential
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"kpasscli/src/debug"
+)
+
+// Actions of the git credential helper protocol
+const (
+	GitGet   = "get"
+	GitStore = "store"
+	GitErase = "erase"
+)
+
+// DefaultGitGroup is the group of the entries created by store, if no group is configured.
+const DefaultGitGroup = "Git"
+
+// GitRequest are the attributes of a git credential request.
+type GitRequest map[string]string
+
+// ReadGitRequest reads the attributes key=value line by line until an empty line or EOF.
+//
+// Parameters:
+//   - r: The reader, typically stdin.
+//
+// Returns:
+//   - GitRequest: The attributes.
+//   - error: An error if a line is no key=value pair or reading failed.
+func ReadGitRequest(r io.Reader) (GitRequest, error) {
+	req := GitRequest{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid credential attribute: %q", line)
+		}
+		req[key] = value
+	}
+	return req, scanner.Err()
+}
+
+// URL returns the URL of the request, built from protocol, host and path, or the url attribute.
+//
+// Returns:
+//   - string: The URL, e.g. "https://github.com/org/repo.git".
+func (req GitRequest) URL() string {
+	if u := req["url"]; u != "" && req["host"] == "" {
+		return u
+	}
+	u := req["host"]
+	if req["protocol"] != "" {
+		u = req["protocol"] + "://" + u
+	}
+	if req["path"] != "" {
+		u += "/" + strings.TrimPrefix(req["path"], "/")
+	}
+	return u
+}
+
+// RunGit answers a git credential helper request read from r.
+// get writes username and password of the matching entry to w; nothing is written, if no entry
+// matches, so git asks the next helper or the user. store saves the credentials, erase removes them.
+//
+// Parameters:
+//   - action: The action get, store or erase.
+//   - r: The reader of the request, typically stdin.
+//   - w: The writer of the response, typically stdout.
+//   - s: The credential store.
+//
+// Returns:
+//   - error: Any error encountered while reading the request or changing the database.
+func RunGit(action string, r io.Reader, w io.Writer, s *Store) error {
+	req, err := ReadGitRequest(r)
+	if err != nil {
+		return err
+	}
+	target := req.URL()
+	if req["host"] == "" && req["url"] == "" {
+		return fmt.Errorf("credential request has no host")
+	}
+	switch action {
+	case GitGet:
+		result, err := s.Lookup(target, req["username"])
+		if errors.Is(err, ErrNotFound) && req["username"] != "" {
+			// the user name of the URL may be missing in the entry
+			result, err = s.Lookup(target, "")
+		}
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kpasscli: %v\n", err)
+			return nil
+		}
+		username, _ := result.GetField("UserName")
+		if username == "" {
+			username = req["username"]
+		}
+		password, err := result.GetField("Password")
+		if err != nil {
+			return err
+		}
+		if strings.ContainsAny(username+password, "\n\x00") {
+			return fmt.Errorf("credentials of %s contain a line break", result.Path)
+		}
+		_, err = fmt.Fprintf(w, "username=%s\npassword=%s\n", username, password)
+		return err
+	case GitStore:
+		if req["password"] == "" {
+			return fmt.Errorf("credential request has no password")
+		}
+		title := req["host"]
+		if req["username"] != "" {
+			title = req["username"] + "@" + title
+		}
+		return s.Put(target, req["username"], req["password"], title)
+	case GitErase:
+		return s.Remove(target, req["username"], req["password"])
+	}
+	// git expects helpers to ignore unknown actions
+	debug.Log("Ignoring unknown git credential action: %s", action)
+	return nil
+}
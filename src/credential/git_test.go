@@ -0,0 +1,147 @@
+package credential
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/search"
+)
+
+// testStore returns a store with the entries /Root/GitHub (https://github.com, alice)
+// and /Root/Work/Org (https://github.com/org, no user name) and counts the saves.
+func testStore(saves *int) *Store {
+	entry := func(title, user, password, url string) gokeepasslib.Entry {
+		e := gokeepasslib.NewEntry()
+		e.Values = []gokeepasslib.ValueData{
+			{Key: "Title", Value: gokeepasslib.V{Content: title}},
+			{Key: "UserName", Value: gokeepasslib.V{Content: user}},
+			{Key: "Password", Value: gokeepasslib.V{Content: password}},
+			{Key: "URL", Value: gokeepasslib.V{Content: url}},
+		}
+		return e
+	}
+	work := gokeepasslib.NewGroup()
+	work.Name = "Work"
+	work.Entries = []gokeepasslib.Entry{entry("Org", "", "orgtoken", "https://github.com/org")}
+	root := gokeepasslib.NewGroup()
+	root.Name = "Root"
+	root.Entries = []gokeepasslib.Entry{entry("GitHub", "alice", "alicepw", "https://github.com")}
+	root.Groups = []gokeepasslib.Group{work}
+	db := gokeepasslib.NewDatabase()
+	db.Content.Root.Groups = []gokeepasslib.Group{root}
+	return &Store{
+		DB:     db,
+		Finder: search.NewFinder(db),
+		Group:  DefaultGitGroup,
+		Save:   func() error { *saves++; return nil },
+	}
+}
+
+func runGit(t *testing.T, s *Store, action, request string) string {
+	t.Helper()
+	var out strings.Builder
+	if err := RunGit(action, strings.NewReader(request), &out, s); err != nil {
+		t.Fatalf("%s: %v", action, err)
+	}
+	return out.String()
+}
+
+func TestReadGitRequest(t *testing.T) {
+	req, err := ReadGitRequest(strings.NewReader("protocol=https\r\nhost=github.com\npath=org/repo.git\n\nignored=1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req) != 3 || req.URL() != "https://github.com/org/repo.git" {
+		t.Errorf("unexpected request %v, URL %s", req, req.URL())
+	}
+	if _, err := ReadGitRequest(strings.NewReader("host\n")); err == nil {
+		t.Error("expected error for attribute without value")
+	}
+	if u := (GitRequest{"url": "https://example.com/x"}).URL(); u != "https://example.com/x" {
+		t.Errorf("expected url attribute, got %s", u)
+	}
+}
+
+func TestRunGit_Get(t *testing.T) {
+	saves := 0
+	s := testStore(&saves)
+	tests := []struct {
+		request string
+		want    string
+	}{
+		{"protocol=https\nhost=github.com\n", "username=alice\npassword=alicepw\n"},
+		{"protocol=https\nhost=github.com\npath=org/repo.git\n", "username=\npassword=orgtoken\n"},
+		{"protocol=https\nhost=github.com\npath=org/repo.git\nusername=bob\n", "username=bob\npassword=orgtoken\n"},
+		{"protocol=https\nhost=github.com\nusername=alice\n", "username=alice\npassword=alicepw\n"},
+		{"protocol=https\nhost=gitlab.com\n", ""},
+		{"protocol=http\nhost=github.com\n", ""},
+	}
+	for _, tt := range tests {
+		if got := runGit(t, s, GitGet, tt.request); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.request, got, tt.want)
+		}
+	}
+	if saves != 0 {
+		t.Errorf("get must not save the database")
+	}
+	if err := RunGit(GitGet, strings.NewReader("protocol=https\n"), &strings.Builder{}, s); err == nil {
+		t.Error("expected error for request without host")
+	}
+	if got := runGit(t, s, "capability", "protocol=https\nhost=github.com\n"); got != "" {
+		t.Errorf("unknown actions must be ignored, got %q", got)
+	}
+}
+
+func TestRunGit_Store(t *testing.T) {
+	saves := 0
+	s := testStore(&saves)
+
+	runGit(t, s, GitStore, "protocol=https\nhost=github.com\nusername=alice\npassword=alicepw\n")
+	if saves != 0 {
+		t.Errorf("unchanged credentials must not be saved")
+	}
+	runGit(t, s, GitStore, "protocol=https\nhost=github.com\nusername=alice\npassword=newpw\n")
+	if got := runGit(t, s, GitGet, "protocol=https\nhost=github.com\n"); got != "username=alice\npassword=newpw\n" {
+		t.Errorf("expected updated password, got %q", got)
+	}
+	runGit(t, s, GitStore, "protocol=https\nhost=github.com\npath=org/x\nusername=carol\npassword=carolpw\n")
+	if got := runGit(t, s, GitGet, "protocol=https\nhost=github.com\npath=org/x\n"); got != "username=carol\npassword=carolpw\n" {
+		t.Errorf("expected user name set in the entry without user name, got %q", got)
+	}
+	runGit(t, s, GitStore, "protocol=https\nhost=git.example.com\nusername=dave\npassword=davepw\n")
+	if got := runGit(t, s, GitGet, "protocol=https\nhost=git.example.com\n"); got != "username=dave\npassword=davepw\n" {
+		t.Errorf("expected new entry, got %q", got)
+	}
+	results, _ := search.NewFinder(s.DB).Find("/Root/Git/dave@git.example.com")
+	if len(results) != 1 {
+		t.Errorf("expected new entry in group Git")
+	}
+	if saves != 3 {
+		t.Errorf("expected 3 saves, got %d", saves)
+	}
+	if err := RunGit(GitStore, strings.NewReader("protocol=https\nhost=github.com\n"), &strings.Builder{}, s); err == nil {
+		t.Error("expected error for store without password")
+	}
+}
+
+func TestRunGit_Erase(t *testing.T) {
+	saves := 0
+	s := testStore(&saves)
+	request := "protocol=https\nhost=github.com\nusername=alice\npassword=alicepw\n"
+
+	runGit(t, s, GitErase, request)
+	if saves != 0 || runGit(t, s, GitGet, request) == "" {
+		t.Errorf("erase must be ignored, if it is not enabled")
+	}
+	s.Erase = true
+	runGit(t, s, GitErase, "protocol=https\nhost=github.com\nusername=alice\npassword=otherpw\n")
+	if saves != 0 {
+		t.Errorf("an entry with another password must not be erased")
+	}
+	runGit(t, s, GitErase, request)
+	if saves != 1 || runGit(t, s, GitGet, "protocol=https\nhost=github.com\nusername=alice\n") != "" {
+		t.Errorf("expected the entry to be erased")
+	}
+}
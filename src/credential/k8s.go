@@ -0,0 +1,136 @@
+package credential
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/keepass"
+	"kpasscli/src/search"
+)
+
+// API versions of the kubernetes ExecCredential
+const (
+	K8sAPIVersion        = "client.authentication.k8s.io/v1"
+	K8sAPIVersionV1beta1 = "client.authentication.k8s.io/v1beta1"
+)
+
+// Names of the fields or attachments holding the PEM encoded client certificate and key
+const (
+	K8sClientCert = "client.crt"
+	K8sClientKey  = "client.key"
+)
+
+// ExecCredential is the credential returned to kubectl by an exec credential plugin.
+type ExecCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     ExecCredentialStatus `json:"status"`
+}
+
+// ExecCredentialStatus holds the token or the client certificate and key.
+type ExecCredentialStatus struct {
+	ExpirationTimestamp   string `json:"expirationTimestamp,omitempty"`
+	Token                 string `json:"token,omitempty"`
+	ClientCertificateData string `json:"clientCertificateData,omitempty"`
+	ClientKeyData         string `json:"clientKeyData,omitempty"`
+}
+
+// K8sAPIVersionFromEnv returns the API version requested by kubectl in KUBERNETES_EXEC_INFO.
+//
+// Parameters:
+//   - execInfo: The value of KUBERNETES_EXEC_INFO, empty if not set.
+//
+// Returns:
+//   - string: K8sAPIVersionV1beta1 if it is requested, otherwise K8sAPIVersion.
+func K8sAPIVersionFromEnv(execInfo string) string {
+	var info struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	if json.Unmarshal([]byte(execInfo), &info) == nil && info.APIVersion == K8sAPIVersionV1beta1 {
+		return K8sAPIVersionV1beta1
+	}
+	return K8sAPIVersion
+}
+
+// k8sValue returns the value of a field or, if the entry has no such field, the content of an attachment.
+//
+// Parameters:
+//   - db: The KeePass database holding the attachment data.
+//   - result: The entry.
+//   - name: The name of the field or attachment.
+//
+// Returns:
+//   - string: The value, empty if the entry has neither a field nor an attachment with the name.
+func k8sValue(db *gokeepasslib.Database, result search.Result, name string) string {
+	if value, err := result.GetField(name); err == nil {
+		return value
+	}
+	data, err := keepass.GetAttachment(db, result.Entry, name)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// expirationTime returns the expiry time of the entry in RFC 3339 format.
+//
+// Parameters:
+//   - result: The entry.
+//   - now: The current time.
+//
+// Returns:
+//   - string: The expiry time in UTC, empty if the entry does not expire.
+//   - error: An error if the entry already expired.
+func expirationTime(result search.Result, now time.Time) (string, error) {
+	times := result.Entry.Times
+	if !times.Expires.Bool || times.ExpiryTime == nil {
+		return "", nil
+	}
+	expiry := times.ExpiryTime.Time.UTC()
+	if !expiry.After(now) {
+		return "", fmt.Errorf("credentials of %s expired at %s", result.Path, expiry.Format(time.RFC3339))
+	}
+	return expiry.Format(time.RFC3339), nil
+}
+
+// NewExecCredential creates the ExecCredential of the entry. If the entry has a client certificate
+// and key (fields or attachments client.crt and client.key), they are returned, otherwise the field
+// is returned as token. The expiry time of the entry becomes the expirationTimestamp.
+//
+// Parameters:
+//   - db: The KeePass database holding the attachments.
+//   - result: The entry.
+//   - field: The field of the token, e.g. Password.
+//   - apiVersion: The API version of the ExecCredential.
+//   - now: The current time, expired entries are rejected.
+//
+// Returns:
+//   - *ExecCredential: The credential.
+//   - error: An error if the entry expired or has neither a certificate nor the token field.
+func NewExecCredential(db *gokeepasslib.Database, result search.Result, field, apiVersion string, now time.Time) (*ExecCredential, error) {
+	expiration, err := expirationTime(result, now)
+	if err != nil {
+		return nil, err
+	}
+	cred := &ExecCredential{APIVersion: apiVersion, Kind: "ExecCredential"}
+	cred.Status.ExpirationTimestamp = expiration
+
+	cert, key := k8sValue(db, result, K8sClientCert), k8sValue(db, result, K8sClientKey)
+	if strings.TrimSpace(cert) != "" && strings.TrimSpace(key) != "" {
+		cred.Status.ClientCertificateData, cred.Status.ClientKeyData = cert, key
+		return cred, nil
+	}
+	token, err := result.GetField(field)
+	if err != nil {
+		return nil, err
+	}
+	if token = strings.TrimSpace(token); token == "" {
+		return nil, fmt.Errorf("field '%s' of %s is empty", field, result.Path)
+	}
+	cred.Status.Token = token
+	return cred, nil
+}
@@ -0,0 +1,68 @@
+package credential
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+
+	"kpasscli/src/search"
+)
+
+func TestK8sAPIVersionFromEnv(t *testing.T) {
+	tests := map[string]string{
+		"": K8sAPIVersion,
+		`{"apiVersion":"client.authentication.k8s.io/v1beta1","kind":"ExecCredential"}`: K8sAPIVersionV1beta1,
+		`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential"}`:      K8sAPIVersion,
+		"invalid": K8sAPIVersion,
+	}
+	for execInfo, want := range tests {
+		if got := K8sAPIVersionFromEnv(execInfo); got != want {
+			t.Errorf("%q: got %s, want %s", execInfo, got, want)
+		}
+	}
+}
+
+func TestNewExecCredential(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	db := gokeepasslib.NewDatabase()
+	entry := gokeepasslib.NewEntry()
+	entry.Values = []gokeepasslib.ValueData{
+		{Key: "Title", Value: gokeepasslib.V{Content: "prod-sa"}},
+		{Key: "Password", Value: gokeepasslib.V{Content: "sa-token\n"}},
+	}
+	entry.Times.Expires = w.NewBoolWrapper(true)
+	entry.Times.ExpiryTime = &w.TimeWrapper{Time: now.Add(time.Hour)}
+	result := search.Result{Path: "/Root/prod-sa", Entry: &entry}
+
+	cred, err := NewExecCredential(db, result, "Password", K8sAPIVersion, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(cred)
+	want := `{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"expirationTimestamp":"2026-01-01T13:00:00Z","token":"sa-token"}}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	if _, err := NewExecCredential(db, result, "Password", K8sAPIVersion, now.Add(2*time.Hour)); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected error for expired entry, got %v", err)
+	}
+	if _, err := NewExecCredential(db, result, "Token", K8sAPIVersion, now); err == nil {
+		t.Error("expected error for missing token field")
+	}
+
+	entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: "client.crt", Value: gokeepasslib.V{Content: "CERT"}})
+	binary := db.AddBinary([]byte("KEY"))
+	entry.Binaries = append(entry.Binaries, binary.CreateReference("client.key"))
+	cred, err = NewExecCredential(db, result, "Password", K8sAPIVersionV1beta1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.APIVersion != K8sAPIVersionV1beta1 || cred.Status.Token != "" || cred.Status.ClientCertificateData != "CERT" || cred.Status.ClientKeyData != "KEY" {
+		t.Errorf("expected client certificate and key, got %+v", cred)
+	}
+}
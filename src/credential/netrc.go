@@ -0,0 +1,132 @@
+package credential
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/debug"
+	"kpasscli/src/search"
+)
+
+// NetrcEntry is a machine stanza of a .netrc file.
+type NetrcEntry struct {
+	Machine  string
+	Login    string
+	Password string
+}
+
+// NetrcEntries returns the machine stanzas of the entries in the group and its subgroups, which have
+// a URL with host and a password. The machine is the host of the URL, the login the UserName.
+// Entries in the recycle bin are ignored, as are duplicates of machine and login.
+// The placeholders of the entries are resolved.
+//
+// Parameters:
+//   - db: The KeePass database.
+//   - groupPath: The path of the group, empty for the root group.
+//
+// Returns:
+//   - []NetrcEntry: The stanzas in the order of the entries.
+//   - error: An error if the group does not exist.
+func NetrcEntries(db *gokeepasslib.Database, groupPath string) ([]NetrcEntry, error) {
+	group, fullPath, err := search.FindGroup(db, groupPath)
+	if err != nil {
+		return nil, err
+	}
+	meta := db.Content.Meta
+	skipRecycleBin := meta != nil && meta.RecycleBinEnabled.Bool
+	var entries []NetrcEntry
+	seen := map[[2]string]bool{}
+	var walk func(g *gokeepasslib.Group, path string)
+	walk = func(g *gokeepasslib.Group, path string) {
+		if skipRecycleBin && meta.RecycleBinUUID.Compare(g.UUID) {
+			return
+		}
+		for i := range g.Entries {
+			entry := g.Entries[i]
+			result := search.NewResult(path+"/"+entry.GetTitle(), &entry)
+			if err := search.ResolvePlaceholders(db, result.Entry); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping %s, %v\n", result.Path, err)
+				continue
+			}
+			raw, _ := result.GetField("URL")
+			password, _ := result.GetField("Password")
+			if strings.TrimSpace(raw) == "" || password == "" {
+				continue
+			}
+			machine, err := netrcHost(raw)
+			if err != nil {
+				debug.Log("Skipping %s: %v", result.Path, err)
+				continue
+			}
+			login, _ := result.GetField("UserName")
+			if strings.ContainsAny(login+password, "\r\n") {
+				fmt.Fprintf(os.Stderr, "Warning: skipping %s, its credentials contain a line break\n", result.Path)
+				continue
+			}
+			key := [2]string{machine, login}
+			if seen[key] {
+				debug.Log("Skipping %s, %s with login %s is already exported", result.Path, machine, login)
+				continue
+			}
+			seen[key] = true
+			entries = append(entries, NetrcEntry{Machine: machine, Login: login, Password: password})
+		}
+		for i := range g.Groups {
+			walk(&g.Groups[i], path+"/"+g.Groups[i].Name)
+		}
+	}
+	walk(group, fullPath)
+	return entries, nil
+}
+
+// netrcHost returns the host of the URL, which is the machine of a .netrc stanza.
+//
+// Parameters:
+//   - raw: The URL, e.g. "https://git.example.com:8443/org".
+//
+// Returns:
+//   - string: The host name without port.
+//   - error: An error if the URL is invalid.
+func netrcHost(raw string) (string, error) {
+	u, err := search.ParseURL(raw)
+	if err != nil {
+		return "", err
+	}
+	return u.Hostname(), nil
+}
+
+// netrcQuote quotes a token of a .netrc file, if it is empty or contains white space, quotes or backslashes.
+//
+// Parameters:
+//   - s: The token.
+//
+// Returns:
+//   - string: The token, quoted with " if necessary.
+func netrcQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// FormatNetrc formats the stanzas as .netrc file, the login is omitted if it is empty.
+//
+// Parameters:
+//   - entries: The stanzas.
+//
+// Returns:
+//   - string: The content of the .netrc file.
+func FormatNetrc(entries []NetrcEntry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "machine %s\n", e.Machine)
+		if e.Login != "" {
+			fmt.Fprintf(&b, "  login %s\n", netrcQuote(e.Login))
+		}
+		fmt.Fprintf(&b, "  password %s\n", netrcQuote(e.Password))
+	}
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package credential
+
+import (
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+func TestNetrcEntries(t *testing.T) {
+	saves := 0
+	s := testStore(&saves)
+	root := &s.DB.Content.Root.Groups[0]
+	extra := func(title, user, password, url string) gokeepasslib.Entry {
+		e := gokeepasslib.NewEntry()
+		e.Values = []gokeepasslib.ValueData{
+			{Key: "Title", Value: gokeepasslib.V{Content: title}},
+			{Key: "UserName", Value: gokeepasslib.V{Content: user}},
+			{Key: "Password", Value: gokeepasslib.V{Content: password}},
+			{Key: "URL", Value: gokeepasslib.V{Content: url}},
+		}
+		return e
+	}
+	root.Entries = append(root.Entries,
+		extra("Repo", "deploy er", `p"w`, "repo.example.com:8443/x"),
+		extra("Duplicate", "alice", "otherpw", "https://github.com/other"),
+		extra("NoURL", "bob", "bobpw", ""),
+		extra("NoPassword", "bob", "", "https://example.com"),
+	)
+	bin := gokeepasslib.NewGroup()
+	bin.Name = "Recycle Bin"
+	bin.Entries = []gokeepasslib.Entry{extra("Deleted", "eve", "evepw", "https://deleted.example.com")}
+	root.Groups = append(root.Groups, bin)
+	s.DB.Content.Meta.RecycleBinEnabled = w.NewBoolWrapper(true)
+	s.DB.Content.Meta.RecycleBinUUID = bin.UUID
+
+	entries, err := NetrcEntries(s.DB, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "machine github.com\n  login alice\n  password alicepw\n" +
+		"machine repo.example.com\n  login \"deploy er\"\n  password \"p\\\"w\"\n" +
+		"machine github.com\n  password orgtoken\n"
+	if got := FormatNetrc(entries); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	entries, err = NetrcEntries(s.DB, "/Root/Work")
+	if err != nil || len(entries) != 1 || entries[0].Password != "orgtoken" {
+		t.Errorf("expected the entry of group Work, got %v (err: %v)", entries, err)
+	}
+	if _, err := NetrcEntries(s.DB, "Missing"); err == nil {
+		t.Error("expected error for missing group")
+	}
+}
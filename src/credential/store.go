@@ -0,0 +1,192 @@
+// Package credential implements credential helpers for git and docker, which look up,
+// store and erase credentials in the entries of the database by matching their URL field,
+// and the exec credential plugin of kubernetes.
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/debug"
+	"kpasscli/src/keepass"
+	"kpasscli/src/search"
+)
+
+// ErrNotFound is returned, if no entry matches the URL.
+var ErrNotFound = errors.New("no entry matches the URL")
+
+// URLFinder finds entries by their URL field, it is implemented by search.Finder.
+type URLFinder interface {
+	FindByURL(target, userName string) ([]search.Result, error)
+}
+
+// Store looks up and changes the credentials in the database.
+type Store struct {
+	// DB is the opened database
+	DB *gokeepasslib.Database
+	// Finder finds the entries by URL
+	Finder URLFinder
+	// Save writes the changed database, it is called after each change
+	Save func() error
+	// Group is the group, in which new entries are created
+	Group string
+	// Erase allows to remove entries, otherwise erase requests are ignored
+	Erase bool
+}
+
+// Lookup returns the entry, whose URL matches the target URL best.
+//
+// Parameters:
+//   - target: The URL of the credentials.
+//   - userName: The user name, empty for any.
+//
+// Returns:
+//   - search.Result: The found entry.
+//   - error: ErrNotFound, or an error if multiple entries match equally well.
+func (s *Store) Lookup(target, userName string) (search.Result, error) {
+	results, err := s.Finder.FindByURL(target, userName)
+	if err != nil {
+		return search.Result{}, err
+	}
+	switch len(results) {
+	case 0:
+		return search.Result{}, ErrNotFound
+	case 1:
+		debug.Log("Credentials for %s found in %s", target, results[0].Path)
+		return results[0], nil
+	}
+	paths := make([]string, 0, len(results))
+	for _, result := range results {
+		paths = append(paths, result.Path)
+	}
+	return search.Result{}, fmt.Errorf("multiple entries match %s: %s", target, strings.Join(paths, ", "))
+}
+
+// Put stores the credentials: the password of the matching entry is updated, if it changed,
+// otherwise a new entry with the title is created in the Group.
+//
+// Parameters:
+//   - target: The URL of the credentials, stored in the URL field of a new entry.
+//   - userName: The user name.
+//   - password: The password or token.
+//   - title: The title of a new entry.
+//
+// Returns:
+//   - error: Any error encountered while changing or saving the database.
+func (s *Store) Put(target, userName, password, title string) error {
+	result, err := s.Lookup(target, userName)
+	if errors.Is(err, ErrNotFound) && userName != "" {
+		// an entry without user name gets the user name
+		if r, err2 := s.Lookup(target, ""); err2 == nil {
+			if current, _ := r.GetField("UserName"); current == "" {
+				result, err = r, nil
+			}
+		}
+	}
+	if errors.Is(err, ErrNotFound) {
+		return s.add(target, userName, password, title)
+	}
+	if err != nil {
+		return err
+	}
+
+	var values []keepass.FieldValue
+	if current, _ := result.GetField("Password"); current != password {
+		values = append(values, keepass.FieldValue{Key: "Password", Value: password})
+	}
+	if current, _ := result.GetField("UserName"); current == "" && userName != "" {
+		values = append(values, keepass.FieldValue{Key: "UserName", Value: userName})
+	}
+	return s.update(target, result, values)
+}
+
+// add creates a new entry with the credentials and the title in the Group and saves the database.
+//
+// Parameters:
+//   - target: The URL of the credentials, stored in the URL field.
+//   - userName: The user name.
+//   - password: The password or token.
+//   - title: The title of the entry, slashes are replaced.
+//
+// Returns:
+//   - error: Any error encountered while adding the entry or saving the database.
+func (s *Store) add(target, userName, password, title string) error {
+	path := strings.TrimSuffix(s.Group, "/") + "/" + strings.ReplaceAll(title, "/", "_")
+	values := []keepass.FieldValue{{Key: "UserName", Value: userName}, {Key: "Password", Value: password}, {Key: "URL", Value: target}}
+	if _, err := keepass.AddEntry(s.DB, path, values); err != nil {
+		return err
+	}
+	debug.Log("Credentials for %s added as %s", target, path)
+	return s.Save()
+}
+
+// update sets the changed fields of the found entry and saves the database, if any field changed.
+//
+// Parameters:
+//   - target: The URL of the credentials, used for logging.
+//   - result: The found entry.
+//   - values: The changed fields.
+//
+// Returns:
+//   - error: Any error encountered while changing or saving the database.
+func (s *Store) update(target string, result search.Result, values []keepass.FieldValue) error {
+	if len(values) == 0 {
+		debug.Log("Credentials for %s are unchanged", target)
+		return nil
+	}
+	group, index, err := keepass.LocateEntry(s.DB, result.Entry.UUID)
+	if err != nil {
+		return err
+	}
+	keepass.SetFields(s.DB, &group.Entries[index], values)
+	debug.Log("Credentials for %s updated in %s", target, result.Path)
+	return s.Save()
+}
+
+// delete removes the found entry and saves the database.
+//
+// Parameters:
+//   - target: The URL of the credentials, used for logging.
+//   - result: The found entry.
+//
+// Returns:
+//   - error: Any error encountered while changing or saving the database.
+func (s *Store) delete(target string, result search.Result) error {
+	if _, err := keepass.DeleteEntry(s.DB, result.Entry.UUID); err != nil {
+		return err
+	}
+	debug.Log("Credentials for %s erased from %s", target, result.Path)
+	return s.Save()
+}
+
+// Remove deletes the matching entry, if Erase is enabled. If a password is given, the entry is only
+// deleted if it still has this password, so an entry updated meanwhile is kept.
+//
+// Parameters:
+//   - target: The URL of the credentials.
+//   - userName: The user name, empty for any.
+//   - password: The rejected password, empty to delete the entry regardless of its password.
+//
+// Returns:
+//   - error: Any error encountered while changing or saving the database.
+func (s *Store) Remove(target, userName, password string) error {
+	if !s.Erase {
+		debug.Log("Erasing credentials is disabled, ignoring erase of %s", target)
+		return nil
+	}
+	result, err := s.Lookup(target, userName)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if current, _ := result.GetField("Password"); password != "" && current != password {
+		debug.Log("Password of %s changed, not erasing it", result.Path)
+		return nil
+	}
+	return s.delete(target, result)
+}
@@ -0,0 +1,572 @@
+package doc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Option describes a command-line option. The same definitions are used to register
+// the flags, to generate the help of the commands and the OPTIONS section of the manual.
+type Option struct {
+	// Name is the long name of the option, e.g. "kdbpath"
+	Name string
+	// Short is the shorthand of the option, e.g. "p"
+	Short string
+	// Arg is the placeholder of the option argument, empty for boolean options
+	Arg string
+	// Default is the default value as string
+	Default string
+	// Usage is the one line description used in the help
+	Usage string
+	// Description is the detailed description used in the manual, defaults to Usage
+	Description string
+	// Hidden options are for internal use and not documented
+	Hidden bool
+}
+
+// Command describes a subcommand of kpasscli.
+type Command struct {
+	// Name is the name of the subcommand, e.g. "get"
+	Name string
+	// Args is the synopsis of the positional arguments, e.g. "<item> [field]"
+	Args string
+	// Summary is the one line description used in the command list
+	Summary string
+	// Description is the detailed description used in the command help and the manual
+	Description string
+	// Options are the names of the command specific options
+	Options []string
+	// Examples are example invocations of the command
+	Examples []string
+}
+
+// Options contains all command-line options of kpasscli.
+var Options = []Option{
+	{Name: "kdbpath", Short: "p", Arg: "path", Usage: "Path to KeePass database file",
+		Description: "Path to the KeePass database file. If not specified, the tool will look for\n" +
+			"the path in the KPASSCLI_KDBPATH environment variable or the config file."},
+	{Name: "kdbpassword", Short: "w", Arg: "path", Usage: "Path to password file or executable, if not given asks for password interactively",
+		Description: "Path to a file containing the database password or to an executable that\n" +
+			"outputs the password. For security reasons, the password cannot be provided\n" +
+			"directly on the command line."},
+	{Name: "keyfile", Short: "k", Arg: "path", Usage: "Path to a key file (.keyx/.key), used together with the password or alone",
+		Description: "Path to a key file to unlock the database. If a password is resolved as well,\n" +
+			"both are combined into a composite key. Supported are KeePass XML key files\n" +
+			"(version 1.0 and 2.0, .key/.keyx), binary key files of 32 bytes, key files with\n" +
+			"64 hex characters and any other file, whose SHA-256 hash is used as key.\n" +
+			"If not specified, the tool will look for the path in the KPASSCLI_KEYFILE\n" +
+			"environment variable or the config file."},
+	{Name: "no-password", Short: "np", Usage: "Open the database with the key file only, do not ask for a password",
+		Description: "Open the database with the key file only. No password is resolved or asked for."},
+	{Name: "config", Short: "cf", Arg: "path", Default: "~/.config/kpasscli/config.yaml", Usage: "Path to configuration file (default: ~/.config/kpasscli/config.yaml)",
+		Description: "Path to the configuration file (default: ~/.config/kpasscli/config.yaml)"},
+	{Name: "item", Short: "i", Arg: "name", Usage: "Entry to search for",
+		Description: "The entry to search for. This can be:\n" +
+			"- An absolute path starting with \"/\" (e.g., \"/Personal/Banking/Account\")\n" +
+			"- A relative path (e.g., \"Banking/Account\")\n" +
+			"- A simple name (e.g., \"Account\")\n" +
+			"- uuid: and the UUID of the entry as hex or base64, which does not change when the\n" +
+			"  entry is moved or renamed (e.g., \"uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60\", see -print-uuid)"},
+	{Name: "print-uuid", Short: "pu", Usage: "Output the UUID of the found entry instead of a field",
+		Description: "Outputs the UUID of the found entry as 32 hex digits instead of a field. The UUID\n" +
+			"does not change when the entry is moved or renamed, use it as item uuid:<UUID> to pin\n" +
+			"the entry in scripts. With search, every path is preceded by the UUID and a tab."},
+	{Name: "fieldname", Short: "f", Arg: "field", Default: "Password", Usage: "Field(s) to retrieve, comma-separated or \"all\" (default: Password)",
+		Description: "The field to retrieve from the entry. Defaults to \"Password\".\n" +
+			"Common fields: Title, UserName, Password, URL, Notes\n" +
+			"Multiple fields are given comma-separated (e.g. \"UserName,Password\"), \"all\" retrieves\n" +
+			"all fields of the entry. Multiple fields are output in the format of -format."},
+	{Name: "show-all", Short: "a", Usage: "Show all fields of the specified item (protected values are masked)",
+		Description: "Show all fields of the entry: title, username, password, url, notes,\n" +
+			"additional fields and metadata. Protected values like the password are masked."},
+	{Name: "reveal", Short: "r", Usage: "Reveal protected values like the password in the -show-all output",
+		Description: "Reveal protected values in the -show-all output."},
+	{Name: "format", Short: "fmt", Arg: "format", Usage: "Output format (text/json/yaml/dotenv/shell/raw, default: text)",
+		Description: "Output format of the retrieved fields and of -show-all. Options:\n" +
+			"- text: The value, or \"Name: value\" lines for multiple fields (default)\n" +
+			"- json, yaml: An object with the field names as keys\n" +
+			"- dotenv (or env): NAME=\"value\" lines\n" +
+			"- shell: export NAME='value' lines, for eval in POSIX shells\n" +
+			"- raw: The values, each terminated by a NUL character\n" +
+			"-show-all supports text, json and yaml only.\n" +
+			"If not specified, the output_format of the config file is used."},
+	{Name: "out", Short: "o", Arg: "type", Usage: "Output type (stdout/clipboard), for render also an output file",
+		Description: "How to output the retrieved value. Options:\n" +
+			"- stdout: Print to standard output (default)\n" +
+			"- clipboard: Copy to system clipboard\n" +
+			"For render any other value is the path of the output file."},
+	{Name: "clipboard", Short: "c", Usage: "Output to clipboard, same as -out clipboard"},
+	{Name: "password-totp", Short: "pt", Usage: "Output TOTP password to the end of password field (default: false)",
+		Description: "Append the current TOTP token to the value of the password field."},
+	{Name: "totp", Short: "t", Usage: "Output TOTP token (default: false)",
+		Description: "Output the current TOTP token of the entry instead of a field."},
+	{Name: "totp-min-validity", Short: "tv", Arg: "n", Default: "0", Usage: "Wait for the next TOTP token, if the current one expires in less than N seconds (default: 0)",
+		Description: "Wait for the next TOTP period, if the current token of -totp or -password-totp expires in\n" +
+			"less than N seconds, so a login does not fail with an expired token. N must be shorter than the period."},
+	{Name: "totp-expiry", Short: "te", Usage: "Output the remaining seconds and the expiry time of the TOTP token (default: false)",
+		Description: "Output the fields Remaining (seconds the TOTP token stays valid) and Expires (RFC 3339 time)\n" +
+			"after the token of -totp or -password-totp, in the format of -format."},
+	{Name: "clear-after", Short: "ca", Arg: "seconds", Default: "20", Usage: "Clear clipboard after N seconds ( default is 20sec, 0=disable, only active if output is clipboard)",
+		Description: "Clear clipboard after nn seconds (default is 20 sec., 0=disable, only active if output is clipboard)"},
+	{Name: "case-sensitive", Short: "cs", Usage: "Enable case-sensitive search"},
+	{Name: "exact-match", Short: "e", Usage: "Enable exact match search"},
+	{Name: "regex", Short: "re", Usage: "Match titles and group names against regular expressions",
+		Description: "The title and the group names of a path query are regular expressions, which match\n" +
+			"anywhere in the name unless -exact-match is given. The path segment ** matches any\n" +
+			"number of groups, a relative path query matches at any depth. The query is split at /."},
+	{Name: "glob", Short: "gl", Usage: "Match titles and group names against shell globs (*, ?, [...])",
+		Description: "The title and the group names of a path query are shell globs, which match the whole\n" +
+			"name. The path segment ** matches any number of groups, e.g. /Root/Prod/**/db-*.\n" +
+			"A relative path query matches at any depth."},
+	{Name: "where", Short: "wh", Arg: "Field=value", Usage: "Only match entries whose field has the value, Field~value matches a part (repeatable)",
+		Description: "Only matches entries, whose field Field (e.g. UserName, URL, Notes or a custom field)\n" +
+			"has the value. Field=value matches the whole value, Field~value a part of it. The\n" +
+			"value is compared case-insensitive unless -case-sensitive is given, and is a pattern\n" +
+			"with -regex or -glob (Field~glob is the same as Field=*glob*). The option can be given\n" +
+			"multiple times, all conditions must hold.\n" +
+			"Without an item all entries are searched, e.g. kpasscli search -where URL~gitlab."},
+	{Name: "fuzzy", Short: "fz", Usage: "Match the characters of the query in order (like fzf) and rank the results",
+		Description: "The title, or the path if the query contains /, must contain the characters of the\n" +
+			"query in order, e.g. prdb matches \"Prod DB\". Matches at word starts and consecutive\n" +
+			"characters score higher. The results are ranked by score, then by path depth and\n" +
+			"last access time."},
+	{Name: "first", Short: "fi", Usage: "Use the best ranked match, if its score clearly leads the second best",
+		Description: "Ranks the results like -fuzzy and uses only the best one, if its score leads the\n" +
+			"second best by at least the score of one matched character. Otherwise all matches\n" +
+			"are listed as usual. E.g. kpasscli get Account -first prefers the entry titled\n" +
+			"\"Account\" over \"Account old\"."},
+	{Name: "raw", Short: "rw", Usage: "Output field values with their KeePass placeholders, do not resolve {REF:...}, {USERNAME}, ...",
+		Description: "Per default the KeePass placeholders in field values are resolved: {TITLE}, {USERNAME},\n" +
+			"{PASSWORD}, {URL}, {NOTES}, {S:Field} for a custom field and {REF:X@Y:Z} for the field X\n" +
+			"(T, U, P, A, N or I for the UUID) of the first entry, whose field Y (T, U, P, A, N, I or O\n" +
+			"for any other field) contains Z, e.g. {REF:P@I:46C9B1FFBD4ABC4BBB260C6190BAD20C}.\n" +
+			"References are resolved recursively up to 10 levels, cycles are an error.\n" +
+			"With -raw the values are output unchanged."},
+	{Name: "backup", Short: "b", Usage: "Keep a copy of the previous database file as <database>.bak when saving",
+		Description: "When the database is changed by add, set or rm, the previous database file is\n" +
+			"copied to <database>.bak before it is replaced. Can also be enabled with\n" +
+			"backup: true in the config file."},
+	{Name: "generate", Short: "g", Usage: "Generate the password of the entry (add/set), see the generate command for the rules",
+		Description: "Generate a random password and store it in the Password field of the entry.\n" +
+			"The rules are the same as of the generate command (-length, -classes, -words, ...)."},
+	{Name: "length", Short: "l", Arg: "n", Default: "20", Usage: "Length of the generated password (default: 20)"},
+	{Name: "classes", Short: "cl", Arg: "list", Default: "lower,upper,digits,symbols", Usage: "Character classes of the generated password (default: lower,upper,digits,symbols)",
+		Description: "Comma separated list of the character classes of the generated password:\n" +
+			"lower, upper, digits, symbols (default: all)."},
+	{Name: "min-lower", Arg: "n", Usage: "Minimum number of lower case letters of the generated password"},
+	{Name: "min-upper", Arg: "n", Usage: "Minimum number of upper case letters of the generated password"},
+	{Name: "min-digits", Arg: "n", Usage: "Minimum number of digits of the generated password"},
+	{Name: "min-symbols", Arg: "n", Usage: "Minimum number of symbols of the generated password"},
+	{Name: "exclude-similar", Short: "xs", Usage: "Exclude look-alike characters (l I 1 | O 0) from the generated password"},
+	{Name: "words", Short: "wd", Arg: "n", Usage: "Generate a diceware passphrase with n words of the EFF large wordlist instead of a password"},
+	{Name: "separator", Short: "sep", Arg: "string", Default: "-", Usage: "Separator between the words of the passphrase (default: -)"},
+	{Name: "socket", Short: "sk", Arg: "path", Usage: "Path to the agent socket (default: $XDG_RUNTIME_DIR/kpasscli/agent.sock)",
+		Description: "Path to the socket of the unlock agent. If not specified, the KPASSCLI_AGENT_SOCK\n" +
+			"environment variable, $XDG_RUNTIME_DIR/kpasscli/agent.sock or\n" +
+			"<tmp>/kpasscli-<uid>/agent.sock is used. The directory of the socket must be owned by\n" +
+			"the current user with mode 0700."},
+	{Name: "no-agent", Short: "na", Usage: "Do not query a running agent, open the database directly"},
+	{Name: "no-interactive", Short: "ni", Usage: "Fail if the search finds multiple entries, instead of showing a selection list",
+		Description: "If a search of get, totp, clip, set or rm finds multiple entries and stdin and stderr are\n" +
+			"terminals, kpasscli shows a numbered list of their paths: typing narrows the list, the arrow\n" +
+			"keys move the selection, Enter chooses the entry and Esc cancels. With -no-interactive, or if\n" +
+			"no terminal is attached, the paths are printed to stderr and the command fails. render, exec\n" +
+			"and the credential helpers always fail."},
+	{Name: "idle-timeout", Short: "it", Arg: "seconds", Default: "900", Usage: "Lock the agent after N seconds without requests (default: 900, 0=never)"},
+	{Name: "foreground", Short: "fg", Usage: "Run the agent in the foreground instead of detaching it"},
+	{Name: "create-config", Short: "cc", Usage: "Create an example config file",
+		Description: "Create an example configuration file"},
+	{Name: "print-config", Short: "pc", Usage: "Print the current detected config"},
+	{Name: "verify", Short: "v", Usage: "Show the path of found item"},
+	{Name: "debug", Short: "d", Usage: "Enable debug logging"},
+	{Name: "man", Short: "m", Usage: "Show full manual",
+		Description: "Display this manual page"},
+	{Name: "help", Short: "h", Usage: "Show this help",
+		Description: "Display brief help message, or the help of the command"},
+	{Name: "clear-clipboard", Usage: "Clear clipboard (internal use)", Hidden: true},
+	{Name: "env", Short: "ev", Arg: "NAME=item:field", Usage: "Set the environment variable of exec to a field of an entry (repeatable)",
+		Description: "Sets the environment variable NAME of the command run by exec to the field of\n" +
+			"the entry item. The field is separated by the last \":\" and defaults to Password.\n" +
+			"The item is searched like for get and must match exactly one entry, uuid:<UUID> is an\n" +
+			"item without field, uuid:<UUID>:UserName names the field.\n" +
+			"The option can be given multiple times."},
+	{Name: "mask", Short: "ms", Usage: "Mask the injected secrets in the output of exec",
+		Description: "Replaces the injected secrets in the stdout and stderr of the command run by exec\n" +
+			"by ********. The output of the command is then no terminal anymore."},
+	{Name: "in", Arg: "path", Usage: "Template file of render (default: stdin)"},
+	{Name: "vault-id", Short: "vi", Arg: "id", Usage: "Vault id of ansible-vault (default: default)",
+		Description: "The vault id passed by ansible to a vault password client. The password is read\n" +
+			"from the entry titled by the vault id in the group ansible_vault_group of the config file."},
+	{Name: "agent-serve", Usage: "Serve the agent, the password is read from stdin (internal use)", Hidden: true},
+}
+
+// generatorOptions are the options of the password generator.
+var generatorOptions = []string{"length", "classes", "min-lower", "min-upper", "min-digits", "min-symbols", "exclude-similar", "words", "separator"}
+
+// GlobalOptions are the options accepted by every subcommand.
+var GlobalOptions = []string{"kdbpath", "kdbpassword", "keyfile", "no-password", "config", "socket", "no-agent", "no-interactive", "verify", "debug", "help"}
+
+// Commands contains all subcommands of kpasscli.
+var Commands = []Command{
+	{Name: "get", Args: "<item> [field]", Summary: "Output a field of an entry (default: Password)",
+		Description: "Searches the entry and outputs the value of the field, per default the password.\n" +
+			"Calling kpasscli without a command and with -item is the same as get.",
+		Options: []string{"item", "fieldname", "format", "out", "clipboard", "clear-after", "password-totp", "totp-min-validity", "totp-expiry", "case-sensitive", "exact-match", "regex", "glob", "where", "fuzzy", "first", "print-uuid", "raw"},
+		Examples: []string{"kpasscli get /Personal/Banking/Account", "kpasscli get Account UserName",
+			"kpasscli get Account UserName,Password -format json", "eval \"$(kpasscli get Account all -format shell)\"",
+			"kpasscli get Account -print-uuid", "kpasscli get uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60"}},
+	{Name: "show", Args: "<item>", Summary: "Show all fields of an entry",
+		Description: "Shows all fields of the entry. Protected values like the password are masked,\n" +
+			"unless -reveal is given.",
+		Options:  []string{"item", "format", "reveal", "out", "clipboard", "clear-after", "case-sensitive", "exact-match", "regex", "glob", "where", "fuzzy", "first", "raw"},
+		Examples: []string{"kpasscli show Account", "kpasscli show Account -format json -reveal"}},
+	{Name: "ls", Args: "[group]", Summary: "List the groups and entries of a group (default: root group)",
+		Description: "Lists the subgroups (with a trailing \"/\") and the entries of the group.\n" +
+			"The group is given as absolute path (e.g. \"/Root/Banking\") or relative to the root group.",
+		Examples: []string{"kpasscli ls", "kpasscli ls /Root/Banking"}},
+	{Name: "tree", Args: "[group]", Summary: "Show the tree of groups and entries below a group",
+		Description: "Shows all groups and entries below the group (default: root group) as tree.",
+		Examples:    []string{"kpasscli tree", "kpasscli tree Banking"}},
+	{Name: "search", Args: "<query>", Summary: "List the paths of all entries matching the query",
+		Description: "Searches like get, but lists the paths of all matching entries instead of\n" +
+			"failing, if more than one entry is found.",
+		Options:  []string{"case-sensitive", "exact-match", "regex", "glob", "where", "fuzzy", "first", "print-uuid", "raw"},
+		Examples: []string{"kpasscli search Account", "kpasscli search Banking/ -e", "kpasscli search -where UserName=svc-deploy -where URL~gitlab", "kpasscli search -fuzzy prdb"}},
+	{Name: "totp", Args: "<item>", Summary: "Output the current TOTP token of an entry",
+		Description: "The TOTP parameters are read from the otp field (an otpauth:// URI with digits, period\n" +
+			"and algorithm, or a base32 secret), from the KeePassXC fields \"TOTP Seed\" and \"TOTP Settings\",\n" +
+			"or from the KeePass fields TimeOtp-Secret, TimeOtp-Length, TimeOtp-Period and TimeOtp-Algorithm.",
+		Options:  []string{"item", "format", "out", "clipboard", "clear-after", "totp-min-validity", "totp-expiry", "case-sensitive", "exact-match", "regex", "glob", "where", "fuzzy", "first", "raw"},
+		Examples: []string{"kpasscli totp /Personal/VPN", "kpasscli totp /Personal/VPN -totp-min-validity 5 -totp-expiry -format json"}},
+	{Name: "clip", Args: "<item> [field]", Summary: "Copy a field of an entry to the clipboard",
+		Description: "Same as get with -out clipboard. The clipboard is cleared after -clear-after seconds.",
+		Options:     []string{"item", "fieldname", "clear-after", "password-totp", "totp-min-validity", "case-sensitive", "exact-match", "regex", "glob", "where", "fuzzy", "first", "raw"},
+		Examples:    []string{"kpasscli clip Account", "kpasscli clip Account UserName -ca 10"}},
+	{Name: "add", Args: "<item> [field=value ...]", Summary: "Add a new entry, missing groups are created",
+		Description: "Creates the entry at the path <item>, the last element of the path is the title.\n" +
+			"The path is absolute (e.g. \"/Root/Banking/Account\") or relative to the root group.\n" +
+			"Missing groups are created. The database is saved to a temporary file, which then\n" +
+			"replaces the database file.",
+		Options: append([]string{"item", "backup", "generate"}, generatorOptions...),
+		Examples: []string{"kpasscli add /Root/Banking/Account UserName=tester URL=https://bank.example.com",
+			"kpasscli add /Root/Services/Backup UserName=backup -generate -length 32"}},
+	{Name: "set", Args: "<item> <field=value> ...", Summary: "Set fields of an entry",
+		Description: "Sets the fields of the entry, new fields are added. The previous version of the\n" +
+			"entry is stored in its history and the modification time is updated.",
+		Options: append([]string{"item", "backup", "case-sensitive", "exact-match", "regex", "glob", "generate"}, generatorOptions...),
+		Examples: []string{"kpasscli set Account UserName=tester", "kpasscli set /Root/Banking/Account Notes= -b",
+			"kpasscli set /Root/Services/Backup -generate -words 6"}},
+	{Name: "rm", Args: "<item>", Summary: "Move an entry to the recycle bin",
+		Description: "Moves the entry to the recycle bin, if the recycle bin is enabled in the database.\n" +
+			"Otherwise, or if the entry is already in the recycle bin, it is deleted permanently.",
+		Options:  []string{"item", "backup", "case-sensitive", "exact-match", "regex", "glob"},
+		Examples: []string{"kpasscli rm /Root/Banking/Account"}},
+	{Name: "generate", Summary: "Generate a random password or diceware passphrase",
+		Description: "Generates a random password of -length characters from the -classes, at least\n" +
+			"-min-<class> characters of each class, or with -words a diceware passphrase\n" +
+			"from the EFF large wordlist. The database is not opened.",
+		Options: append([]string{"out", "clipboard", "clear-after"}, generatorOptions...),
+		Examples: []string{"kpasscli generate", "kpasscli generate -length 32 -classes lower,digits -min-digits 4 -xs",
+			"kpasscli generate -words 6 -sep ' ' -c"}},
+	{Name: "agent", Summary: "Start the unlock agent, which holds the unlocked database",
+		Description: "Asks for the password once, opens the database and starts a background process,\n" +
+			"which holds the unlocked database and listens on a Unix socket, that only the\n" +
+			"current user can access. The other commands query the agent transparently and\n" +
+			"open the database directly, if no agent is running. The agent reopens the\n" +
+			"database, if the file was changed, and locks (exits) after -idle-timeout seconds\n" +
+			"without requests or on kpasscli lock.",
+		Options:  []string{"idle-timeout", "foreground", "agent-serve"},
+		Examples: []string{"kpasscli agent", "kpasscli agent -idle-timeout 3600", "kpasscli agent -foreground -socket /run/user/1000/kp.sock"}},
+	{Name: "exec", Args: "-- <command> [args ...]", Summary: "Run a command with secrets injected into its environment",
+		Description: "Resolves all -env references with one database open and runs the command with\n" +
+			"the environment variables set. Signals are forwarded to the command and kpasscli\n" +
+			"exits with the exit status of the command.",
+		Options: []string{"env", "mask", "case-sensitive", "exact-match", "regex", "glob", "raw"},
+		Examples: []string{"kpasscli exec --env DB_PASS=/Prod/DB:Password --env DB_USER=/Prod/DB:UserName -- ./deploy.sh",
+			"kpasscli exec -env TOKEN=GitHub -mask -- make release"}},
+	{Name: "render", Args: "[template]", Summary: "Render a template with secret references",
+		Description: "Renders the Go text/template -in (default: stdin) with the functions:\n" +
+			"- {{ kp \"/Prod/DB\" \"UserName\" }}: A field of an entry, the field defaults to Password\n" +
+			"- {{ totp \"/Prod/VPN\" }}: The current TOTP token of an entry\n" +
+			"- {{ attachment \"/Prod/TLS\" \"key.pem\" }}: The content of an attachment of an entry\n" +
+			"The items are searched like for get and must match exactly one entry. Any unresolved or\n" +
+			"ambiguous reference fails the rendering and nothing is written. If -out is a file path,\n" +
+			"the output is written to this file with permissions 0600, otherwise it is output like get.",
+		Options: []string{"in", "out", "case-sensitive", "exact-match", "regex", "glob", "raw"},
+		Examples: []string{"kpasscli render -in template.tmpl -out app.conf",
+			"kpasscli render app.conf.tmpl > /dev/null && echo all references resolved"}},
+	{Name: "batch", Summary: "Answer item/field requests read line by line from stdin",
+		Description: "Reads requests line by line from stdin and answers each request on one line,\n" +
+			"using one opened database for all requests. A request is either\n" +
+			"- plain: item<TAB>field, answered by the value or an empty line and the error on stderr\n" +
+			"- JSON: {\"id\": 1, \"item\": \"/Prod/DB\", \"field\": \"UserName\"}, answered by\n" +
+			"  {\"id\": 1, \"item\": ..., \"field\": ..., \"value\": ...} or with \"error\" instead of \"value\"\n" +
+			"The field defaults to Password, the id is optional and returned unchanged. Values with\n" +
+			"line breaks require a JSON request. Empty lines are ignored. The exit status is 1,\n" +
+			"if any request failed. As stdin carries the requests, the password can not be asked\n" +
+			"for, it must be given by -kdbpassword, the config file or a running agent.",
+		Options: []string{"case-sensitive", "exact-match", "regex", "glob", "raw"},
+		Examples: []string{"printf '/Prod/DB\\tUserName\\n/Prod/DB\\n' | kpasscli batch",
+			"echo '{\"item\": \"/Prod/DB\", \"field\": \"UserName\"}' | kpasscli batch"}},
+	{Name: "git-credential", Args: "get|store|erase", Summary: "Git credential helper, entries are matched by their URL field",
+		Description: "Implements the git credential helper protocol. The request of git is read from stdin\n" +
+			"and matched against the URL field of the entries: the host must be equal, the protocol\n" +
+			"and port if given, and the path of the entry URL must be a prefix of the requested path.\n" +
+			"The most specific URL wins, entries in the recycle bin are ignored.\n" +
+			"- get: outputs username and password of the matching entry, nothing if none matches\n" +
+			"- store: updates the password of the matching entry or creates an entry in the group\n" +
+			"  git_credential_group of the config file (default: Git)\n" +
+			"- erase: removes the matching entry, if it still has the rejected password and\n" +
+			"  git_credential_erase is enabled in the config file, otherwise erase is ignored\n" +
+			"git can not pass a password prompt, use a running agent or -kdbpassword.",
+		Options: []string{"backup"},
+		Examples: []string{"git config --global credential.helper '!kpasscli git-credential'",
+			"printf 'protocol=https\\nhost=github.com\\n' | kpasscli git-credential get"}},
+	{Name: "docker-credential", Args: "get|store|erase|list", Summary: "Docker credential helper, also run as docker-credential-kpasscli",
+		Description: "Implements the docker credential helper protocol, kpasscli runs this command also, if it is\n" +
+			"called as docker-credential-kpasscli (e.g. a symlink in the PATH). The server URL of the\n" +
+			"registry is matched against the URL field of the entries like git-credential, otherwise the\n" +
+			"entry titled by the registry host in the group docker_credential_group of the config file\n" +
+			"(default: Registries) is used.\n" +
+			"- get: reads the server URL and outputs {\"ServerURL\", \"Username\", \"Secret\"} as JSON\n" +
+			"- store: reads the credentials as JSON, updates the matching entry or creates an entry\n" +
+			"  titled by the registry host in docker_credential_group\n" +
+			"- erase: reads the server URL and removes the matching entry (docker logout)\n" +
+			"- list: outputs the URLs and user names of the entries in docker_credential_group\n" +
+			"Errors are written to stdout as docker expects. docker can not pass a password prompt,\n" +
+			"use a running agent or -kdbpassword.",
+		Options: []string{"backup"},
+		Examples: []string{"ln -s $(command -v kpasscli) ~/bin/docker-credential-kpasscli",
+			"echo '{\"credsStore\": \"kpasscli\"}' > ~/.docker/config.json",
+			"echo registry.example.com | kpasscli docker-credential get"}},
+	{Name: "k8s-credential", Args: "<item> [field]", Summary: "Output the ExecCredential of an entry for kubectl",
+		Description: "Outputs a client.authentication.k8s.io/v1 ExecCredential as JSON, so an exec section of a\n" +
+			"kubeconfig can call kpasscli. If the entry has a client certificate and key (fields or\n" +
+			"attachments client.crt and client.key), they are returned, otherwise the field (default:\n" +
+			"Password) is returned as token. If the entry expires, its expiry time is returned as\n" +
+			"expirationTimestamp, an expired entry is an error. The API version v1beta1 is returned, if\n" +
+			"kubectl requests it in KUBERNETES_EXEC_INFO. kubectl can not pass a password prompt,\n" +
+			"use a running agent or -kdbpassword.",
+		Options:  []string{"item", "fieldname", "case-sensitive", "exact-match", "regex", "glob", "raw"},
+		Examples: []string{"kpasscli k8s-credential /Clusters/prod-sa", "kpasscli k8s-credential /Clusters/prod-sa Token"}},
+	{Name: "aws-credential-process", Args: "<item>", Summary: "Output the AWS credentials of an entry for credential_process",
+		Description: "Outputs the Version 1 JSON document of the credential_process of the AWS SDKs and CLI.\n" +
+			"The fields AccessKeyId, SecretAccessKey and the optional SessionToken are read from the\n" +
+			"entry, their names are set by aws_access_key_id_field, aws_secret_access_key_field and\n" +
+			"aws_session_token_field in the config file. If the entry expires, its expiry time is\n" +
+			"returned as Expiration, an expired entry is an error. The SDKs can not pass a password\n" +
+			"prompt, use a running agent or -kdbpassword.",
+		Options:  []string{"item", "case-sensitive", "exact-match", "regex", "glob", "raw"},
+		Examples: []string{"aws configure set credential_process 'kpasscli aws-credential-process /Cloud/AWS/prod' --profile prod"}},
+	{Name: "ansible-vault", Args: "[--vault-id id]", Summary: "Ansible vault password client, also run as <name>-client",
+		Description: "Outputs the vault password of the vault id for ansible. ansible passes --vault-id to\n" +
+			"vault password clients, whose name ends with -client, kpasscli runs this command, if it is\n" +
+			"called by such a name (e.g. a symlink kpasscli-vault-client). The password is the field\n" +
+			"(default: Password) of the entry titled by the vault id (default: default) in the group\n" +
+			"ansible_vault_group of the config file (default: Ansible). The exit status is 2, if there\n" +
+			"is no entry for the vault id, as ansible expects.",
+		Options: []string{"vault-id", "fieldname"},
+		Examples: []string{"ln -s $(command -v kpasscli) ~/bin/kpasscli-vault-client",
+			"ansible-playbook --vault-id prod@~/bin/kpasscli-vault-client site.yml",
+			"kpasscli ansible-vault --vault-id prod"}},
+	{Name: "lookup", Summary: "Answer a JSON list of item/field requests by one JSON object",
+		Description: "Reads a JSON array of requests [{\"item\": \"...\", \"field\": \"...\"}, ...] from stdin and\n" +
+			"outputs one JSON object, which maps the items to their fields and values:\n" +
+			"{\"item\": {\"field\": \"value\"}}. The field defaults to Password. If any request fails,\n" +
+			"nothing is output and the exit status is 1. A lookup plugin of ansible can fetch many\n" +
+			"secrets with one invocation this way.",
+		Options:  []string{"case-sensitive", "exact-match", "regex", "glob", "raw"},
+		Examples: []string{"echo '[{\"item\": \"/Prod/DB\", \"field\": \"UserName\"}, {\"item\": \"/Prod/DB\"}]' | kpasscli lookup"}},
+	{Name: "tf-external", Summary: "Terraform external data source, resolves a JSON map of name to item:field",
+		Description: "Implements the protocol of the terraform external data source. The query is read from\n" +
+			"stdin as JSON object, which maps names to references item:field, the field is separated\n" +
+			"by the last \":\" and defaults to Password, uuid:<UUID> is an item without field.\n" +
+			"The result maps the names to the values.\n" +
+			"If any reference fails, the error is written to stderr and the exit status is 1, so\n" +
+			"terraform fails. terraform can not pass a password prompt, use a running agent or\n" +
+			"-kdbpassword.",
+		Options:  []string{"case-sensitive", "exact-match", "regex", "glob", "raw"},
+		Examples: []string{"echo '{\"db_user\": \"/Prod/DB:UserName\", \"db_pass\": \"/Prod/DB\"}' | kpasscli tf-external"}},
+	{Name: "export-netrc", Args: "[group] [-- command [args ...]]", Summary: "Output a .netrc file, or run a command with it",
+		Description: "Builds the stanzas machine/login/password of a .netrc file from the host of the URL, the\n" +
+			"UserName and the Password of the entries in the group and its subgroups. The group\n" +
+			"defaults to netrc_group of the config file or the root group. Entries without URL or\n" +
+			"password and entries in the recycle bin are skipped.\n" +
+			"Without command the .netrc file is output. With a command after \"--\" it is written to a\n" +
+			"temporary file readable only by the user, the command is run with NETRC set to the file\n" +
+			"and the file is removed after the command finished. The exit status is that of the command.",
+		Options: []string{"out", "clear-after"},
+		Examples: []string{"kpasscli export-netrc Servers > ~/.netrc && chmod 600 ~/.netrc",
+			"kpasscli export-netrc Servers -- sh -c 'curl --netrc-file \"$NETRC\" https://repo.example.com/file'"}},
+	{Name: "lock", Summary: "Lock the unlock agent, the database is dropped and the agent exits",
+		Examples: []string{"kpasscli lock"}},
+	{Name: "config", Args: "[print|create]", Summary: "Print the detected config or create an example config file",
+		Description: "print (default) prints the current detected config,\n" +
+			"create creates the example config file config.yaml in the current directory.",
+		Examples: []string{"kpasscli config", "kpasscli config create"}},
+	{Name: "help", Args: "[command]", Summary: "Show the help of kpasscli or of a command",
+		Examples: []string{"kpasscli help get"}},
+}
+
+// LookupOption returns the option with the given long name.
+//
+// Parameters:
+//   - name: The long name of the option.
+//
+// Returns:
+//   - Option: The option definition.
+//   - bool: True if the option exists.
+func LookupOption(name string) (Option, bool) {
+	for _, o := range Options {
+		if o.Name == name {
+			return o, true
+		}
+	}
+	return Option{}, false
+}
+
+// LookupCommand returns the subcommand with the given name.
+//
+// Parameters:
+//   - name: The name of the subcommand.
+//
+// Returns:
+//   - Command: The command definition.
+//   - bool: True if the command exists.
+func LookupCommand(name string) (Command, bool) {
+	for _, c := range Commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
+
+// OptionNames returns the long names of all options accepted by the command,
+// the command specific options followed by the global options.
+// For an unknown command (the flat legacy invocation) all options are returned.
+//
+// Parameters:
+//   - command: The name of the subcommand.
+//
+// Returns:
+//   - []string: The long names of the accepted options.
+func OptionNames(command string) []string {
+	c, ok := LookupCommand(command)
+	if !ok {
+		names := make([]string, 0, len(Options))
+		for _, o := range Options {
+			names = append(names, o.Name)
+		}
+		return names
+	}
+	return append(append([]string{}, c.Options...), GlobalOptions...)
+}
+
+// flagSpec returns the flag names and argument of an option, e.g. "-kdbpath | -p path".
+//
+// Parameters:
+//   - o: The option.
+//   - sep: The separator between long name and shorthand.
+//
+// Returns:
+//   - string: The formatted option specification.
+func flagSpec(o Option, sep string) string {
+	spec := "-" + o.Name
+	if o.Short != "" {
+		spec += sep + "-" + o.Short
+	}
+	if o.Arg != "" {
+		spec += " " + o.Arg
+	}
+	return spec
+}
+
+// optionsHelp formats the given options as aligned help lines.
+//
+// Parameters:
+//   - names: The long names of the options to format.
+//
+// Returns:
+//   - string: One line per documented option.
+func optionsHelp(names []string) string {
+	var b strings.Builder
+	for _, name := range names {
+		o, ok := LookupOption(name)
+		if !ok || o.Hidden {
+			continue
+		}
+		fmt.Fprintf(&b, "    %-30s%s\n", flagSpec(o, " | "), o.Usage)
+	}
+	return b.String()
+}
+
+// commandsHelp formats all subcommands as aligned help lines.
+//
+// Returns:
+//   - string: One line per subcommand.
+func commandsHelp() string {
+	var b strings.Builder
+	for _, c := range Commands {
+		fmt.Fprintf(&b, "    %-30s%s\n", strings.TrimSpace(c.Name+" "+c.Args), c.Summary)
+	}
+	return b.String()
+}
+
+// indent prefixes every line of text with the given number of spaces.
+//
+// Parameters:
+//   - text: The text to indent.
+//   - n: The number of spaces.
+//
+// Returns:
+//   - string: The indented text.
+func indent(text string, n int) string {
+	prefix := strings.Repeat(" ", n)
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
+// CommandHelp returns the help text of a subcommand.
+//
+// Parameters:
+//   - name: The name of the subcommand.
+//
+// Returns:
+//   - string: The help text.
+//   - error: An error if the command does not exist.
+func CommandHelp(name string) (string, error) {
+	c, ok := LookupCommand(name)
+	if !ok {
+		return "", fmt.Errorf("unknown command: %s", name)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: kpasscli %s [OPTIONS]\n\n", strings.TrimSpace(c.Name+" "+c.Args))
+	b.WriteString(c.Summary + "\n")
+	if c.Description != "" {
+		b.WriteString("\n" + c.Description + "\n")
+	}
+	if len(c.Options) > 0 {
+		b.WriteString("\nOptions:\n" + optionsHelp(c.Options))
+	}
+	b.WriteString("\nGlobal options:\n" + optionsHelp(GlobalOptions))
+	if len(c.Examples) > 0 {
+		b.WriteString("\nExamples:\n")
+		for _, e := range c.Examples {
+			b.WriteString("    " + e + "\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// ShowCommandHelp prints the help of a subcommand, or the general help if the command is unknown.
+//
+// Parameters:
+//   - name: The name of the subcommand.
+func ShowCommandHelp(name string) {
+	help, err := CommandHelp(name)
+	if err != nil {
+		ShowHelp()
+		return
+	}
+	fmt.Print(help)
+}
@@ -0,0 +1,34 @@
+package doc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandHelp(t *testing.T) {
+	help, err := CommandHelp("get")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"kpasscli get", "-fieldname", "-kdbpath"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("expected help of get to contain %q, got:\n%s", want, help)
+		}
+	}
+	if strings.Contains(help, "-show-all") {
+		t.Errorf("expected help of get not to contain -show-all")
+	}
+	if _, err := CommandHelp("unknown"); err == nil {
+		t.Error("expected error for unknown command")
+	}
+}
+
+func TestOptionNames(t *testing.T) {
+	if got := len(OptionNames("")); got != len(Options) {
+		t.Errorf("expected all %d options without command, got %d", len(Options), got)
+	}
+	names := strings.Join(OptionNames("ls"), ",")
+	if !strings.Contains(names, "kdbpath") || strings.Contains(names, "fieldname") {
+		t.Errorf("unexpected options for ls: %s", names)
+	}
+}
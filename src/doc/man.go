@@ -2,13 +2,15 @@ package doc
 
 import (
 	"fmt"
+	"strings"
 )
 
-const manPage = `NAME
+const manHeader = `NAME
     kpasscli - KeePass database command line interface
 
 SYNOPSIS
-    kpasscli [-kdbpath|-p path] [-kdbpassword|-w path] [-config|-c] -item|-i name [-fieldname|-f field] [-out|-o type] [-verify|-v] [-man|-m] [-help|-h]
+    kpasscli [-kdbpath|-p path] [-kdbpassword|-w path] [-keyfile|-k path] [-config|-cf] -item|-i name [-fieldname|-f field] [-out|-o type] [-verify|-v] [-man|-m] [-help|-h]
+    kpasscli <command> [ARGS] [OPTIONS]
 
 DESCRIPTION
     kpasscli is a command-line tool for querying KeePass database files.
@@ -29,68 +31,9 @@ DESCRIPTION
     - stdout: The value is printed to stdout
     - clipboard: The value is copied into the clipboard and can be pasted wherever it is needed
       with tho parameter -ca nn  (or -clear-after nn)  the clipboard ist automatically cleared after nn seconds
+`
 
-OPTIONS
-    -kdbpath|-p path
-        Path to the KeePass database file. If not specified, the tool will look for
-        the path in the KDBPATH environment variable or the config file.
-
-    -kdbpassword|-w password-file
-        Path to a file containing the database password or to an executable that
-        outputs the password. For security reasons, the password cannot be provided
-        directly on the command line.
-
-    -config|-c config-file
-        Path to a file containing the configuration settings. If not specified,
-        the tool will look for the path in the KDBCONFIG environment variable
-        or the default config file.
-
-    -item|-i name
-        The entry to search for. This can be:
-        - An absolute path starting with "/" (e.g., "/Personal/Banking/Account")
-        - A relative path (e.g., "Banking/Account")
-        - A simple name (e.g., "Account")
-
-    -fieldname|-f field
-        The field to retrieve from the entry. Defaults to "Password".
-        Common fields: Title, UserName, Password, URL, Notes
-
-    -out|-o type
-        How to output the retrieved value. Options:
-        - stdout: Print to standard output (default)
-        - clipboard: Copy to system clipboard
-
-    -clear-after nn | -ca nn
-        Clear clipboard after nn seconds (default is 20 sec., 0=disable, only active if output is clipboard)
-
-    -case-sensitive|-cs
-        Enable case-sensitive search
-
-    -exact-match|-e
-        Enable exact match search
-
-    -config|-c path
-        Path to the configuration file (default: ~/.config/kpasscli/config.yaml)
-        
-    -create-config|-cc
-        Create an example configuration file
-
-    -print-config|-pc
-        Print the current detected config
-
-    -man|-m
-        Display this manual page
-
-    -help|-h
-        Display brief help message
-
-    -verify|-v
-        Show the path of found item
-
-    -debug|-d
-        Enable debug logging
-
-SEARCH BEHAVIOR
+const manFooter = `SEARCH BEHAVIOR
     Absolute Path (/path/to/entry):
         Searches for an exact match at the specified location in the database.
 
@@ -102,21 +45,70 @@ SEARCH BEHAVIOR
         Searches all entries regardless of location.
         If multiple matches are found, lists all matches.
 
+    UUID (uuid:<UUID>):
+        Finds the entry by its UUID as hex or base64, which does not change when the entry
+        is moved or renamed. -print-uuid outputs the UUID of the found entry.
+
+    Regular expressions and globs (-regex, -glob):
+        The title and the group names of a path are regular expressions or shell globs.
+        The path segment ** matches any number of groups, e.g. -glob /Root/Prod/**/db-*.
+        A relative path matches at any depth.
+
+    Field values (-where Field=value, -where Field~value):
+        Only entries, whose field has the whole value (=) or contains it (~), are matched.
+        All conditions must hold, without an item all entries are searched.
+
+    Fuzzy search (-fuzzy, -first):
+        The characters of the query must appear in order in the title (or the path), like in fzf.
+        The results are ranked by score, path depth and last access time. -first uses the best
+        match, if its score clearly leads the second best.
+
+    Placeholders (-raw):
+        KeePass placeholders in field values like {USERNAME}, {S:Field} or {REF:P@I:<UUID>} are
+        resolved, unless -raw is given.
+
+    Multiple matches (-no-interactive):
+        If stdin and stderr are terminals, get, totp, clip, set and rm show a numbered list of the
+        paths: typing narrows it, the arrow keys move the selection, Enter chooses and Esc cancels.
+        With -no-interactive or without a terminal the paths are printed to stderr and kpasscli fails.
+        render, exec and the credential helpers always fail on multiple matches.
+
+TOTP
+    The TOTP parameters are read from the otp field (otpauth:// URI with secret, digits, period,
+    algorithm and issuer, or a base32 secret), the KeePassXC fields "TOTP Seed" and "TOTP Settings"
+    (period;digits) or the KeePass fields TimeOtp-Secret (-Hex, -Base32, -Base64), TimeOtp-Length,
+    TimeOtp-Period and TimeOtp-Algorithm. The defaults are 6 digits, 30 seconds and SHA1.
+    -totp-min-validity N waits for the next period, if the token expires in less than N seconds,
+    -totp-expiry outputs the remaining seconds and the expiry time after the token.
+
 CONFIGURATION
     Configuration can be provided via a config.yaml file with the following fields:
     - database_path:       Default path to the KeePass database
     - default_output:      Default output type (stdout/clipboard)
+    - output_format:       Default output format (text/json/yaml/dotenv/shell/raw)
+    - backup:              true to keep a copy of the previous database file as <database>.bak when saving
 
     # Password retrieval methods, take care, this can be unsecure if you not protect the password file
     # or the executable properly. See SECURITY
     - password_file:       file which contains the password to open the keepass db
     - password_executable: the path to the executable, that returns the password to open the keepass database.
                            This method can be safe, if the executable itself asks for a general password to run it.
+    - key_file:            path to a key file, used together with the password or alone (see -no-password)
+    - git_credential_group: group of the entries created by git-credential store (default: Git)
+    - git_credential_erase: true to let git-credential erase remove entries with rejected passwords
+    - docker_credential_group: group of the registry entries of docker-credential (default: Registries)
+    - aws_access_key_id_field: field of the access key id of aws-credential-process (default: AccessKeyId)
+    - aws_secret_access_key_field: field of the secret access key of aws-credential-process (default: SecretAccessKey)
+    - aws_session_token_field: field of the optional session token of aws-credential-process (default: SessionToken)
+    - ansible_vault_group: group of the vault passwords of ansible-vault (default: Ansible)
+    - netrc_group: group exported by export-netrc (default: the root group)
 
 ENVIRONMENT
     KPASSCLI_KDBPATH       Alternative way to specify the KeePass database path
     KPASSCLI_OUT           Alternative way to specify the output type (stdout/clipboard)
     KPASSCLI_kdbpassword   Alternative way to specify the password file or executable
+    KPASSCLI_KEYFILE       Alternative way to specify the key file
+    KPASSCLI_AGENT_SOCK    Alternative way to specify the socket of the unlock agent
 
     define an alias like
 
@@ -147,6 +139,46 @@ EXAMPLES
         kpasscli -kdbpath=/path/to/db.kdbx -kdbpassword=/path/to/pass.txt -item="Account" -fieldname=UserName
         kpasscli -p=/path/to/db.kdbx -w=/path/to/pass.txt -i="Account" -f=UserName
 
+    Open a database protected by password and key file:
+        kpasscli -p=/path/to/db.kdbx -w=/path/to/pass.txt -k=/path/to/db.keyx -i="Account"
+
+    Open a database protected by a key file only:
+        kpasscli -p=/path/to/db.kdbx -k=/path/to/db.keyx -np -i="Account"
+
+    Show all fields of an entry as json:
+        kpasscli -i="Account" -show-all -format=json
+
+    Set user name and password as environment variables:
+        eval "$(kpasscli get Account UserName,Password -format shell)"
+
+    Run a command with secrets in its environment:
+        kpasscli exec --env DB_PASS=/Prod/DB:Password --env DB_USER=/Prod/DB:UserName -- ./deploy.sh
+
+    Render a config file with secrets:
+        kpasscli render -in app.conf.tmpl -out app.conf
+
+    Use kpasscli as git credential helper:
+        git config --global credential.helper '!kpasscli git-credential'
+
+    Use kpasscli as docker credential helper ("credsStore": "kpasscli" in ~/.docker/config.json):
+        ln -s $(command -v kpasscli) ~/bin/docker-credential-kpasscli
+
+    Use kpasscli as exec credential plugin of kubectl (users[].user.exec in the kubeconfig):
+        exec: {apiVersion: client.authentication.k8s.io/v1, command: kpasscli, args: [k8s-credential, /Clusters/prod-sa], interactiveMode: Never}
+
+    Use kpasscli as credential_process of an AWS profile (~/.aws/config):
+        credential_process = kpasscli aws-credential-process /Cloud/AWS/prod
+
+    Use kpasscli as ansible vault password client:
+        ln -s $(command -v kpasscli) ~/bin/kpasscli-vault-client
+        ansible-playbook --vault-id prod@~/bin/kpasscli-vault-client site.yml
+
+    Run a command with a temporary .netrc file:
+        kpasscli export-netrc Servers -- sh -c 'curl --netrc-file "$NETRC" https://repo.example.com/file'
+
+    Use kpasscli as terraform external data source:
+        data "external" "db" { program = ["kpasscli", "tf-external"], query = { password = "/Prod/DB:Password" } }
+
     Copy password to clipboard:
         kpasscli -kdbpath=/path/to/db.kdbx -kdbpassword=/path/to/pass.txt -item="Account" -out=clipboard
         kpasscli -p=/path/to/db.kdbx -w=/path/to/pass.txt -i="Account" -o=clipboard
@@ -163,7 +195,57 @@ LICENSE
 	  GNU GENERAL PUBLIC LICENSE Version 3, 29 June 2007
 `
 
+// manCommands generates the COMMANDS section of the manual from the command definitions.
+//
+// Returns:
+//   - string: The COMMANDS section.
+func manCommands() string {
+	var b strings.Builder
+	b.WriteString("COMMANDS\n")
+	b.WriteString("    Without a command, kpasscli behaves like the get command and accepts all options.\n\n")
+	for _, c := range Commands {
+		fmt.Fprintf(&b, "    %s\n", strings.TrimSpace(c.Name+" "+c.Args))
+		b.WriteString(indent(c.Summary, 8) + "\n")
+		if c.Description != "" {
+			b.WriteString(indent(c.Description, 8) + "\n")
+		}
+		if len(c.Options) > 0 {
+			opts := make([]string, 0, len(c.Options))
+			for _, name := range c.Options {
+				if o, ok := LookupOption(name); ok {
+					opts = append(opts, flagSpec(o, "|"))
+				}
+			}
+			b.WriteString(indent("Options: "+strings.Join(opts, ", "), 8) + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// manOptions generates the OPTIONS section of the manual from the option definitions.
+//
+// Returns:
+//   - string: The OPTIONS section.
+func manOptions() string {
+	var b strings.Builder
+	b.WriteString("OPTIONS\n")
+	for _, o := range Options {
+		if o.Hidden {
+			continue
+		}
+		description := o.Description
+		if description == "" {
+			description = o.Usage
+		}
+		fmt.Fprintf(&b, "    %s\n", flagSpec(o, "|"))
+		b.WriteString(indent(description, 8) + "\n\n")
+	}
+	return b.String()
+}
+
+// ShowMan prints the full manual page.
 func ShowMan() {
-	fmt.Print(manPage)
+	fmt.Print(manHeader + "\n" + manCommands() + manOptions() + manFooter)
 	fmt.Println()
 }
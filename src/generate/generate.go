@@ -0,0 +1,236 @@
+// Package generate creates random passwords and diceware passphrases using crypto/rand.
+package generate
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Character classes of the password generator
+const (
+	ClassLower   = "lower"
+	ClassUpper   = "upper"
+	ClassDigits  = "digits"
+	ClassSymbols = "symbols"
+)
+
+// DefaultLength is the default length of generated passwords
+const DefaultLength = 20
+
+// DefaultClasses are the character classes used, if none are given
+const DefaultClasses = "lower,upper,digits,symbols"
+
+// similarChars are the look-alike characters removed by ExcludeSimilar
+const similarChars = "lI1|O0"
+
+// classChars maps the character classes to their characters
+var classChars = map[string]string{
+	ClassLower:   "abcdefghijklmnopqrstuvwxyz",
+	ClassUpper:   "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	ClassDigits:  "0123456789",
+	ClassSymbols: "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+// classOrder is the order of the character classes
+var classOrder = []string{ClassLower, ClassUpper, ClassDigits, ClassSymbols}
+
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+var (
+	wordsOnce sync.Once
+	words     []string
+)
+
+// Options are the rules of the password generator.
+type Options struct {
+	// Length is the length of the password
+	Length int
+	// Classes are the character classes to use, comma separated (lower, upper, digits, symbols)
+	Classes string
+	// MinCounts are the minimum number of characters per class
+	MinCounts map[string]int
+	// ExcludeSimilar removes look-alike characters like l, I, 1, |, O and 0
+	ExcludeSimilar bool
+}
+
+// ParseClasses parses a comma separated list of character classes.
+//
+// Parameters:
+//   - classes: The character classes, e.g. "lower,upper,digits". Empty uses DefaultClasses.
+//
+// Returns:
+//   - []string: The character classes in the order lower, upper, digits, symbols.
+//   - error: An error for unknown classes.
+func ParseClasses(classes string) ([]string, error) {
+	if strings.TrimSpace(classes) == "" {
+		classes = DefaultClasses
+	}
+	selected := map[string]bool{}
+	for _, c := range strings.Split(classes, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if _, ok := classChars[c]; !ok {
+			return nil, fmt.Errorf("unknown character class '%s', use lower, upper, digits or symbols", c)
+		}
+		selected[c] = true
+	}
+	var result []string
+	for _, c := range classOrder {
+		if selected[c] {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+// charset returns the characters of a class, without look-alike characters if excludeSimilar is set.
+//
+// Parameters:
+//   - class: The character class.
+//   - excludeSimilar: Remove look-alike characters.
+//
+// Returns:
+//   - string: The characters of the class.
+func charset(class string, excludeSimilar bool) string {
+	chars := classChars[class]
+	if !excludeSimilar {
+		return chars
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(similarChars, r) {
+			return -1
+		}
+		return r
+	}, chars)
+}
+
+// randomInt returns a uniform random number in [0, n) from crypto/rand.
+//
+// Parameters:
+//   - n: The upper bound, must be greater than 0.
+//
+// Returns:
+//   - int: The random number.
+//   - error: Any error of the random source.
+func randomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+// Password generates a random password following the rules of opts.
+// At first the minimum number of characters of each class is chosen, the rest is chosen
+// from all selected classes and the result is shuffled.
+//
+// Parameters:
+//   - opts: The rules of the password.
+//
+// Returns:
+//   - string: The generated password.
+//   - error: An error if the rules can not be fulfilled or the random source fails.
+func Password(opts Options) (string, error) {
+	classes, err := ParseClasses(opts.Classes)
+	if err != nil {
+		return "", err
+	}
+	if opts.Length <= 0 {
+		return "", fmt.Errorf("password length must be greater than 0")
+	}
+
+	var all string
+	var password []byte
+	selected := map[string]bool{}
+	for _, c := range classes {
+		all += charset(c, opts.ExcludeSimilar)
+		selected[c] = true
+	}
+	minTotal := 0
+	for class, count := range opts.MinCounts {
+		if count <= 0 {
+			continue
+		}
+		if !selected[class] {
+			return "", fmt.Errorf("minimum count for character class '%s', which is not selected", class)
+		}
+		minTotal += count
+	}
+	if minTotal > opts.Length {
+		return "", fmt.Errorf("password length %d is less than the sum of the minimum counts %d", opts.Length, minTotal)
+	}
+
+	for _, c := range classes {
+		chars := charset(c, opts.ExcludeSimilar)
+		for i := 0; i < opts.MinCounts[c]; i++ {
+			n, err := randomInt(len(chars))
+			if err != nil {
+				return "", err
+			}
+			password = append(password, chars[n])
+		}
+	}
+	for len(password) < opts.Length {
+		n, err := randomInt(len(all))
+		if err != nil {
+			return "", err
+		}
+		password = append(password, all[n])
+	}
+
+	// Fisher-Yates shuffle, so the minimum counts are not at the beginning
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+// Wordlist returns the words of the embedded EFF large wordlist (7776 words).
+//
+// Returns:
+//   - []string: The words of the list.
+func Wordlist() []string {
+	wordsOnce.Do(func() {
+		for _, line := range strings.Split(effLargeWordlist, "\n") {
+			// each line is "<dice rolls>\t<word>"
+			fields := strings.Fields(line)
+			if len(fields) == 2 {
+				words = append(words, fields[1])
+			}
+		}
+	})
+	return words
+}
+
+// Passphrase generates a diceware passphrase from the embedded EFF large wordlist.
+//
+// Parameters:
+//   - count: The number of words.
+//   - separator: The separator between the words.
+//
+// Returns:
+//   - string: The generated passphrase.
+//   - error: An error if count is not greater than 0 or the random source fails.
+func Passphrase(count int, separator string) (string, error) {
+	if count <= 0 {
+		return "", fmt.Errorf("number of words must be greater than 0")
+	}
+	list := Wordlist()
+	chosen := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		n, err := randomInt(len(list))
+		if err != nil {
+			return "", err
+		}
+		chosen = append(chosen, list[n])
+	}
+	return strings.Join(chosen, separator), nil
+}
@@ -0,0 +1,95 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+)
+
+func countIn(s, chars string) int {
+	n := 0
+	for _, r := range s {
+		if strings.ContainsRune(chars, r) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestParseClasses(t *testing.T) {
+	classes, err := ParseClasses("digits, UPPER")
+	if err != nil || strings.Join(classes, ",") != "upper,digits" {
+		t.Errorf("unexpected classes %v (err: %v)", classes, err)
+	}
+	if classes, _ := ParseClasses(""); len(classes) != 4 {
+		t.Errorf("expected default classes, got %v", classes)
+	}
+	if _, err := ParseClasses("lower,emoji"); err == nil {
+		t.Error("expected error for unknown class")
+	}
+}
+
+func TestPassword(t *testing.T) {
+	opts := Options{
+		Length:         16,
+		Classes:        "lower,upper,digits,symbols",
+		MinCounts:      map[string]int{ClassDigits: 4, ClassSymbols: 3},
+		ExcludeSimilar: true,
+	}
+	for i := 0; i < 50; i++ {
+		pw, err := Password(opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(pw) != 16 {
+			t.Fatalf("expected length 16, got %d: %s", len(pw), pw)
+		}
+		if countIn(pw, classChars[ClassDigits]) < 4 || countIn(pw, classChars[ClassSymbols]) < 3 {
+			t.Errorf("minimum counts not met: %s", pw)
+		}
+		if countIn(pw, similarChars) > 0 {
+			t.Errorf("look-alike characters not excluded: %s", pw)
+		}
+	}
+
+	pw, err := Password(Options{Length: 30, Classes: "digits"})
+	if err != nil || countIn(pw, classChars[ClassDigits]) != 30 {
+		t.Errorf("expected digits only, got %s (err: %v)", pw, err)
+	}
+}
+
+func TestPassword_Errors(t *testing.T) {
+	tests := []Options{
+		{Length: 0},
+		{Length: 4, MinCounts: map[string]int{ClassDigits: 5}},
+		{Length: 10, Classes: "lower", MinCounts: map[string]int{ClassDigits: 1}},
+		{Length: 10, Classes: "unknown"},
+	}
+	for _, opts := range tests {
+		if _, err := Password(opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
+
+func TestWordlist(t *testing.T) {
+	list := Wordlist()
+	if len(list) != 7776 {
+		t.Fatalf("expected 7776 words, got %d", len(list))
+	}
+	if list[0] != "abacus" || list[len(list)-1] != "zoom" {
+		t.Errorf("unexpected first/last word: %s %s", list[0], list[len(list)-1])
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	phrase, err := Passphrase(6, " ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parts := strings.Split(phrase, " "); len(parts) != 6 {
+		t.Errorf("expected 6 words, got %s", phrase)
+	}
+	if _, err := Passphrase(0, "-"); err == nil {
+		t.Error("expected error for 0 words")
+	}
+}
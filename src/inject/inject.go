@@ -0,0 +1,142 @@
+// Package inject runs a command with secrets of the database injected into its environment.
+package inject
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"kpasscli/src/debug"
+	"kpasscli/src/search"
+)
+
+// DefaultField is the field of an environment reference without field name.
+const DefaultField = "Password"
+
+// Mask replaces the secrets in the output of the command.
+const Mask = "********"
+
+// EnvRef is a reference of an environment variable to a field of an entry,
+// given as NAME=item:field, e.g. DB_PASS=/Prod/DB:Password.
+type EnvRef struct {
+	// Name is the name of the environment variable
+	Name string
+	// Item is the entry as for the get command
+	Item string
+	// Field is the field of the entry
+	Field string
+}
+
+// ParseEnvRef parses an environment reference NAME=item[:field], the reference is parsed by
+// search.ParseRef and the field defaults to DefaultField.
+//
+// Parameters:
+//   - s: The reference, e.g. "DB_PASS=/Prod/DB:Password".
+//
+// Returns:
+//   - EnvRef: The parsed reference.
+//   - error: An error if the name or the item is missing.
+func ParseEnvRef(s string) (EnvRef, error) {
+	name, ref, ok := strings.Cut(s, "=")
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return EnvRef{}, fmt.Errorf("invalid environment reference %q, expected NAME=item:field", s)
+	}
+	item, field, err := search.ParseRef(ref, DefaultField)
+	if err != nil {
+		return EnvRef{}, fmt.Errorf("invalid environment reference %q, expected NAME=item:field", s)
+	}
+	return EnvRef{Name: name, Item: item, Field: field}, nil
+}
+
+// ExitError is returned by Run, if the command exited with a non-zero status.
+type ExitError struct {
+	// Code is the exit status of the command, 128+n if it was terminated by signal n
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
+
+// Command is a command to run with an injected environment.
+type Command struct {
+	// Args are the command and its arguments
+	Args []string
+	// Env is the complete environment of the command
+	Env []string
+	// Secrets are masked in the output of the command, nil disables masking
+	Secrets []string
+	// Stdin, Stdout and Stderr of the command
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// forwardedSignals are passed on to the command while it runs.
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// Run starts the command, forwards the signals received meanwhile to it and waits until it exits.
+// If secrets are given, stdout and stderr of the command are filtered and each secret is replaced by Mask.
+//
+// Returns:
+//   - error: An *ExitError with the exit status of the command, or an error if the command can not be started.
+func (c *Command) Run() error {
+	if len(c.Args) == 0 {
+		return fmt.Errorf("no command given")
+	}
+	child := exec.Command(c.Args[0], c.Args[1:]...)
+	child.Env = c.Env
+	child.Stdin = c.Stdin
+	child.Stdout = c.Stdout
+	child.Stderr = c.Stderr
+
+	var masks []*MaskWriter
+	if len(c.Secrets) > 0 {
+		stdout := NewMaskWriter(c.Stdout, c.Secrets)
+		stderr := NewMaskWriter(c.Stderr, c.Secrets)
+		child.Stdout, child.Stderr = stdout, stderr
+		masks = append(masks, stdout, stderr)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		return err
+	}
+	debug.Log("Started command %s (PID: %d)", c.Args[0], child.Process.Pid)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				debug.Log("Forwarding signal %v to command", sig)
+				child.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	err := child.Wait()
+	close(done)
+	for _, m := range masks {
+		m.Flush()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			code = 128 + int(status.Signal())
+		}
+		return &ExitError{Code: code}
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package inject
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvRef(t *testing.T) {
+	tests := []struct {
+		in   string
+		want EnvRef
+	}{
+		{"DB_PASS=/Prod/DB:Password", EnvRef{Name: "DB_PASS", Item: "/Prod/DB", Field: "Password"}},
+		{"DB_USER=DB:UserName", EnvRef{Name: "DB_USER", Item: "DB", Field: "UserName"}},
+		{"TOKEN=GitHub", EnvRef{Name: "TOKEN", Item: "GitHub", Field: DefaultField}},
+		{"URL=/Web/a:b:URL", EnvRef{Name: "URL", Item: "/Web/a:b", Field: "URL"}},
+		{"DB=uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60", EnvRef{Name: "DB", Item: "uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60", Field: DefaultField}},
+		{"DB=uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60:UserName", EnvRef{Name: "DB", Item: "uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60", Field: "UserName"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseEnvRef(tt.in)
+		if err != nil {
+			t.Errorf("ParseEnvRef(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseEnvRef(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"", "DB_PASS", "=DB:Password", "DB PASS=DB", "A=:Password", "A=DB:"} {
+		if _, err := ParseEnvRef(in); err == nil {
+			t.Errorf("ParseEnvRef(%q): expected error", in)
+		}
+	}
+}
+
+func TestMaskWriter(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMaskWriter(&buf, []string{"secret", "", "secret-long"})
+	for _, part := range []string{"a sec", "ret and secret-lo", "ng and sec"} {
+		if _, err := m.Write([]byte(part)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := buf.String(); got != "a ******** and ******** and " {
+		t.Errorf("before flush got %q", got)
+	}
+	if err := m.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "a ******** and ******** and sec" {
+		t.Errorf("after flush got %q", got)
+	}
+}
+
+func TestCommand_Run(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	var stdout, stderr bytes.Buffer
+	c := &Command{
+		Args:    []string{"sh", "-c", `echo "user=$DB_USER pass=$DB_PASS"; echo "$DB_PASS" >&2`},
+		Env:     append(os.Environ(), "DB_USER=tester", "DB_PASS=s3cr3t"),
+		Secrets: []string{"s3cr3t"},
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	}
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "user=tester pass=********\n" {
+		t.Errorf("unexpected stdout %q", stdout.String())
+	}
+	if stderr.String() != "********\n" {
+		t.Errorf("unexpected stderr %q", stderr.String())
+	}
+
+	c = &Command{Args: []string{"sh", "-c", "exit 3"}, Stdout: &stdout, Stderr: &stderr}
+	var exitErr *ExitError
+	if err := c.Run(); !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Errorf("expected exit status 3, got %v", err)
+	}
+
+	c = &Command{Args: []string{"sh", "-c", "kill -TERM $$"}, Stdout: &stdout, Stderr: &stderr}
+	if err := c.Run(); !errors.As(err, &exitErr) || exitErr.Code != 128+15 {
+		t.Errorf("expected exit status 143, got %v", err)
+	}
+
+	c = &Command{Args: []string{"kpasscli-no-such-command"}}
+	if err := c.Run(); err == nil || errors.As(err, &exitErr) {
+		t.Errorf("expected start error, got %v", err)
+	}
+	if err := (&Command{}).Run(); err == nil || !strings.Contains(err.Error(), "no command") {
+		t.Errorf("expected error for empty command, got %v", err)
+	}
+}
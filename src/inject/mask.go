@@ -0,0 +1,106 @@
+package inject
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"sync"
+)
+
+// MaskWriter replaces secrets in the written data by Mask before passing it on.
+// A secret may be split over several writes, so the end of the data, which could be the
+// beginning of a secret, is held back until the next write or Flush.
+type MaskWriter struct {
+	w       io.Writer
+	secrets [][]byte
+	mu      sync.Mutex
+	pending []byte
+}
+
+// NewMaskWriter creates a MaskWriter. Empty secrets are ignored.
+//
+// Parameters:
+//   - w: The writer receiving the masked data.
+//   - secrets: The values to mask.
+//
+// Returns:
+//   - *MaskWriter: The new writer.
+func NewMaskWriter(w io.Writer, secrets []string) *MaskWriter {
+	m := &MaskWriter{w: w}
+	for _, s := range secrets {
+		if s != "" {
+			m.secrets = append(m.secrets, []byte(s))
+		}
+	}
+	// the longest secret wins, if secrets start alike
+	sort.Slice(m.secrets, func(i, j int) bool { return len(m.secrets[i]) > len(m.secrets[j]) })
+	return m
+}
+
+// Write masks the secrets in p and writes the result, except a possibly incomplete secret at the end.
+//
+// Parameters:
+//   - p: The data to write.
+//
+// Returns:
+//   - int: len(p), if the masked data was written.
+//   - error: Any error of the underlying writer.
+func (m *MaskWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = append(m.pending, p...)
+	out, rest := m.mask(m.pending, false)
+	m.pending = append(m.pending[:0], rest...)
+	if len(out) > 0 {
+		if _, err := m.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes the held back data.
+//
+// Returns:
+//   - error: Any error of the underlying writer.
+func (m *MaskWriter) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out, _ := m.mask(m.pending, true)
+	m.pending = m.pending[:0]
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := m.w.Write(out)
+	return err
+}
+
+// mask replaces the secrets in data.
+//
+// Parameters:
+//   - data: The data to mask.
+//   - final: If true, no more data follows and nothing is held back.
+//
+// Returns:
+//   - []byte: The masked data, which can be written.
+//   - []byte: The end of data, which could be the beginning of a secret.
+func (m *MaskWriter) mask(data []byte, final bool) ([]byte, []byte) {
+	var out bytes.Buffer
+	i := 0
+next:
+	for i < len(data) {
+		for _, s := range m.secrets {
+			if bytes.HasPrefix(data[i:], s) {
+				out.WriteString(Mask)
+				i += len(s)
+				continue next
+			}
+			if !final && len(data)-i < len(s) && bytes.HasPrefix(s, data[i:]) {
+				return out.Bytes(), data[i:]
+			}
+		}
+		out.WriteByte(data[i])
+		i++
+	}
+	return out.Bytes(), nil
+}
@@ -23,22 +23,28 @@ import (
 //
 //	path: Path to the KeePass database file
 //	password: Password to decrypt the database
+//	keyFile: Optional path to a key file, empty if the database is protected by password only
 //
 // Returns:
 //
 //	*gokeepasslib.Database: Decoded database object
 //	error: Any error encountered during opening or decoding
-func OpenDatabase(path string, password string) (*gokeepasslib.Database, error) {
+func OpenDatabase(path string, password string, keyFile string) (*gokeepasslib.Database, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		debug.Log("Error opening file: %v\n", err)
 		return nil, err
 	}
 	defer file.Close()
-	debug.Log("OpenDatabase %s %s", path, strings.Repeat("*", len(password)))
+	debug.Log("OpenDatabase %s %s %s", path, strings.Repeat("*", len(password)), keyFile)
+
+	credentials, err := NewCredentials(password, keyFile)
+	if err != nil {
+		return nil, err
+	}
 
 	db := gokeepasslib.NewDatabase()
-	db.Credentials = gokeepasslib.NewPasswordCredentials(password)
+	db.Credentials = credentials
 	// debug.Log("OpenDatabase\n%v\n", db)
 
 	if err := gokeepasslib.NewDecoder(file).Decode(db); err != nil {
@@ -268,3 +274,28 @@ func GetAllFieldsWithFinder(
 	showAllFields(singleEntry, *config)
 	return nil
 }
+
+// GetAttachment returns the content of an attachment of the entry.
+//
+// Parameters:
+//   - db: The KeePass database holding the attachment data.
+//   - entry: The entry the attachment belongs to.
+//   - name: The file name of the attachment.
+//
+// Returns:
+//   - []byte: The content of the attachment.
+//   - error: An error if the entry has no attachment with the name or its data is missing.
+func GetAttachment(db *gokeepasslib.Database, entry *gokeepasslib.Entry, name string) ([]byte, error) {
+	for i := range entry.Binaries {
+		ref := &entry.Binaries[i]
+		if ref.Name != name {
+			continue
+		}
+		binary := ref.Find(db)
+		if binary == nil {
+			return nil, fmt.Errorf("data of attachment '%s' not found", name)
+		}
+		return binary.GetContentBytes()
+	}
+	return nil, fmt.Errorf("attachment '%s' not found", name)
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func TestOpenDatabase_FileNotFound(t *testing.T) {
-	_, err := OpenDatabase("/nonexistent/file.kdbx", "pw")
+	_, err := OpenDatabase("/nonexistent/file.kdbx", "pw", "")
 	if err == nil {
 		t.Error("expected error for nonexistent file")
 	}
@@ -22,7 +22,7 @@ func TestOpenDatabase_InvalidFile(t *testing.T) {
 	defer os.Remove(f.Name())
 	f.WriteString("notakeepassfile")
 	f.Close()
-	_, err = OpenDatabase(f.Name(), "pw")
+	_, err = OpenDatabase(f.Name(), "pw", "")
 	if err == nil {
 		t.Error("expected error for invalid file")
 	}
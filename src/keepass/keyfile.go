@@ -0,0 +1,90 @@
+package keepass
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/config"
+	"kpasscli/src/debug"
+)
+
+// ResolveKeyFile returns the key file path based on flag, environment, or config.
+//
+// Parameters:
+//   - flagPath: The key file path provided via command-line flag.
+//   - cfg: The configuration object containing a default key file path.
+//   - keyfileenv: The value of the KPASSCLI_KEYFILE environment variable, if set.
+//
+// Returns:
+//   - string: The resolved key file path, or empty string if no key file is used.
+func ResolveKeyFile(flagPath string, cfg *config.Config, keyfileenv string) string {
+	if flagPath != "" {
+		return flagPath
+	}
+	if keyfileenv != "" {
+		return keyfileenv
+	}
+	if cfg != nil && cfg.KeyFile != "" {
+		return cfg.KeyFile
+	}
+	return ""
+}
+
+// LoadKeyFile reads a KeePass key file and returns the 32-byte key component.
+//
+// The following formats are supported, in this order of detection:
+//   - KeePass XML key files version 1.0 (base64 data) and 2.0 (hex data with hash, .keyx)
+//   - Raw binary key files of exactly 32 bytes
+//   - Key files containing exactly 64 hexadecimal characters
+//   - Any other file, whose SHA-256 hash is used as key
+//
+// Parameters:
+//   - path: Path to the key file.
+//
+// Returns:
+//   - []byte: The key component used to build the composite key.
+//   - error: Any error encountered while reading or parsing the key file.
+func LoadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		debug.Log("Error reading key file: %v\n", err)
+		return nil, err
+	}
+	key, err := gokeepasslib.ParseKeyData(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file '%s': %w", path, err)
+	}
+	return key, nil
+}
+
+// NewCredentials builds the database credentials from a password and an optional key file.
+//
+// If keyFile is empty, only the password is used. If a key file is given and the password
+// is empty, the key file alone unlocks the database. Otherwise a composite key of both is built.
+//
+// Parameters:
+//   - password: The master password, may be empty when a key file is given.
+//   - keyFile: Path to the key file, may be empty.
+//
+// Returns:
+//   - *gokeepasslib.DBCredentials: The credentials to unlock the database.
+//   - error: Any error encountered while loading the key file.
+func NewCredentials(password string, keyFile string) (*gokeepasslib.DBCredentials, error) {
+	if keyFile == "" {
+		return gokeepasslib.NewPasswordCredentials(password), nil
+	}
+	key, err := LoadKeyFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		debug.Log("Using key file %s without password", keyFile)
+		return &gokeepasslib.DBCredentials{Key: key}, nil
+	}
+	debug.Log("Using composite key of password and key file %s", keyFile)
+	credentials := gokeepasslib.NewPasswordCredentials(password)
+	credentials.Key = key
+	return credentials, nil
+}
@@ -0,0 +1,183 @@
+package keepass
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/config"
+)
+
+// testKey is a fixed 32-byte key used to build the key file fixtures.
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeTestDatabase encodes a small database with one entry "/Root/Account" using the given credentials.
+func writeTestDatabase(t *testing.T, path string, credentials *gokeepasslib.DBCredentials) {
+	t.Helper()
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: "Account"}},
+		gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: "secret"}},
+	)
+	root := gokeepasslib.NewGroup()
+	root.Name = "Root"
+	root.Entries = append(root.Entries, entry)
+
+	db := gokeepasslib.NewDatabase()
+	db.Credentials = credentials
+	db.Content.Root.Groups = []gokeepasslib.Group{root}
+	if err := db.LockProtectedEntries(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := gokeepasslib.NewEncoder(f).Encode(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKeyFile_Formats(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha256.Sum256(testKey)
+	arbitrary := []byte("just some arbitrary content used as key file")
+	arbitraryHash := sha256.Sum256(arbitrary)
+
+	xmlV1 := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<KeyFile>
+	<Meta><Version>1.00</Version></Meta>
+	<Key><Data>%s</Data></Key>
+</KeyFile>`, base64.StdEncoding.EncodeToString(testKey))
+	xmlV2 := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<KeyFile>
+	<Meta><Version>2.0</Version></Meta>
+	<Key><Data Hash="%X">%s</Data></Key>
+</KeyFile>`, hash[:4], strings.ToUpper(hex.EncodeToString(testKey)))
+
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{"xml v1", []byte(xmlV1), testKey},
+		{"xml v2", []byte(xmlV2), testKey},
+		{"raw 32 byte", testKey, testKey},
+		{"hex 64", []byte(hex.EncodeToString(testKey)), testKey},
+		{"arbitrary", arbitrary, arbitraryHash[:]},
+	}
+	for _, tc := range tests {
+		path := writeFile(t, dir, strings.ReplaceAll(tc.name, " ", "_")+".key", tc.data)
+		got, err := LoadKeyFile(path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("%s: got key %x, want %x", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestLoadKeyFile_InvalidXMLHash(t *testing.T) {
+	dir := t.TempDir()
+	xmlV2 := fmt.Sprintf(`<KeyFile><Meta><Version>2.0</Version></Meta><Key><Data Hash="00000000">%s</Data></Key></KeyFile>`, hex.EncodeToString(testKey))
+	path := writeFile(t, dir, "bad.keyx", []byte(xmlV2))
+	if _, err := LoadKeyFile(path); err == nil {
+		t.Error("expected error for key file with invalid hash")
+	}
+}
+
+func TestLoadKeyFile_NotExist(t *testing.T) {
+	if _, err := LoadKeyFile("/nonexistent/file.key"); err == nil {
+		t.Error("expected error for nonexistent key file")
+	}
+}
+
+func TestNewCredentials(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := writeFile(t, dir, "raw.key", testKey)
+
+	c, err := NewCredentials("pw", "")
+	if err != nil || c.Passphrase == nil || c.Key != nil {
+		t.Errorf("expected password only credentials, got %v (err: %v)", c, err)
+	}
+	c, err = NewCredentials("", keyPath)
+	if err != nil || c.Passphrase != nil || !bytes.Equal(c.Key, testKey) {
+		t.Errorf("expected key only credentials, got %v (err: %v)", c, err)
+	}
+	c, err = NewCredentials("pw", keyPath)
+	if err != nil || c.Passphrase == nil || !bytes.Equal(c.Key, testKey) {
+		t.Errorf("expected composite credentials, got %v (err: %v)", c, err)
+	}
+}
+
+func TestResolveKeyFile(t *testing.T) {
+	cfg := &config.Config{KeyFile: "cfg.keyx"}
+	if got := ResolveKeyFile("flag.keyx", cfg, "env.keyx"); got != "flag.keyx" {
+		t.Errorf("flag key file not used: got %v", got)
+	}
+	if got := ResolveKeyFile("", cfg, "env.keyx"); got != "env.keyx" {
+		t.Errorf("env key file not used: got %v", got)
+	}
+	if got := ResolveKeyFile("", cfg, ""); got != "cfg.keyx" {
+		t.Errorf("config key file not used: got %v", got)
+	}
+	if got := ResolveKeyFile("", nil, ""); got != "" {
+		t.Errorf("expected empty string, got %v", got)
+	}
+}
+
+func TestOpenDatabase_WithKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := writeFile(t, dir, "db.key", []byte(hex.EncodeToString(testKey)))
+
+	composite, err := NewCredentials("pw", keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compositeDB := filepath.Join(dir, "composite.kdbx")
+	writeTestDatabase(t, compositeDB, composite)
+
+	keyOnly, err := NewCredentials("", keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyOnlyDB := filepath.Join(dir, "keyonly.kdbx")
+	writeTestDatabase(t, keyOnlyDB, keyOnly)
+
+	db, err := OpenDatabase(compositeDB, "pw", keyPath)
+	if err != nil {
+		t.Fatalf("unexpected error opening composite key database: %v", err)
+	}
+	if got := db.Content.Root.Groups[0].Entries[0].GetPassword(); got != "secret" {
+		t.Errorf("expected password 'secret', got '%v'", got)
+	}
+	if _, err := OpenDatabase(compositeDB, "pw", ""); err == nil {
+		t.Error("expected error opening composite key database without key file")
+	}
+	if _, err := OpenDatabase(keyOnlyDB, "", keyPath); err != nil {
+		t.Errorf("unexpected error opening key file only database: %v", err)
+	}
+	if _, err := OpenDatabase(keyOnlyDB, "pw", keyPath); err == nil {
+		t.Error("expected error opening key file only database with password")
+	}
+}
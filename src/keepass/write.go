@@ -0,0 +1,404 @@
+package keepass
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+
+	"kpasscli/src/debug"
+)
+
+// recycleBinName is the name of the recycle bin group, if it has to be created
+const recycleBinName = "Recycle Bin"
+
+// recycleBinIconID is the KeePass icon of the recycle bin group
+const recycleBinIconID = 43
+
+// FieldValue is a field assignment of the form Key=Value
+type FieldValue struct {
+	Key   string
+	Value string
+}
+
+// ParseFieldValues parses field assignments of the form Field=Value.
+//
+// Parameters:
+//   - args: The assignments, e.g. []string{"UserName=tester", "URL=https://example.com"}.
+//
+// Returns:
+//   - []FieldValue: The parsed assignments in the given order.
+//   - error: An error if an assignment has no "=" or an empty field name.
+func ParseFieldValues(args []string) ([]FieldValue, error) {
+	values := make([]FieldValue, 0, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid field assignment '%s', expected Field=Value", arg)
+		}
+		values = append(values, FieldValue{Key: strings.TrimSpace(key), Value: value})
+	}
+	return values, nil
+}
+
+// splitPath splits an absolute path (starting with the root group name) or a path
+// relative to the root group into its group names.
+//
+// Parameters:
+//   - db: The KeePass database.
+//   - path: The path to split.
+//
+// Returns:
+//   - []string: The group names below the root group.
+//   - error: An error if the database has no root group or an absolute path does not start with it.
+func splitPath(db *gokeepasslib.Database, path string) ([]string, error) {
+	if db == nil || db.Content == nil || db.Content.Root == nil || len(db.Content.Root.Groups) == 0 {
+		return nil, fmt.Errorf("database has no root group")
+	}
+	var parts []string
+	for _, p := range strings.Split(strings.Trim(path, "/"), "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if strings.HasPrefix(path, "/") {
+		rootName := db.Content.Root.Groups[0].Name
+		if len(parts) == 0 || parts[0] != rootName {
+			return nil, fmt.Errorf("path '%s' does not start with the root group '/%s'", path, rootName)
+		}
+		parts = parts[1:]
+	}
+	return parts, nil
+}
+
+// ensureGroup returns the group with the given names below the root group
+// and creates all missing groups.
+//
+// Parameters:
+//   - db: The KeePass database.
+//   - names: The group names below the root group.
+//
+// Returns:
+//   - *gokeepasslib.Group: The found or created group.
+func ensureGroup(db *gokeepasslib.Database, names []string) *gokeepasslib.Group {
+	group := &db.Content.Root.Groups[0]
+	for _, name := range names {
+		found := false
+		for i := range group.Groups {
+			if group.Groups[i].Name == name {
+				group = &group.Groups[i]
+				found = true
+				break
+			}
+		}
+		if !found {
+			debug.Log("Creating group: %s", name)
+			newGroup := gokeepasslib.NewGroup()
+			newGroup.Name = name
+			group.Groups = append(group.Groups, newGroup)
+			group = &group.Groups[len(group.Groups)-1]
+		}
+	}
+	return group
+}
+
+// AddEntry creates a new entry at the given path. Missing groups are created.
+// The last element of the path is used as title.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - path: The path of the new entry, absolute (e.g. "/Root/Banking/Account") or relative to the root group.
+//   - values: The fields of the new entry.
+//
+// Returns:
+//   - *gokeepasslib.Entry: The new entry, stored in the database.
+//   - error: An error if the path is invalid or an entry with the title already exists in the group.
+func AddEntry(db *gokeepasslib.Database, path string, values []FieldValue) (*gokeepasslib.Entry, error) {
+	parts, err := splitPath(db, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no entry title in path '%s'", path)
+	}
+	title := parts[len(parts)-1]
+	group := ensureGroup(db, parts[:len(parts)-1])
+	for i := range group.Entries {
+		if group.Entries[i].GetTitle() == title {
+			return nil, fmt.Errorf("entry already exists: %s", path)
+		}
+	}
+
+	entry := gokeepasslib.NewEntry()
+	setValue(&entry, "Title", title)
+	for _, key := range []string{"UserName", "Password", "URL", "Notes"} {
+		setValue(&entry, key, "")
+	}
+	for _, v := range values {
+		setValue(&entry, v.Key, v.Value)
+	}
+	group.Entries = append(group.Entries, entry)
+	debug.Log("Added entry: %s", path)
+	return &group.Entries[len(group.Entries)-1], nil
+}
+
+// SetFields changes fields of an entry. The previous version of the entry is stored
+// in the history of the entry and the modification time is updated.
+//
+// Parameters:
+//   - db: The opened KeePass database, used for the history limit.
+//   - entry: The entry to change, which must be stored in the database (see LocateEntry).
+//   - values: The fields to set, new fields are added.
+func SetFields(db *gokeepasslib.Database, entry *gokeepasslib.Entry, values []FieldValue) {
+	addHistory(db, entry)
+	for _, v := range values {
+		setValue(entry, v.Key, v.Value)
+	}
+	now := wrappers.Now()
+	entry.Times.LastModificationTime = &now
+	entry.Times.LastAccessTime = &now
+}
+
+// setValue sets the value of a field, new fields are appended. The password is always protected.
+//
+// Parameters:
+//   - entry: The entry to change.
+//   - key: The field name.
+//   - value: The new value.
+func setValue(entry *gokeepasslib.Entry, key, value string) {
+	if i := entry.GetIndex(key); i >= 0 {
+		entry.Values[i].Value.Content = value
+		return
+	}
+	entry.Values = append(entry.Values, gokeepasslib.ValueData{
+		Key:   key,
+		Value: gokeepasslib.V{Content: value, Protected: wrappers.NewBoolWrapper(key == "Password")},
+	})
+}
+
+// addHistory stores a copy of the current entry in its history,
+// removing the oldest versions beyond the HistoryMaxItems of the database.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - entry: The entry to record.
+func addHistory(db *gokeepasslib.Database, entry *gokeepasslib.Entry) {
+	snapshot := *entry
+	snapshot.Values = append([]gokeepasslib.ValueData(nil), entry.Values...)
+	snapshot.Binaries = append([]gokeepasslib.BinaryReference(nil), entry.Binaries...)
+	snapshot.Histories = nil
+
+	if len(entry.Histories) == 0 {
+		entry.Histories = []gokeepasslib.History{{}}
+	}
+	history := &entry.Histories[0]
+	history.Entries = append(history.Entries, snapshot)
+	if db.Content.Meta != nil && db.Content.Meta.HistoryMaxItems >= 0 {
+		if max := int(db.Content.Meta.HistoryMaxItems); len(history.Entries) > max {
+			history.Entries = history.Entries[len(history.Entries)-max:]
+		}
+	}
+}
+
+// LocateEntry finds the entry with the given UUID in the database.
+// The search results contain copies of the entries, to change an entry it has to be located in the database.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - uuid: The UUID of the entry.
+//
+// Returns:
+//   - *gokeepasslib.Group: The group containing the entry.
+//   - int: The index of the entry in the group.
+//   - error: An error if no entry with the UUID exists.
+func LocateEntry(db *gokeepasslib.Database, uuid gokeepasslib.UUID) (*gokeepasslib.Group, int, error) {
+	if db != nil && db.Content != nil && db.Content.Root != nil {
+		for i := range db.Content.Root.Groups {
+			if group, index := locateEntryInGroup(&db.Content.Root.Groups[i], uuid); group != nil {
+				return group, index, nil
+			}
+		}
+	}
+	return nil, -1, fmt.Errorf("entry not found in database")
+}
+
+// locateEntryInGroup recursively searches the entry with the given UUID.
+//
+// Parameters:
+//   - group: The group to search.
+//   - uuid: The UUID of the entry.
+//
+// Returns:
+//   - *gokeepasslib.Group: The group containing the entry or nil.
+//   - int: The index of the entry in the group.
+func locateEntryInGroup(group *gokeepasslib.Group, uuid gokeepasslib.UUID) (*gokeepasslib.Group, int) {
+	for i := range group.Entries {
+		if group.Entries[i].UUID.Compare(uuid) {
+			return group, i
+		}
+	}
+	for i := range group.Groups {
+		if g, index := locateEntryInGroup(&group.Groups[i], uuid); g != nil {
+			return g, index
+		}
+	}
+	return nil, -1
+}
+
+// findGroupByUUID recursively searches the group with the given UUID.
+//
+// Parameters:
+//   - group: The group to start with.
+//   - uuid: The UUID of the group.
+//
+// Returns:
+//   - *gokeepasslib.Group: The found group or nil.
+func findGroupByUUID(group *gokeepasslib.Group, uuid gokeepasslib.UUID) *gokeepasslib.Group {
+	if group.UUID.Compare(uuid) {
+		return group
+	}
+	for i := range group.Groups {
+		if g := findGroupByUUID(&group.Groups[i], uuid); g != nil {
+			return g
+		}
+	}
+	return nil
+}
+
+// recycleBin returns the recycle bin group of the database and creates it, if it does not exist.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//
+// Returns:
+//   - *gokeepasslib.Group: The recycle bin group.
+func recycleBin(db *gokeepasslib.Database) *gokeepasslib.Group {
+	root := &db.Content.Root.Groups[0]
+	if bin := findGroupByUUID(root, db.Content.Meta.RecycleBinUUID); bin != nil && bin != root {
+		return bin
+	}
+	debug.Log("Creating recycle bin group")
+	bin := gokeepasslib.NewGroup()
+	bin.Name = recycleBinName
+	bin.IconID = recycleBinIconID
+	bin.EnableAutoType = wrappers.NewNullableBoolWrapper(false)
+	bin.EnableSearching = wrappers.NewNullableBoolWrapper(false)
+	root.Groups = append(root.Groups, bin)
+	now := wrappers.Now()
+	db.Content.Meta.RecycleBinUUID = bin.UUID
+	db.Content.Meta.RecycleBinChanged = &now
+	return &root.Groups[len(root.Groups)-1]
+}
+
+// DeleteEntry moves the entry with the given UUID to the recycle bin. If the recycle bin is
+// disabled or the entry is already in the recycle bin, the entry is deleted permanently.
+//
+// Parameters:
+//   - db: The opened KeePass database.
+//   - uuid: The UUID of the entry.
+//
+// Returns:
+//   - bool: True if the entry was deleted permanently, false if it was moved to the recycle bin.
+//   - error: An error if the entry does not exist.
+func DeleteEntry(db *gokeepasslib.Database, uuid gokeepasslib.UUID) (bool, error) {
+	group, index, err := LocateEntry(db, uuid)
+	if err != nil {
+		return false, err
+	}
+	meta := db.Content.Meta
+	toRecycleBin := meta != nil && meta.RecycleBinEnabled.Bool
+	if toRecycleBin {
+		if bin := findGroupByUUID(&db.Content.Root.Groups[0], meta.RecycleBinUUID); bin != nil {
+			if g, _ := locateEntryInGroup(bin, uuid); g != nil {
+				toRecycleBin = false
+			}
+		}
+	}
+
+	entry := group.Entries[index]
+	group.Entries = append(group.Entries[:index], group.Entries[index+1:]...)
+	now := wrappers.Now()
+
+	if toRecycleBin {
+		bin := recycleBin(db)
+		entry.Times.LocationChanged = &now
+		bin.Entries = append(bin.Entries, entry)
+		debug.Log("Moved entry %s to the recycle bin", entry.GetTitle())
+		return false, nil
+	}
+
+	db.Content.Root.DeletedObjects = append(db.Content.Root.DeletedObjects,
+		gokeepasslib.DeletedObjectData{UUID: entry.UUID, DeletionTime: &now})
+	debug.Log("Deleted entry %s permanently", entry.GetTitle())
+	return true, nil
+}
+
+// SaveDatabase encodes the database to a temporary file next to path, which then replaces
+// the database file by an atomic rename. The file mode of an existing database is kept.
+//
+// Parameters:
+//   - db: The opened (unlocked) KeePass database.
+//   - path: Path to the KeePass database file.
+//   - backup: If true, the previous database file is copied to path + ".bak" before it is replaced.
+//
+// Returns:
+//   - error: Any error encountered during encoding, writing or renaming.
+func SaveDatabase(db *gokeepasslib.Database, path string, backup bool) error {
+	mode := os.FileMode(0600)
+	info, err := os.Stat(path)
+	if err == nil {
+		mode = info.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op after a successful rename
+
+	if err := db.LockProtectedEntries(); err != nil {
+		tmp.Close()
+		return err
+	}
+	encodeErr := gokeepasslib.NewEncoder(tmp).Encode(db)
+	if err := db.UnlockProtectedEntries(); err != nil && encodeErr == nil {
+		encodeErr = err
+	}
+	if encodeErr != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode database: %w", encodeErr)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+
+	if backup && info != nil {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path+".bak", data, mode); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+		debug.Log("Saved backup: %s.bak", path)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	debug.Log("Saved database: %s", path)
+	return nil
+}
@@ -0,0 +1,191 @@
+package keepass
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+// newWriteTestDatabase returns a database with the entry /Root/Banking/Account.
+func newWriteTestDatabase() *gokeepasslib.Database {
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: "Account"}},
+		gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: "secret", Protected: wrappers.NewBoolWrapper(true)}},
+	)
+	banking := gokeepasslib.NewGroup()
+	banking.Name = "Banking"
+	banking.Entries = append(banking.Entries, entry)
+	root := gokeepasslib.NewGroup()
+	root.Name = "Root"
+	root.Groups = append(root.Groups, banking)
+
+	db := gokeepasslib.NewDatabase()
+	db.Content.Root.Groups = []gokeepasslib.Group{root}
+	return db
+}
+
+func TestParseFieldValues(t *testing.T) {
+	values, err := ParseFieldValues([]string{"UserName=tester", "URL=https://a.example/?x=1", "Notes="})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []FieldValue{{"UserName", "tester"}, {"URL", "https://a.example/?x=1"}, {"Notes", ""}}
+	for i, v := range want {
+		if values[i] != v {
+			t.Errorf("got %v, want %v", values[i], v)
+		}
+	}
+	for _, arg := range []string{"UserName", "=value"} {
+		if _, err := ParseFieldValues([]string{arg}); err == nil {
+			t.Errorf("expected error for %q", arg)
+		}
+	}
+}
+
+func TestAddEntry(t *testing.T) {
+	db := newWriteTestDatabase()
+	entry, err := AddEntry(db, "/Root/Mail/Work/Outlook", []FieldValue{{"UserName", "tester"}, {"Password", "pw"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.GetTitle() != "Outlook" || entry.GetContent("UserName") != "tester" {
+		t.Errorf("unexpected entry values: %v", entry.Values)
+	}
+	if !entry.Get("Password").Value.Protected.Bool {
+		t.Error("expected password to be protected")
+	}
+	mail := db.Content.Root.Groups[0].Groups[1]
+	if mail.Name != "Mail" || len(mail.Groups) != 1 || mail.Groups[0].Name != "Work" || len(mail.Groups[0].Entries) != 1 {
+		t.Errorf("expected groups Mail/Work to be created, got %+v", mail)
+	}
+
+	if _, err := AddEntry(db, "Banking/Account", nil); err == nil {
+		t.Error("expected error for existing entry")
+	}
+	if _, err := AddEntry(db, "/Other/Account", nil); err == nil {
+		t.Error("expected error for path outside of the root group")
+	}
+	if _, err := AddEntry(db, "Banking/New", nil); err != nil || len(db.Content.Root.Groups[0].Groups[0].Entries) != 2 {
+		t.Errorf("expected entry added to existing group, got %v", err)
+	}
+}
+
+func TestSetFields_History(t *testing.T) {
+	db := newWriteTestDatabase()
+	db.Content.Meta.HistoryMaxItems = 2
+	entry := &db.Content.Root.Groups[0].Groups[0].Entries[0]
+
+	for _, pw := range []string{"one", "two", "three"} {
+		SetFields(db, entry, []FieldValue{{"Password", pw}, {"Custom", "c"}})
+	}
+	if entry.GetPassword() != "three" || entry.GetContent("Custom") != "c" {
+		t.Errorf("unexpected values: %v", entry.Values)
+	}
+	history := entry.Histories[0].Entries
+	if len(history) != 2 {
+		t.Fatalf("expected history limited to 2 entries, got %d", len(history))
+	}
+	if history[0].GetPassword() != "one" || history[1].GetPassword() != "two" {
+		t.Errorf("unexpected history passwords: %s, %s", history[0].GetPassword(), history[1].GetPassword())
+	}
+	if !history[1].UUID.Compare(entry.UUID) {
+		t.Error("expected history entries to keep the UUID")
+	}
+}
+
+func TestLocateEntry(t *testing.T) {
+	db := newWriteTestDatabase()
+	uuid := db.Content.Root.Groups[0].Groups[0].Entries[0].UUID
+	group, index, err := LocateEntry(db, uuid)
+	if err != nil || group.Name != "Banking" || index != 0 {
+		t.Errorf("unexpected result: %v %d %v", group, index, err)
+	}
+	if _, _, err := LocateEntry(db, gokeepasslib.NewUUID()); err == nil {
+		t.Error("expected error for unknown UUID")
+	}
+}
+
+func TestDeleteEntry(t *testing.T) {
+	db := newWriteTestDatabase()
+	uuid := db.Content.Root.Groups[0].Groups[0].Entries[0].UUID
+	permanent, err := DeleteEntry(db, uuid)
+	if err != nil || !permanent {
+		t.Errorf("expected permanent deletion with disabled recycle bin, got %v %v", permanent, err)
+	}
+	if len(db.Content.Root.DeletedObjects) != 1 {
+		t.Error("expected deleted object to be recorded")
+	}
+
+	db = newWriteTestDatabase()
+	db.Content.Meta.RecycleBinEnabled = wrappers.NewBoolWrapper(true)
+	uuid = db.Content.Root.Groups[0].Groups[0].Entries[0].UUID
+	permanent, err = DeleteEntry(db, uuid)
+	if err != nil || permanent {
+		t.Fatalf("expected move to recycle bin, got %v %v", permanent, err)
+	}
+	group, _, err := LocateEntry(db, uuid)
+	if err != nil || group.Name != recycleBinName || !group.UUID.Compare(db.Content.Meta.RecycleBinUUID) {
+		t.Fatalf("expected entry in recycle bin, got %v %v", group, err)
+	}
+	if len(db.Content.Root.Groups[0].Groups[0].Entries) != 0 {
+		t.Error("expected entry removed from Banking")
+	}
+
+	permanent, err = DeleteEntry(db, uuid)
+	if err != nil || !permanent {
+		t.Errorf("expected permanent deletion from recycle bin, got %v %v", permanent, err)
+	}
+	if _, err := DeleteEntry(db, uuid); err == nil {
+		t.Error("expected error for deleted entry")
+	}
+}
+
+func TestSaveDatabase(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.kdbx")
+	credentials, _ := NewCredentials("pw", "")
+	writeTestDatabase(t, path, credentials)
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenDatabase(path, "pw", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddEntry(db, "Banking/New", []FieldValue{{"Password", "newpw"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveDatabase(db, path, true); err != nil {
+		t.Fatalf("unexpected error saving database: %v", err)
+	}
+	if db.Content.Root.Groups[0].Entries[0].GetPassword() != "secret" {
+		t.Error("expected database to stay unlocked after saving")
+	}
+
+	saved, err := OpenDatabase(path, "pw", "")
+	if err != nil {
+		t.Fatalf("unexpected error opening saved database: %v", err)
+	}
+	if got := saved.Content.Root.Groups[0].Groups[0].Entries[0].GetPassword(); got != "newpw" {
+		t.Errorf("expected saved password 'newpw', got '%s'", got)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("expected file mode 0640 to be kept, got %v %v", info.Mode().Perm(), err)
+	}
+	backup, err := OpenDatabase(path+".bak", "pw", "")
+	if err != nil {
+		t.Fatalf("unexpected error opening backup: %v", err)
+	}
+	if len(backup.Content.Root.Groups[0].Groups) != 0 {
+		t.Error("expected backup to contain the previous database")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected only database and backup in directory, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,163 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Field is a named value of an entry.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// WriteFields writes the fields in the given output format:
+//
+//   - text: "Name: value" per line
+//   - json: an object with the field names as keys
+//   - yaml: a mapping with the field names as keys
+//   - dotenv/env: NAME="value" per line, see EnvName
+//   - shell: export NAME='value' per line, for eval in POSIX shells
+//   - raw: the values, each terminated by a NUL character
+//
+// Parameters:
+//   - w: The writer to write the fields to.
+//   - fields: The fields in output order.
+//   - format: The output format.
+//
+// Returns:
+//   - error: An error for unknown formats or any error encountered while writing.
+func WriteFields(w io.Writer, fields []Field, format string) error {
+	switch format {
+	case FormatText:
+		for _, f := range fields {
+			if _, err := fmt.Fprintf(w, "%s: %s\n", f.Name, f.Value); err != nil {
+				return err
+			}
+		}
+	case FormatJSON:
+		return writeFieldsJSON(w, fields)
+	case FormatYAML:
+		m := make(yaml.MapSlice, 0, len(fields))
+		for _, f := range fields {
+			m = append(m, yaml.MapItem{Key: f.Name, Value: f.Value})
+		}
+		data, err := yaml.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatDotenv, FormatEnv:
+		for _, f := range fields {
+			if _, err := fmt.Fprintf(w, "%s=%s\n", EnvName(f.Name), dotenvQuote(f.Value)); err != nil {
+				return err
+			}
+		}
+	case FormatShell:
+		for _, f := range fields {
+			if _, err := fmt.Fprintf(w, "export %s=%s\n", EnvName(f.Name), ShellQuote(f.Value)); err != nil {
+				return err
+			}
+		}
+	case FormatRaw:
+		for _, f := range fields {
+			if _, err := fmt.Fprintf(w, "%s\x00", f.Value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+	return nil
+}
+
+// writeFieldsJSON writes the fields as JSON object, keeping the order of the fields.
+//
+// Parameters:
+//   - w: The writer to write the object to.
+//   - fields: The fields in output order.
+//
+// Returns:
+//   - error: Any error encountered while encoding or writing.
+func writeFieldsJSON(w io.Writer, fields []Field) error {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, f := range fields {
+		key, err := json.Marshal(f.Name)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "\n  %s: %s", key, value)
+	}
+	if len(fields) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// EnvName converts a field name to an environment variable name:
+// letters are upper cased, all other characters except digits are replaced by "_",
+// e.g. "UserName" becomes "USERNAME" and "TOTP Seed" becomes "TOTP_SEED".
+//
+// Parameters:
+//   - name: The field name.
+//
+// Returns:
+//   - string: The environment variable name.
+func EnvName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(unicode.ToUpper(r))
+		default:
+			b.WriteRune('_')
+		}
+	}
+	env := b.String()
+	if env == "" || unicode.IsDigit(rune(env[0])) {
+		env = "_" + env
+	}
+	return env
+}
+
+// ShellQuote quotes a value with single quotes for POSIX shells.
+// A single quote in the value ends the quoting, is escaped by a backslash and the quoting is reopened.
+//
+// Parameters:
+//   - value: The value to quote.
+//
+// Returns:
+//   - string: The quoted value.
+func ShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// dotenvQuote quotes a value with double quotes for dotenv files.
+// Backslashes, double quotes, dollar signs and line breaks are escaped, so docker compose and
+// python-dotenv do not expand ${VAR} in the value.
+//
+// Parameters:
+//   - value: The value to quote.
+//
+// Returns:
+//   - string: The quoted value.
+func dotenvQuote(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(value) + `"`
+}
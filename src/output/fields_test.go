@@ -0,0 +1,104 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteFields_Formats(t *testing.T) {
+	fields := []Field{
+		{Name: "UserName", Value: "tester"},
+		{Name: "Password", Value: "it's \"secret\"\n$HOME"},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatText, "UserName: tester\nPassword: it's \"secret\"\n$HOME\n"},
+		{FormatJSON, "{\n  \"UserName\": \"tester\",\n  \"Password\": \"it's \\\"secret\\\"\\n$HOME\"\n}\n"},
+		{FormatYAML, "UserName: tester\nPassword: |-\n  it's \"secret\"\n  $HOME\n"},
+		{FormatDotenv, "USERNAME=\"tester\"\nPASSWORD=\"it's \\\"secret\\\"\\n\\$HOME\"\n"},
+		{FormatEnv, "USERNAME=\"tester\"\nPASSWORD=\"it's \\\"secret\\\"\\n\\$HOME\"\n"},
+		{FormatShell, "export USERNAME='tester'\nexport PASSWORD='it'\\''s \"secret\"\n$HOME'\n"},
+		{FormatRaw, "tester\x00it's \"secret\"\n$HOME\x00"},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		if err := WriteFields(&sb, fields, tt.format); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if sb.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, sb.String(), tt.want)
+		}
+	}
+}
+
+func TestWriteFields_DotenvDollar(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteFields(&sb, []Field{{Name: "Password", Value: "p'a${DB_PASS}\n$1"}}, FormatDotenv); err != nil {
+		t.Fatal(err)
+	}
+	if want := `PASSWORD="p'a\${DB_PASS}\n\$1"` + "\n"; sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
+}
+
+func TestWriteFields_UnknownFormat(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteFields(&sb, []Field{{Name: "Title", Value: "x"}}, "xml"); err == nil {
+		t.Error("expected error for unknown output format")
+	}
+}
+
+func TestWriteFields_EmptyJSON(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteFields(&sb, nil, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != "{}\n" {
+		t.Errorf("got %q", sb.String())
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"UserName":  "USERNAME",
+		"TOTP Seed": "TOTP_SEED",
+		"api-key.2": "API_KEY_2",
+		"2fa":       "_2FA",
+		"Kennwört":  "KENNW_RT",
+		"":          "_",
+	}
+	for name, want := range tests {
+		if got := EnvName(name); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"":         "''",
+		"a b":      "'a b'",
+		"it's":     `'it'\''s'`,
+		"$(id)`x`": "'$(id)`x`'",
+	}
+	for value, want := range tests {
+		if got := ShellQuote(value); got != want {
+			t.Errorf("ShellQuote(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestSupportsShowAll(t *testing.T) {
+	for _, format := range []string{FormatText, FormatJSON, FormatYAML} {
+		if !SupportsShowAll(format) {
+			t.Errorf("%s should be supported by show-all", format)
+		}
+	}
+	for _, format := range []string{FormatDotenv, FormatShell, FormatRaw} {
+		if SupportsShowAll(format) {
+			t.Errorf("%s should not be supported by show-all", format)
+		}
+	}
+}
@@ -3,13 +3,17 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	// Hinzugefügt für Debug-Logs
 
 	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
 	"golang.design/x/clipboard"
+	"gopkg.in/yaml.v2"
 
 	"kpasscli/src/config"
 	"kpasscli/src/debug"
@@ -108,7 +112,7 @@ func (h *stdHandler) toClipboard(value string) error {
 	return nil
 }
 
-// toStdout prints the given value to the standard output.
+// toStdout prints the given value to the standard output, followed by a newline.
 // Parameters:
 //   - value: The value to be printed to stdout.
 //
@@ -116,6 +120,11 @@ func (h *stdHandler) toClipboard(value string) error {
 //   - error: Any error encountered during the stdout operation.
 func (h *stdHandler) toStdout(value string) error {
 	debug.Log("Printing to stdout: %s", value)
+	if strings.HasSuffix(value, "\x00") {
+		// NUL terminated values (FormatRaw) are printed without a newline
+		fmt.Print(value)
+		return nil
+	}
 	fmt.Println(value)
 	return nil
 }
@@ -136,54 +145,233 @@ func IsValidType(outputType string) bool {
 	}
 }
 
-// ShowAllFields displays all fields of a KeePass entry
-// in a human-readable format.
+// Output formats for displaying entries
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatDotenv = "dotenv"
+	FormatEnv    = "env" // alias of FormatDotenv
+	FormatShell  = "shell"
+	FormatRaw    = "raw"
+	masked       = "********"
+)
+
+// IsValidFormat checks if the provided output format is valid.
+//
 // Parameters:
+//   - format: The output format to check (string).
+//
+// Returns:
+//   - bool: True if the output format is valid, false otherwise.
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatText, FormatJSON, FormatYAML, FormatDotenv, FormatEnv, FormatShell, FormatRaw:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsShowAll checks if the output format can be used to show all fields of an entry.
+//
+// Parameters:
+//   - format: The output format to check (string).
+//
+// Returns:
+//   - bool: True for text, json and yaml.
+func SupportsShowAll(format string) bool {
+	switch format {
+	case FormatText, FormatJSON, FormatYAML:
+		return true
+	default:
+		return false
+	}
+}
+
+// ResolveOutputFormat determines the output format based on the provided flag or configuration.
+//
+// Order of precedence:
+//  1. If the flagFormat parameter is not empty, it is returned.
+//  2. If the cfg parameter is not nil and cfg.OutputFormat is not empty, it is returned.
+//  3. Otherwise it defaults to FormatText.
+//
+// Parameters:
+//   - flagFormat: A string representing the output format specified by a flag.
+//   - cfg: A pointer to a config.Config struct that may contain a default output format.
+//
+// Returns:
+//   - string: The resolved output format.
+func ResolveOutputFormat(flagFormat string, cfg *config.Config) string {
+	if flagFormat != "" {
+		return flagFormat
+	}
+	if cfg != nil && cfg.OutputFormat != "" {
+		return cfg.OutputFormat
+	}
+	return FormatText
+}
+
+// ShowAllFields displays all fields of a KeePass entry on stdout
+// in the output format of the configuration. Protected values are masked.
+// Parameters:
+//   - entry: The KeePass entry to display.
+//   - config: The configuration object containing output format settings.
+func ShowAllFields(entry *gokeepasslib.Entry, config config.Config) {
+	if err := WriteAllFields(os.Stdout, entry, config, false); err != nil {
+		fmt.Fprintf(os.Stderr, "Error showing entry: %v\n", err)
+	}
+}
+
+// WriteAllFields writes all fields of a KeePass entry to w
+// in the output format of the configuration (text/json/yaml).
+// Parameters:
+//   - w: The writer to write the entry to.
 //   - entry: The KeePass entry to display.
 //   - config: The configuration object containing output format settings.
+//   - reveal: If true, protected values like the password are shown in clear text.
 //
 // Returns:
 //   - error: Any error encountered during the operation.
-func ShowAllFields(entry *gokeepasslib.Entry, config config.Config) {
+func WriteAllFields(w io.Writer, entry *gokeepasslib.Entry, config config.Config, reveal bool) error {
 	if entry == nil {
-		return
+		return nil
+	}
+	data := newEntryData(entry, reveal)
+	switch ResolveOutputFormat("", &config) {
+	case FormatText:
+		return writeAllFieldsText(w, entry, data)
+	case FormatJSON:
+		return writeAllFieldsJSON(w, data)
+	case FormatYAML:
+		return writeAllFieldsYAML(w, data)
+	default:
+		return fmt.Errorf("unknown output format: %s", config.OutputFormat)
+	}
+}
+
+// entryData is the structured representation of an entry for json and yaml output.
+type entryData struct {
+	Title            string            `json:"title" yaml:"title"`
+	Username         string            `json:"username" yaml:"username"`
+	Password         string            `json:"password" yaml:"password"`
+	URL              string            `json:"url" yaml:"url"`
+	Notes            string            `json:"notes" yaml:"notes"`
+	AdditionalFields map[string]string `json:"additional_fields,omitempty" yaml:"additional_fields,omitempty"`
+	Metadata         struct {
+		Created  string `json:"created" yaml:"created"`
+		Modified string `json:"modified" yaml:"modified"`
+		Accessed string `json:"accessed" yaml:"accessed"`
+	} `json:"metadata" yaml:"metadata"`
+}
+
+// newEntryData collects the fields of an entry, masking protected values unless reveal is set.
+// Parameters:
+//   - entry: The KeePass entry to collect the fields from.
+//   - reveal: If true, protected values are not masked.
+//
+// Returns:
+//   - entryData: The collected fields.
+func newEntryData(entry *gokeepasslib.Entry, reveal bool) entryData {
+	data := entryData{
+		Title:            displayValue(entry, "Title", reveal),
+		Username:         displayValue(entry, "UserName", reveal),
+		Password:         displayValue(entry, "Password", reveal),
+		URL:              displayValue(entry, "URL", reveal),
+		Notes:            displayValue(entry, "Notes", reveal),
+		AdditionalFields: make(map[string]string),
 	}
 
-	if config.OutputFormat == "json" {
-		showAllFieldsJson(entry)
-		return
+	// Fill additional fields
+	for _, v := range entry.Values {
+		if isAdditionalField(v.Key) && v.Value.Content != "" {
+			data.AdditionalFields[v.Key] = displayValue(entry, v.Key, reveal)
+		}
 	}
 
-	fmt.Println(lineBreak)
-	fmt.Printf("Entry Details:\n")
-	fmt.Println(lineBreak)
+	// Fill metadata
+	data.Metadata.Created = formatTimeWrapper(entry.Times.CreationTime)
+	data.Metadata.Modified = formatTimeWrapper(entry.Times.LastModificationTime)
+	data.Metadata.Accessed = formatTimeWrapper(entry.Times.LastAccessTime)
+	return data
+}
+
+// writeAllFieldsText writes all fields of a KeePass entry in a human-readable format.
+// Parameters:
+//   - w: The writer to write the entry to.
+//   - entry: The KeePass entry, used for the order of the additional fields.
+//   - data: The collected fields of the entry.
+//
+// Returns:
+//   - error: Any error encountered while writing.
+func writeAllFieldsText(w io.Writer, entry *gokeepasslib.Entry, data entryData) error {
+	var b strings.Builder
+	fmt.Fprintln(&b, lineBreak)
+	fmt.Fprintf(&b, "Entry Details:\n")
+	fmt.Fprintln(&b, lineBreak)
 
 	// Standard fields
-	printNonEmptyValue("Title", getValue(entry, "Title"))
-	printNonEmptyValue("Username", getValue(entry, "UserName"))
-	printNonEmptyValue("URL", getValue(entry, "URL"))
-	printNonEmptyValue("Notes", getValue(entry, "Notes"))
+	printNonEmptyValue(&b, "Title", data.Title)
+	printNonEmptyValue(&b, "Username", data.Username)
+	printNonEmptyValue(&b, "Password", data.Password)
+	printNonEmptyValue(&b, "URL", data.URL)
+	printNonEmptyValue(&b, "Notes", data.Notes)
 
 	// Additional fields
 	hasAdditionalFields := false
 	for _, v := range entry.Values {
-		if isAdditionalField(v.Key) && v.Value.Content != "" {
+		if value, ok := data.AdditionalFields[v.Key]; ok {
 			if !hasAdditionalFields {
-				fmt.Println(lineBreak)
-				fmt.Println("Additional Fields:")
+				fmt.Fprintln(&b, lineBreak)
+				fmt.Fprintln(&b, "Additional Fields:")
 				hasAdditionalFields = true
 			}
-			printNonEmptyValue(v.Key, v.Value.Content)
+			printNonEmptyValue(&b, v.Key, value)
 		}
 	}
 
 	// Metadata
-	fmt.Println(lineBreak)
-	fmt.Println("Metadata:")
-	printNonEmptyValue("Created", formatTime(entry.Times.CreationTime.Time))
-	printNonEmptyValue("Modified", formatTime(entry.Times.LastModificationTime.Time))
-	printNonEmptyValue("Accessed", formatTime(entry.Times.LastAccessTime.Time))
-	fmt.Println(lineBreak)
+	fmt.Fprintln(&b, lineBreak)
+	fmt.Fprintln(&b, "Metadata:")
+	printNonEmptyValue(&b, "Created", data.Metadata.Created)
+	printNonEmptyValue(&b, "Modified", data.Metadata.Modified)
+	printNonEmptyValue(&b, "Accessed", data.Metadata.Accessed)
+	fmt.Fprintln(&b, lineBreak)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeAllFieldsJSON writes all fields of a KeePass entry in JSON format.
+// Parameters:
+//   - w: The writer to write the entry to.
+//   - data: The collected fields of the entry.
+//
+// Returns:
+//   - error: Any error encountered during marshalling or writing.
+func writeAllFieldsJSON(w io.Writer, data entryData) error {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error creating JSON output: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(jsonData))
+	return err
+}
+
+// writeAllFieldsYAML writes all fields of a KeePass entry in YAML format.
+// Parameters:
+//   - w: The writer to write the entry to.
+//   - data: The collected fields of the entry.
+//
+// Returns:
+//   - error: Any error encountered during marshalling or writing.
+func writeAllFieldsYAML(w io.Writer, data entryData) error {
+	yamlData, err := yaml.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error creating YAML output: %w", err)
+	}
+	_, err = w.Write(yamlData)
+	return err
 }
 
 // Helper functions
@@ -203,13 +391,50 @@ func getValue(entry *gokeepasslib.Entry, key string) string {
 	return ""
 }
 
-// printNonEmptyValue prints a key-value pair if the value is not empty.
+// isProtected checks if the value for a given key is protected in the entry.
+// The Password field is always treated as protected.
+// Parameters:
+//   - entry: The KeePass entry to search.
+//   - key: The key to check.
+//
+// Returns:
+//   - bool: True if the value is protected, false otherwise.
+func isProtected(entry *gokeepasslib.Entry, key string) bool {
+	if key == "Password" {
+		return true
+	}
+	for _, v := range entry.Values {
+		if v.Key == key {
+			return v.Value.Protected.Bool
+		}
+	}
+	return false
+}
+
+// displayValue returns the value for a given key, masked if it is protected and reveal is not set.
+// Parameters:
+//   - entry: The KeePass entry to search.
+//   - key: The key for which to retrieve the value.
+//   - reveal: If true, protected values are returned in clear text.
+//
+// Returns:
+//   - string: The value to display, or an empty string if not found.
+func displayValue(entry *gokeepasslib.Entry, key string, reveal bool) string {
+	value := getValue(entry, key)
+	if value != "" && !reveal && isProtected(entry, key) {
+		return masked
+	}
+	return value
+}
+
+// printNonEmptyValue prints a key-value pair to w if the value is not empty.
 // Parameters:
+//   - w: The writer to print to.
 //   - key: The key to print.
 //   - value: The value to print.
-func printNonEmptyValue(key, value string) {
+func printNonEmptyValue(w io.Writer, key, value string) {
 	if value != "" {
-		fmt.Printf("%s: %s\n", key, value)
+		fmt.Fprintf(w, "%s: %s\n", key, value)
 	}
 }
 
@@ -223,6 +448,19 @@ func formatTime(t time.Time) string {
 	return t.Format(timeFormat)
 }
 
+// formatTimeWrapper formats an optional time of the entry's time data.
+// Parameters:
+//   - t: The time wrapper to format, may be nil.
+//
+// Returns:
+//   - string: The formatted time string, or an empty string if t is nil.
+func formatTimeWrapper(t *wrappers.TimeWrapper) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(t.Time)
+}
+
 // isAdditionalField checks if a key is considered an additional field.
 // Parameters:
 //   - key: The key to check.
@@ -240,52 +478,6 @@ func isAdditionalField(key string) bool {
 	return !standardFields[key]
 }
 
-// showAllFieldsJson outputs all fields of a KeePass entry in JSON format.
-// Parameters:
-//   - entry: The KeePass entry to display.
-func showAllFieldsJson(entry *gokeepasslib.Entry) {
-	type entryData struct {
-		Title            string            `json:"title"`
-		Username         string            `json:"username"`
-		URL              string            `json:"url"`
-		Notes            string            `json:"notes"`
-		AdditionalFields map[string]string `json:"additional_fields,omitempty"`
-		Metadata         struct {
-			Created  string `json:"created"`
-			Modified string `json:"modified"`
-			Accessed string `json:"accessed"`
-		} `json:"metadata"`
-	}
-
-	data := entryData{
-		Title:            getValue(entry, "Title"),
-		Username:         getValue(entry, "UserName"),
-		URL:              getValue(entry, "URL"),
-		Notes:            getValue(entry, "Notes"),
-		AdditionalFields: make(map[string]string),
-	}
-
-	// Fill additional fields
-	for _, v := range entry.Values {
-		if isAdditionalField(v.Key) && v.Value.Content != "" {
-			data.AdditionalFields[v.Key] = v.Value.Content
-		}
-	}
-
-	// Fill metadata
-	data.Metadata.Created = formatTime(entry.Times.CreationTime.Time)
-	data.Metadata.Modified = formatTime(entry.Times.LastModificationTime.Time)
-	data.Metadata.Accessed = formatTime(entry.Times.LastAccessTime.Time)
-
-	jsonData, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		fmt.Printf("Error creating JSON output: %v\n", err)
-		return
-	}
-
-	fmt.Println(string(jsonData))
-}
-
 // ResolveOutputType determines the output type based on the provided flag, environment variable, or configuration.
 //
 // Order of precedence:
@@ -154,8 +154,12 @@ func Test_getValue(t *testing.T) {
 }
 
 func Test_printNonEmptyValue(t *testing.T) {
-	printNonEmptyValue("Key", "Value") // Should print
-	printNonEmptyValue("Key", "")      // Should not print
+	var b strings.Builder
+	printNonEmptyValue(&b, "Key", "Value") // Should print
+	printNonEmptyValue(&b, "Key", "")      // Should not print
+	if b.String() != "Key: Value\n" {
+		t.Errorf("printNonEmptyValue wrote %q, want %q", b.String(), "Key: Value\n")
+	}
 }
 
 func Test_formatTime(t *testing.T) {
@@ -174,7 +178,7 @@ func Test_isAdditionalField(t *testing.T) {
 	}
 }
 
-func Test_showAllFieldsJson(t *testing.T) {
+func Test_writeAllFieldsJSON(t *testing.T) {
 	entry := &gokeepasslib.Entry{
 		Values: []gokeepasslib.ValueData{
 			{Key: "Title", Value: gokeepasslib.V{Content: "TestTitle"}},
@@ -186,5 +190,69 @@ func Test_showAllFieldsJson(t *testing.T) {
 			LastAccessTime:       &wrappers.TimeWrapper{Time: time.Now()},
 		},
 	}
-	showAllFieldsJson(entry)
+	var b strings.Builder
+	if err := writeAllFieldsJSON(&b, newEntryData(entry, false)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), `"Custom": "CustomValue"`) {
+		t.Errorf("expected custom field in JSON output, got %s", b.String())
+	}
+}
+
+func TestWriteAllFields_Formats(t *testing.T) {
+	entry := &gokeepasslib.Entry{
+		Values: []gokeepasslib.ValueData{
+			{Key: "Title", Value: gokeepasslib.V{Content: "TestTitle"}},
+			{Key: "Password", Value: gokeepasslib.V{Content: "TopSecret", Protected: wrappers.NewBoolWrapper(true)}},
+			{Key: "Token", Value: gokeepasslib.V{Content: "TokenValue", Protected: wrappers.NewBoolWrapper(true)}},
+			{Key: "Custom", Value: gokeepasslib.V{Content: "CustomValue"}},
+		},
+	}
+	tests := []struct {
+		format string
+		reveal bool
+		want   []string
+		hidden []string
+	}{
+		{FormatText, false, []string{"Title: TestTitle", "Password: ********", "Token: ********", "Custom: CustomValue"}, []string{"TopSecret", "TokenValue"}},
+		{FormatText, true, []string{"Password: TopSecret", "Token: TokenValue"}, nil},
+		{FormatJSON, false, []string{`"password": "********"`, `"Token": "********"`}, []string{"TopSecret", "TokenValue"}},
+		{FormatYAML, false, []string{"title: TestTitle", "password: '********'", "Custom: CustomValue"}, []string{"TopSecret", "TokenValue"}},
+		{FormatYAML, true, []string{"password: TopSecret", "Token: TokenValue"}, nil},
+	}
+	for _, tc := range tests {
+		var b strings.Builder
+		if err := WriteAllFields(&b, entry, config.Config{OutputFormat: tc.format}, tc.reveal); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.format, err)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s (reveal=%v): expected %q in output, got:\n%s", tc.format, tc.reveal, want, b.String())
+			}
+		}
+		for _, hidden := range tc.hidden {
+			if strings.Contains(b.String(), hidden) {
+				t.Errorf("%s (reveal=%v): protected value %q must not be in output", tc.format, tc.reveal, hidden)
+			}
+		}
+	}
+	if err := WriteAllFields(&strings.Builder{}, entry, config.Config{OutputFormat: "xml"}, false); err == nil {
+		t.Error("expected error for unknown output format")
+	}
+}
+
+func TestResolveOutputFormat(t *testing.T) {
+	cfg := &config.Config{OutputFormat: FormatYAML}
+	if got := ResolveOutputFormat(FormatJSON, cfg); got != FormatJSON {
+		t.Errorf("flag format not used: got %v", got)
+	}
+	if got := ResolveOutputFormat("", cfg); got != FormatYAML {
+		t.Errorf("config format not used: got %v", got)
+	}
+	if got := ResolveOutputFormat("", nil); got != FormatText {
+		t.Errorf("expected default format text, got %v", got)
+	}
+	if !IsValidFormat(FormatYAML) || IsValidFormat("xml") {
+		t.Error("IsValidFormat returned unexpected result")
+	}
 }
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// WriteGroupList writes the subgroups (with a trailing "/") and the entry titles of a group.
+//
+// Parameters:
+//   - w: The writer to write the list to.
+//   - group: The group to list.
+//
+// Returns:
+//   - error: Any error encountered while writing.
+func WriteGroupList(w io.Writer, group *gokeepasslib.Group) error {
+	for _, g := range group.Groups {
+		if _, err := fmt.Fprintf(w, "%s/\n", g.Name); err != nil {
+			return err
+		}
+	}
+	for i := range group.Entries {
+		if _, err := fmt.Fprintln(w, getValue(&group.Entries[i], "Title")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteGroupTree writes the group and all its subgroups and entries as tree.
+//
+// Parameters:
+//   - w: The writer to write the tree to.
+//   - group: The top group of the tree.
+//
+// Returns:
+//   - error: Any error encountered while writing.
+func WriteGroupTree(w io.Writer, group *gokeepasslib.Group) error {
+	if _, err := fmt.Fprintf(w, "%s/\n", group.Name); err != nil {
+		return err
+	}
+	return writeTreeChildren(w, group, "")
+}
+
+// writeTreeChildren writes the subgroups and entries of a group with tree branches.
+//
+// Parameters:
+//   - w: The writer to write the tree to.
+//   - group: The group whose children are written.
+//   - prefix: The indentation of the children, built from the parent branches.
+//
+// Returns:
+//   - error: Any error encountered while writing.
+func writeTreeChildren(w io.Writer, group *gokeepasslib.Group, prefix string) error {
+	count := len(group.Groups) + len(group.Entries)
+	n := 0
+	for i := range group.Groups {
+		n++
+		branch, childPrefix := "├── ", prefix+"│   "
+		if n == count {
+			branch, childPrefix = "└── ", prefix+"    "
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s/\n", prefix, branch, group.Groups[i].Name); err != nil {
+			return err
+		}
+		if err := writeTreeChildren(w, &group.Groups[i], childPrefix); err != nil {
+			return err
+		}
+	}
+	for i := range group.Entries {
+		n++
+		branch := "├── "
+		if n == count {
+			branch = "└── "
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s\n", prefix, branch, getValue(&group.Entries[i], "Title")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
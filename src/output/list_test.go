@@ -0,0 +1,51 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func makeTestGroup() *gokeepasslib.Group {
+	entry := func(title string) gokeepasslib.Entry {
+		return gokeepasslib.Entry{Values: []gokeepasslib.ValueData{{Key: "Title", Value: gokeepasslib.V{Content: title}}}}
+	}
+	return &gokeepasslib.Group{
+		Name: "Root",
+		Groups: []gokeepasslib.Group{
+			{Name: "Banking", Entries: []gokeepasslib.Entry{entry("Bank"), entry("Card")}},
+			{Name: "Mail", Groups: []gokeepasslib.Group{{Name: "Old"}}},
+		},
+		Entries: []gokeepasslib.Entry{entry("Router")},
+	}
+}
+
+func TestWriteGroupList(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteGroupList(&sb, makeTestGroup()); err != nil {
+		t.Fatal(err)
+	}
+	want := "Banking/\nMail/\nRouter\n"
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+func TestWriteGroupTree(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteGroupTree(&sb, makeTestGroup()); err != nil {
+		t.Fatal(err)
+	}
+	want := `Root/
+├── Banking/
+│   ├── Bank
+│   └── Card
+├── Mail/
+│   └── Old/
+└── Router
+`
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
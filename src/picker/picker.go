@@ -0,0 +1,223 @@
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ErrCancelled is returned if the selection is cancelled with Esc, Ctrl-C or Ctrl-D.
+var ErrCancelled = errors.New("selection cancelled")
+
+// maxVisible is the number of items shown at once, the list scrolls with the selection
+const maxVisible = 10
+
+// Key codes of the raw terminal input
+const (
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyBackspace = 0x08
+	keyCtrlN     = 0x0e
+	keyCtrlP     = 0x10
+	keyCtrlU     = 0x15
+	keyEscape    = 0x1b
+	keyDelete    = 0x7f
+)
+
+// state is the filter, the visible items and the selection of the picker.
+type state struct {
+	items    []string
+	filter   string
+	visible  []int
+	selected int
+	offset   int
+}
+
+// update recomputes the visible items after the filter changed and selects the first one.
+// An item is visible, if it contains all space separated words of the filter (case-insensitive).
+// If the filter is the number of an item, this item is shown first.
+func (s *state) update() {
+	s.visible = s.visible[:0]
+	number, err := strconv.Atoi(strings.TrimSpace(s.filter))
+	if err == nil && number >= 1 && number <= len(s.items) {
+		s.visible = append(s.visible, number-1)
+	} else {
+		number = 0
+	}
+	words := strings.Fields(strings.ToLower(s.filter))
+	for i, item := range s.items {
+		if i == number-1 {
+			continue
+		}
+		lower := strings.ToLower(item)
+		match := true
+		for _, w := range words {
+			if !strings.Contains(lower, w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			s.visible = append(s.visible, i)
+		}
+	}
+	s.selected, s.offset = 0, 0
+}
+
+// move moves the selection by delta within the visible items and scrolls the list.
+func (s *state) move(delta int) {
+	if len(s.visible) == 0 {
+		return
+	}
+	s.selected = (s.selected + delta + len(s.visible)) % len(s.visible)
+	if s.selected < s.offset {
+		s.offset = s.selected
+	} else if s.selected >= s.offset+maxVisible {
+		s.offset = s.selected - maxVisible + 1
+	}
+}
+
+// render draws the visible items and the filter prompt, the cursor stays behind the prompt.
+//
+// Returns:
+//   - string: The lines separated by "\r\n", which also works in a raw terminal.
+//   - int: The number of lines above the prompt.
+func (s *state) render() (string, int) {
+	var b strings.Builder
+	lines := 0
+	end := s.offset + maxVisible
+	if end > len(s.visible) {
+		end = len(s.visible)
+	}
+	for pos := s.offset; pos < end; pos++ {
+		marker := "  "
+		if pos == s.selected {
+			marker = "> "
+		}
+		i := s.visible[pos]
+		fmt.Fprintf(&b, "%s%*d) %s\r\n", marker, len(strconv.Itoa(len(s.items))), i+1, s.items[i])
+		lines++
+	}
+	fmt.Fprintf(&b, "%d/%d  (type to filter, up/down to move, Enter to choose, Esc to cancel)\r\n", len(s.visible), len(s.items))
+	lines++
+	fmt.Fprintf(&b, "Select entry: %s", s.filter)
+	return b.String(), lines
+}
+
+// Select shows a numbered, filterable list of the items and returns the chosen one.
+// Typed characters narrow the list, Backspace and Ctrl-U edit the filter, the arrow keys,
+// Ctrl-P and Ctrl-N move the selection and Enter chooses it. If the filter is the number of an
+// item, this item is selected first.
+//
+// Parameters:
+//   - r: The key input, a terminal in raw mode.
+//   - w: The output, the list is redrawn in place with ANSI escape sequences.
+//   - items: The items to choose from, e.g. the paths of the found entries.
+//
+// Returns:
+//   - int: The index of the chosen item.
+//   - error: ErrCancelled, or an error if reading the input fails.
+func Select(r io.Reader, w io.Writer, items []string) (int, error) {
+	in := bufio.NewReader(r)
+	s := &state{items: items}
+	s.update()
+	drawn := 0
+	erase := func() {
+		if drawn > 0 {
+			fmt.Fprintf(w, "\r\x1b[%dA", drawn)
+		} else {
+			fmt.Fprint(w, "\r")
+		}
+		fmt.Fprint(w, "\x1b[J")
+	}
+	defer erase()
+	for {
+		erase()
+		text, lines := s.render()
+		fmt.Fprint(w, text)
+		drawn = lines
+
+		c, _, err := in.ReadRune()
+		if err == io.EOF {
+			return 0, ErrCancelled
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case '\r', '\n':
+			if len(s.visible) > 0 {
+				return s.visible[s.selected], nil
+			}
+		case keyCtrlC, keyCtrlD:
+			return 0, ErrCancelled
+		case keyEscape:
+			if in.Buffered() == 0 {
+				// a single Esc, no escape sequence
+				return 0, ErrCancelled
+			}
+			seq, _ := in.ReadByte()
+			if seq != '[' && seq != 'O' {
+				return 0, ErrCancelled
+			}
+			switch key, _ := in.ReadByte(); key {
+			case 'A':
+				s.move(-1)
+			case 'B':
+				s.move(1)
+			}
+		case keyCtrlP:
+			s.move(-1)
+		case keyCtrlN, '\t':
+			s.move(1)
+		case keyBackspace, keyDelete:
+			if s.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(s.filter)
+				s.filter = s.filter[:len(s.filter)-size]
+				s.update()
+			}
+		case keyCtrlU:
+			s.filter = ""
+			s.update()
+		default:
+			if unicode.IsPrint(c) {
+				s.filter += string(c)
+				s.update()
+			}
+		}
+	}
+}
+
+// IsTerminal reports whether stdin and stderr are terminals, which the picker requires.
+//
+// Returns:
+//   - bool: True if an interactive selection is possible.
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// SelectTerminal shows the picker on stderr and reads the keys from stdin in raw mode.
+//
+// Parameters:
+//   - items: The items to choose from.
+//
+// Returns:
+//   - int: The index of the chosen item.
+//   - error: ErrCancelled, or an error if the terminal can not be used.
+func SelectTerminal(items []string) (int, error) {
+	fd := int(os.Stdin.Fd())
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set terminal to raw mode: %w", err)
+	}
+	defer term.Restore(fd, old)
+	return Select(os.Stdin, os.Stderr, items)
+}
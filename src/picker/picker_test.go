@@ -0,0 +1,60 @@
+package picker
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	items := []string{"/Root/Account", "/Root/Banking/Account", "/Root/Old/Account", "/Root/Old/Banking/Account"}
+	tests := []struct {
+		name  string
+		keys  string
+		want  int
+		isErr error
+	}{
+		{"enter", "\r", 0, nil},
+		{"down", "\x1b[B\x1b[B\r", 2, nil},
+		{"up wraps", "\x1b[A\r", 3, nil},
+		{"ctrl-n ctrl-p", "\x0e\x0e\x10\r", 1, nil},
+		{"filter", "old bank\r", 3, nil},
+		{"filter case-insensitive", "OLD\x0e\r", 3, nil},
+		{"backspace", "oldx\x7f\x7f\x7f\x7fbank\r", 1, nil},
+		{"ctrl-u", "zzz\x15\x1b[B\r", 1, nil},
+		{"number", "3\r", 2, nil},
+		{"no match", "zzz\r\x7f\x7f\x7f\r", 0, nil},
+		{"escape", "\x1b", 0, ErrCancelled},
+		{"ctrl-c", "ol\x03", 0, ErrCancelled},
+		{"eof", "old", 0, ErrCancelled},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got, err := Select(strings.NewReader(tt.keys), &out, items)
+		if !errors.Is(err, tt.isErr) || (err == nil && got != tt.want) {
+			t.Errorf("%s: got %d, %v, want %d, %v", tt.name, got, err, tt.want, tt.isErr)
+		}
+	}
+}
+
+func TestSelect_Render(t *testing.T) {
+	items := make([]string, 12)
+	for i := range items {
+		items[i] = "/Root/Entry " + string(rune('a'+i))
+	}
+	var out bytes.Buffer
+	// move to the last item, the list scrolls
+	keys := strings.Repeat("\x0e", 11) + "\r"
+	got, err := Select(strings.NewReader(keys), &out, items)
+	if err != nil || got != 11 {
+		t.Fatalf("got %d, %v", got, err)
+	}
+	screen := out.String()
+	if !strings.Contains(screen, "   1) /Root/Entry a\r\n") || !strings.Contains(screen, "> 12) /Root/Entry l\r\n") {
+		t.Errorf("unexpected output: %q", screen)
+	}
+	if !strings.Contains(screen, "12/12  (type to filter") || !strings.HasSuffix(screen, "\r\x1b[11A\x1b[J") {
+		t.Errorf("expected prompt and erased list, got %q", screen)
+	}
+}
@@ -0,0 +1,175 @@
+// Package render renders Go text/template templates, whose functions resolve secret references
+// to fields, TOTP tokens and attachments of the entries of the database.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/keepass"
+	"kpasscli/src/search"
+)
+
+// defaultField is the field of the kp function without field name.
+const defaultField = "Password"
+
+// Resolver resolves the references of the template functions.
+type Resolver struct {
+	// Find returns the entry for an item, it must fail if the item matches none or multiple entries
+	Find func(item string) (search.Result, error)
+	// DB is the database holding the attachments
+	DB *gokeepasslib.Database
+}
+
+// Funcs returns the template functions:
+//
+//   - kp item [field]: The field of the entry, default Password
+//   - totp item: The current TOTP token of the entry
+//   - attachment item name: The content of the attachment of the entry
+//
+// Returns:
+//   - template.FuncMap: The functions for template.Template.Funcs.
+func (r *Resolver) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"kp":         r.field,
+		"totp":       r.totp,
+		"attachment": r.attachment,
+	}
+}
+
+// field returns a field of the entry.
+//
+// Parameters:
+//   - item: The entry as for the get command.
+//   - field: The optional field name, default Password.
+//
+// Returns:
+//   - string: The value of the field.
+//   - error: An error if the entry or the field is not found or the item is ambiguous.
+func (r *Resolver) field(item string, field ...string) (string, error) {
+	if len(field) > 1 {
+		return "", fmt.Errorf("kp expects an item and at most one field, got %d fields", len(field))
+	}
+	name := defaultField
+	if len(field) == 1 {
+		name = field[0]
+	}
+	result, err := r.find(item)
+	if err != nil {
+		return "", err
+	}
+	return result.GetField(name)
+}
+
+// totp returns the current TOTP token of the entry.
+//
+// Parameters:
+//   - item: The entry as for the get command.
+//
+// Returns:
+//   - string: The TOTP token.
+//   - error: An error if the entry is not found, the item is ambiguous or the entry has no valid TOTP secret.
+func (r *Resolver) totp(item string) (string, error) {
+	result, err := r.find(item)
+	if err != nil {
+		return "", err
+	}
+	return result.GetTotpToken("otp")
+}
+
+// attachment returns the content of an attachment of the entry.
+//
+// Parameters:
+//   - item: The entry as for the get command.
+//   - name: The file name of the attachment.
+//
+// Returns:
+//   - string: The content of the attachment.
+//   - error: An error if the entry or the attachment is not found or the item is ambiguous.
+func (r *Resolver) attachment(item, name string) (string, error) {
+	result, err := r.find(item)
+	if err != nil {
+		return "", err
+	}
+	data, err := keepass.GetAttachment(r.DB, result.Entry, name)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// find returns the entry for the item, with the item in the error message.
+//
+// Parameters:
+//   - item: The entry as for the get command.
+//
+// Returns:
+//   - search.Result: The found entry.
+//   - error: An error if the item matches none or multiple entries.
+func (r *Resolver) find(item string) (search.Result, error) {
+	result, err := r.Find(item)
+	if err != nil {
+		return search.Result{}, fmt.Errorf("%s: %w", item, err)
+	}
+	return result, nil
+}
+
+// Render executes the template. The output is only returned, if all references are resolved.
+//
+// Parameters:
+//   - name: The name of the template used in error messages, e.g. the file name.
+//   - text: The template text.
+//   - r: The resolver of the template functions.
+//
+// Returns:
+//   - []byte: The rendered output.
+//   - error: An error if the template is invalid or any reference can not be resolved.
+func Render(name, text string, r *Resolver) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(r.Funcs()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile writes the data to a temporary file with permissions 0600 in the directory of path,
+// which then replaces path. An existing file gets the permissions 0600 as well.
+//
+// Parameters:
+//   - path: The path of the output file.
+//   - data: The data to write.
+//
+// Returns:
+//   - error: Any error encountered while writing or renaming.
+func WriteFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
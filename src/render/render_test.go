@@ -0,0 +1,102 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/search"
+)
+
+// testResolver returns a resolver for the entries "DB" with an attachment "key.pem" and "Dup",
+// which is ambiguous.
+func testResolver() *Resolver {
+	db := gokeepasslib.NewDatabase()
+	binary := db.AddBinary([]byte("-----BEGIN KEY-----\n"))
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: "UserName", Value: gokeepasslib.V{Content: "dbuser"}},
+		gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: "dbpass"}},
+	)
+	entry.Binaries = append(entry.Binaries, binary.CreateReference("key.pem"))
+	return &Resolver{
+		DB: db,
+		Find: func(item string) (search.Result, error) {
+			switch item {
+			case "DB":
+				return search.Result{Path: "/Root/DB", Entry: &entry}, nil
+			case "Dup":
+				return search.Result{}, fmt.Errorf("multiple items found")
+			}
+			return search.Result{}, fmt.Errorf("no items found")
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	text := `user={{ kp "DB" "UserName" }} pass={{ kp "DB" }}
+{{ attachment "DB" "key.pem" }}`
+	got, err := Render("test.tmpl", text, testResolver())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "user=dbuser pass=dbpass\n-----BEGIN KEY-----\n"
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{`{{ kp "Missing" }}`, "Missing: no items found"},
+		{`{{ kp "Dup" "UserName" }}`, "Dup: multiple items found"},
+		{`{{ kp "DB" "URL" }}`, "field 'URL' not found"},
+		{`{{ kp "DB" "UserName" "Password" }}`, "at most one field"},
+		{`{{ attachment "DB" "cert.pem" }}`, "attachment 'cert.pem' not found"},
+		{`{{ totp "DB" }}`, "TOTP secret field 'otp' not found"},
+		{`{{ .Missing }}`, "Missing"},
+		{`{{ kp "DB" `, "test.tmpl"},
+	}
+	for _, tt := range tests {
+		got, err := Render("test.tmpl", "before "+tt.text, testResolver())
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.text, tt.wantErr, err)
+		}
+		if got != nil {
+			t.Errorf("%s: expected no output on error, got %q", tt.text, got)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("expected new content, got %q (err: %v)", data, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("expected permissions 0600, got %v", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files, got %v", entries)
+	}
+}
@@ -2,14 +2,10 @@ package search
 
 import (
 	"fmt" // Hinzugefügt für Debug-Logs
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
-	"unicode"
 
-	"github.com/pquerna/otp/totp"
 	"github.com/tobischo/gokeepasslib/v3"
 
 	"kpasscli/src/debug"
@@ -26,9 +22,13 @@ type FinderInterface interface {
 
 // Result represents the outcome of a search operation.
 // It contains the path to the found entry and a pointer to the entry itself.
+// UUID is the UUID of the entry, which does not change when the entry is moved or renamed.
+// Score is the fuzzy score of the match, set by the Fuzzy and First search options.
 type Result struct {
 	Path  string
 	Entry *gokeepasslib.Entry
+	UUID  gokeepasslib.UUID
+	Score int
 }
 
 // GetField returns the value of the specified field from the entry
@@ -49,52 +49,6 @@ func (r *Result) GetField(fieldName string) (string, error) {
 	return "", fmt.Errorf("field '%s' not found", fieldName)
 }
 
-func (r *Result) GetTotpToken(fieldName string) (string, error) {
-
-	rawSecret, err := r.GetField(fieldName)
-	if err != nil {
-		return "", fmt.Errorf("TOTP secret field '%s' not found: %w", fieldName, err)
-	}
-
-	// Trim whitespace
-	rawSecret = strings.TrimSpace(rawSecret)
-
-	// If it starts with otpauth://, parse the secret parameter
-	if strings.HasPrefix(rawSecret, "otpauth://") {
-		// Parse query parameters to extract secret
-		u, err := url.Parse(rawSecret)
-		if err != nil {
-			return "invalid otpauth URI", err
-		}
-
-		q := u.Query()
-		secret, ok := q["secret"]
-		if !ok || len(secret) == 0 {
-			return "", fmt.Errorf("no 'secret' parameter found in otpauth URI")
-		}
-
-		rawSecret = secret[0] // Take the first value
-	}
-
-	// Now clean the actual Base32 secret only
-	cleanedSecret := strings.Map(func(r rune) rune {
-		if unicode.IsSpace(r) || r == '\n' || r == '\r' || r == '\t' {
-			return -1 // remove
-		}
-		return unicode.ToUpper(r)
-	}, rawSecret)
-
-	if cleanedSecret == "" {
-		return "", fmt.Errorf("TOTP secret is empty after cleaning")
-	}
-
-	token, err := totp.GenerateCode(cleanedSecret, time.Now())
-	if err != nil {
-		return "", fmt.Errorf("Error generating TOTP token: %w (secret: '%s')", err, cleanedSecret)
-	}
-	return token, nil
-}
-
 var verify bool
 
 // EnableVerify enables verification logging for search operations.
@@ -109,6 +63,18 @@ func EnableVerify() {
 type SearchOptions struct {
 	CaseSensitive bool
 	ExactMatch    bool
+	// Regex matches titles and group names against regular expressions
+	Regex bool
+	// Glob matches titles and group names against shell globs
+	Glob bool
+	// Where are conditions "Field=value" or "Field~value", which all found entries must satisfy
+	Where []string
+	// Fuzzy matches the characters of the query in order (like fzf) and ranks the results
+	Fuzzy bool
+	// First ranks the results and returns only the best one, if its score clearly dominates
+	First bool
+	// Raw keeps the KeePass placeholders like {USERNAME} or {REF:P@I:...} in the field values
+	Raw bool
 }
 
 // Finder handles searching through the KeePass database
@@ -143,9 +109,17 @@ func DefaultSearchOptions() SearchOptions {
 }
 
 // Find searches for entries in the KeePass database based on the provided query string.
+// With the Regex or Glob option the title and the segments of a path query are patterns,
+// a path segment "**" matches any number of groups.
+// The placeholders of the field values are resolved unless the Raw option is set.
+// The results are filtered by the Where conditions, with conditions an empty query matches all entries.
+// An entry, whose placeholders can not be resolved, does not match the conditions, so only the
+// placeholders of the returned entries can fail the search.
+// With Fuzzy or First the results are ranked by score, path depth and last access time,
+// with First only the best result is returned, if its score leads the second best clearly.
 //
 // Parameters:
-//   - query: Search query, can be absolute path, relative path, or entry name.
+//   - query: Search query, can be absolute path, relative path, entry name or "uuid:" and the UUID of an entry.
 //
 // Returns:
 //   - []Result: Array of matching entries with their paths.
@@ -154,14 +128,42 @@ func (f *Finder) Find(query string) ([]Result, error) {
 	debug.Log("Starting search for query: %s", query) // Debug-Log hinzugefügt
 	var results []Result
 
-	if strings.HasPrefix(query, "/") {
+	if query == "" && len(f.Options.Where) > 0 {
+		// Field search only, all entries are candidates
+		var err error
+		results, err = f.allEntries()
+		if err != nil {
+			return nil, fmt.Errorf("field search failed: %w", err)
+		}
+	} else if strings.HasPrefix(query, UUIDPrefix) {
+		// UUID search, independent of the path
+		var err error
+		results, err = f.findByUUID(query)
+		if err != nil {
+			return nil, fmt.Errorf("UUID search failed: %w", err)
+		}
+	} else if f.Options.Fuzzy {
+		// Fuzzy search, scored subsequence match
+		var err error
+		results, err = f.findFuzzy(query)
+		if err != nil {
+			return nil, fmt.Errorf("fuzzy search failed: %w", err)
+		}
+	} else if (f.Options.Regex || f.Options.Glob) && strings.Contains(query, "/") {
+		// Pattern search, the segments of the path are patterns
+		var err error
+		results, err = f.findByPattern(query)
+		if err != nil {
+			return nil, fmt.Errorf("pattern search failed: %w", err)
+		}
+	} else if strings.HasPrefix(query, "/") {
 		// Absolute path search
 		entry, err := f.findByAbsolutePath(query)
 		if err != nil {
 			return nil, fmt.Errorf("absolute path search failed: %w", err)
 		}
 		if entry != nil {
-			results = append(results, Result{Path: query, Entry: entry})
+			results = append(results, NewResult(query, entry))
 		}
 	} else if strings.Contains(query, "/") {
 		// Subpath search
@@ -178,6 +180,30 @@ func (f *Finder) Find(query string) ([]Result, error) {
 			return nil, fmt.Errorf("name search failed: %w", err)
 		}
 	}
+	if len(f.Options.Where) > 0 {
+		// resolves the placeholders of the candidates
+		var err error
+		results, err = f.filterWhere(results)
+		if err != nil {
+			return nil, fmt.Errorf("field search failed: %w", err)
+		}
+	}
+	if f.Options.Fuzzy || f.Options.First {
+		if !f.Options.Fuzzy {
+			f.scoreResults(query, results)
+		}
+		rankResults(results)
+		if f.Options.First {
+			results = pickFirst(results)
+		}
+	}
+	if len(f.Options.Where) == 0 {
+		for i := range results {
+			if err := f.resolve(&results[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
 	// Wenn genau ein Eintrag gefunden wurde, gib den vollständigen Pfad aus
 	if verify && len(results) == 1 {
 		fmt.Fprintf(os.Stderr, "Found one entry: %s\n", results[0].Path)
@@ -330,10 +356,7 @@ func (f *Finder) searchGroupForSubpath(
 			// debug.Log("opts: %+v", opts)
 			if matchesName(title, targetName, opts) {
 				fullPath := filepath.Join(groupPath, title)
-				*results = append(*results, Result{
-					Path:  "/" + fullPath, // Ensure path starts with /
-					Entry: &entry,
-				})
+				*results = append(*results, NewResult("/"+fullPath, &entry)) // Ensure path starts with /
 				debug.Log("Found matching entry: %s", fullPath)
 			} else {
 				debug.Log("Entry %s does not match target %s", title, targetName)
@@ -434,11 +457,13 @@ func matchesName(value, pattern string, opts SearchOptions) bool {
 func (f *Finder) findByName(query string) ([]Result, error) {
 	debug.Log("Searching by name: %s", query) // Debug-Log hinzugefügt
 	var results []Result
-	opts := f.Options
-	// opts.ExactMatch = false // Exact match for name search
+	target, err := newNameMatcher(query, f.Options)
+	if err != nil {
+		return nil, err
+	}
 
 	// Start recursive search from root group
-	err := f.searchGroupForName(&f.db.Content.Root.Groups[0], "", query, &results, opts)
+	err = f.searchGroupForName(&f.db.Content.Root.Groups[0], "", target, &results)
 	if err != nil {
 		return nil, fmt.Errorf("name search failed: %w", err)
 	}
@@ -452,20 +477,18 @@ func (f *Finder) findByName(query string) ([]Result, error) {
 // Parameters:
 //   - group - The group to search within.
 //   - currentPath - The current path of the group being searched.
-//   - targetName - The name to search for within the group's entries.
+//   - target - The matcher of the name to search for within the group's entries.
 //   - results - A pointer to a slice where the search results will be appended.
-//   - opts - Options for customizing the search behavior.
 //
 // Returns:
 //   - An error if the search encounters an issue, otherwise nil.
 func (f *Finder) searchGroupForName(
 	group *gokeepasslib.Group,
 	currentPath string,
-	targetName string,
+	target *nameMatcher,
 	results *[]Result,
-	opts SearchOptions,
 ) error {
-	debug.Log("Searching group: %s, CurrentPath: %s, TargetName: %s", group.Name, currentPath, targetName) // Debug-Log hinzugefügt
+	debug.Log("Searching group: %s, CurrentPath: %s, TargetName: %s", group.Name, currentPath, target.pattern) // Debug-Log hinzugefügt
 	// Build the full path for the current group
 	groupPath := currentPath
 	if group.Name != "" {
@@ -485,18 +508,15 @@ func (f *Finder) searchGroupForName(
 				break
 			}
 		}
-		if matchesName(title, targetName, opts) {
+		if target.match(title) {
 			fullPath := filepath.Join(groupPath, title)
-			*results = append(*results, Result{
-				Path:  "/" + fullPath, // Ensure path starts with /
-				Entry: &entry,
-			})
+			*results = append(*results, NewResult("/"+fullPath, &entry)) // Ensure path starts with /
 		}
 	}
 
 	// Recursively search subgroups
 	for i := range group.Groups {
-		err := f.searchGroupForName(&group.Groups[i], groupPath, targetName, results, opts)
+		err := f.searchGroupForName(&group.Groups[i], groupPath, target, results)
 		if err != nil {
 			return err
 		}
@@ -504,3 +524,54 @@ func (f *Finder) searchGroupForName(
 
 	return nil
 }
+
+// FindGroup returns the group at the given path and its full path.
+// The path is either absolute, starting with the name of the root group (e.g. "/Root/Banking"),
+// or relative to the root group (e.g. "Banking"). An empty path or "/" returns the root group.
+//
+// Parameters:
+//   - db: The KeePass database to search.
+//   - path: The path of the group.
+//
+// Returns:
+//   - *gokeepasslib.Group: The found group.
+//   - string: The full path of the group, starting with "/".
+//   - error: An error if the group does not exist.
+func FindGroup(db *gokeepasslib.Database, path string) (*gokeepasslib.Group, string, error) {
+	debug.Log("Searching group: %s", path)
+	if db == nil || db.Content == nil || db.Content.Root == nil || len(db.Content.Root.Groups) == 0 {
+		return nil, "", fmt.Errorf("database has no root group")
+	}
+	group := &db.Content.Root.Groups[0]
+	groupPath := "/" + group.Name
+
+	var parts []string
+	if strings.HasPrefix(path, "/") {
+		parts = strings.Split(strings.Trim(path, "/"), "/")
+		if parts[0] != "" && parts[0] != group.Name {
+			return nil, "", fmt.Errorf("group not found: %s", parts[0])
+		}
+		parts = parts[1:]
+	} else if path != "" {
+		parts = strings.Split(strings.Trim(path, "/"), "/")
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		found := false
+		for i := range group.Groups {
+			if group.Groups[i].Name == part {
+				group = &group.Groups[i]
+				groupPath = groupPath + "/" + part
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, "", fmt.Errorf("group not found: %s", part)
+		}
+	}
+	return group, groupPath, nil
+}
@@ -44,6 +44,22 @@ func TestFinder_Find(t *testing.T) {
 	}
 }
 
+// makeTestEntry returns a new entry with a random UUID and the fields given as name, value pairs.
+func makeTestEntry(values ...string) gokeepasslib.Entry {
+	e := gokeepasslib.NewEntry()
+	for i := 0; i < len(values); i += 2 {
+		e.Values = append(e.Values, gokeepasslib.ValueData{Key: values[i], Value: gokeepasslib.V{Content: values[i+1]}})
+	}
+	return e
+}
+
+// newTestDB returns a database with the root group, without meta data.
+func newTestDB(root gokeepasslib.Group) *gokeepasslib.Database {
+	return &gokeepasslib.Database{Content: &gokeepasslib.DBContent{
+		Root: &gokeepasslib.RootData{Groups: []gokeepasslib.Group{root}},
+	}}
+}
+
 func makeTestDB() *gokeepasslib.Database {
 	db := &gokeepasslib.Database{}
 	db.Content = &gokeepasslib.DBContent{}
@@ -209,3 +225,34 @@ func TestFinderInterface_Find(t *testing.T) {
 		t.Errorf("expected no results, got %+v", results)
 	}
 }
+
+func TestFindGroup(t *testing.T) {
+	db := makeTestDB()
+	tests := []struct {
+		path     string
+		wantName string
+		wantPath string
+	}{
+		{"", "Root", "/Root"},
+		{"/", "Root", "/Root"},
+		{"/Root", "Root", "/Root"},
+		{"/Root/Banking", "Banking", "/Root/Banking"},
+		{"Banking", "Banking", "/Root/Banking"},
+		{"Banking/", "Banking", "/Root/Banking"},
+	}
+	for _, tc := range tests {
+		group, path, err := FindGroup(db, tc.path)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.path, err)
+			continue
+		}
+		if group.Name != tc.wantName || path != tc.wantPath {
+			t.Errorf("%q: got %s (%s), want %s (%s)", tc.path, group.Name, path, tc.wantName, tc.wantPath)
+		}
+	}
+	for _, p := range []string{"/Other/Banking", "Missing", "/Root/Banking/Missing"} {
+		if _, _, err := FindGroup(db, p); err == nil {
+			t.Errorf("%q: expected error", p)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"kpasscli/src/debug"
+)
+
+// Scores of the fuzzy matcher, similar to fzf: every matched character scores, characters at the
+// start of a word and directly following the previous match earn a bonus, gaps cost.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+	bonusBoundary     = 8
+	bonusCamelCase    = 7
+	bonusConsecutive  = 4
+	// bonusExact is earned if the pattern matches the whole text
+	bonusExact = 2 * scoreMatch
+	// minScoreLead is the lead of the best over the second best result, which First requires
+	minScoreLead = scoreMatch
+)
+
+// fuzzyScore matches the pattern as subsequence of the text. Like fzf, the first occurrence
+// of the subsequence is shortened from its end to the latest possible start, then scored.
+//
+// Parameters:
+//   - text: The title or path.
+//   - pattern: The query.
+//   - caseSensitive: True to compare the characters case-sensitive.
+//
+// Returns:
+//   - int: The score, higher is better.
+//   - bool: True if the pattern is a subsequence of the text.
+func fuzzyScore(text, pattern string, caseSensitive bool) (int, bool) {
+	t := []rune(text)
+	p := []rune(pattern)
+	if len(p) == 0 {
+		return 0, false
+	}
+	fold := func(r rune) rune {
+		if caseSensitive {
+			return r
+		}
+		return unicode.ToLower(r)
+	}
+	// forward scan for the end of the first occurrence
+	end, j := -1, 0
+	for i := 0; i < len(t) && end < 0; i++ {
+		if fold(t[i]) == fold(p[j]) {
+			j++
+			if j == len(p) {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return 0, false
+	}
+	// backward scan for the latest start
+	start, j := end, len(p)-1
+	for i := end; i >= 0; i-- {
+		if fold(t[i]) == fold(p[j]) {
+			start = i
+			j--
+			if j < 0 {
+				break
+			}
+		}
+	}
+
+	score, j, prev := 0, 0, -1
+	for i := start; i <= end && j < len(p); i++ {
+		if fold(t[i]) != fold(p[j]) {
+			continue
+		}
+		score += scoreMatch
+		switch {
+		case i == 0 || strings.ContainsRune(" -_/.:@", t[i-1]):
+			score += bonusBoundary
+		case unicode.IsUpper(t[i]) && unicode.IsLower(t[i-1]):
+			score += bonusCamelCase
+		}
+		if prev >= 0 {
+			if gap := i - prev - 1; gap == 0 {
+				score += bonusConsecutive
+			} else {
+				score += scoreGapStart + (gap-1)*scoreGapExtension
+			}
+		}
+		prev = i
+		j++
+	}
+	if len(t) == len(p) {
+		score += bonusExact
+	}
+	return score, true
+}
+
+// fuzzyTarget returns the text a query is matched against: the path for queries with "/",
+// otherwise the title.
+//
+// Parameters:
+//   - result: The entry.
+//   - query: The query.
+//
+// Returns:
+//   - string: The path without leading "/" or the title.
+func fuzzyTarget(result *Result, query string) string {
+	if strings.Contains(query, "/") {
+		if strings.HasPrefix(query, "/") {
+			return result.Path
+		}
+		return strings.TrimPrefix(result.Path, "/")
+	}
+	return result.Entry.GetTitle()
+}
+
+// findFuzzy searches the entries, whose title (or path, if the query contains "/") contains the
+// characters of the query in order, and sets their scores.
+//
+// Parameters:
+//   - query: The query, e.g. "prdb" for "Prod DB".
+//
+// Returns:
+//   - []Result: The matching entries with their scores.
+//   - error: An error if the search fails.
+func (f *Finder) findFuzzy(query string) ([]Result, error) {
+	debug.Log("Searching fuzzy: %s", query)
+	if f.Options.Regex || f.Options.Glob {
+		return nil, fmt.Errorf("fuzzy search can not be combined with regex or glob search")
+	}
+	all, err := f.allEntries()
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, result := range all {
+		if score, ok := fuzzyScore(fuzzyTarget(&result, query), query, f.Options.CaseSensitive); ok {
+			result.Score = score
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// scoreResults sets the fuzzy scores of results found by another search mode, a result, which is
+// no fuzzy match (e.g. of a regular expression), scores 0.
+//
+// Parameters:
+//   - query: The query.
+//   - results: The results, changed in place.
+func (f *Finder) scoreResults(query string, results []Result) {
+	for i := range results {
+		results[i].Score, _ = fuzzyScore(fuzzyTarget(&results[i], query), query, f.Options.CaseSensitive)
+	}
+}
+
+// lastAccess returns the last access time of the entry, the zero time if it is not set.
+func lastAccess(result *Result) time.Time {
+	if result.Entry.Times.LastAccessTime == nil {
+		return time.Time{}
+	}
+	return result.Entry.Times.LastAccessTime.Time
+}
+
+// rankResults sorts the results by score, then by path depth (shallow first), then by last access
+// time (recent first).
+//
+// Parameters:
+//   - results: The results, sorted in place.
+func rankResults(results []Result) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if da, db := strings.Count(a.Path, "/"), strings.Count(b.Path, "/"); da != db {
+			return da < db
+		}
+		return lastAccess(a).After(lastAccess(b))
+	})
+}
+
+// pickFirst returns only the best of the ranked results, if its score leads the second best by at
+// least minScoreLead, otherwise all results.
+//
+// Parameters:
+//   - results: The ranked results.
+//
+// Returns:
+//   - []Result: The best result or all results.
+func pickFirst(results []Result) []Result {
+	if len(results) < 2 {
+		return results
+	}
+	if results[0].Score-results[1].Score < minScoreLead {
+		debug.Log("Best match %s (score %d) does not dominate %s (score %d)",
+			results[0].Path, results[0].Score, results[1].Path, results[1].Score)
+		return results
+	}
+	return results[:1]
+}
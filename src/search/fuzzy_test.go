@@ -0,0 +1,101 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := fuzzyScore("Prod DB", "pdx", false); ok {
+		t.Error("expected no match for pdx")
+	}
+	if _, ok := fuzzyScore("Prod DB", "pdb", true); ok {
+		t.Error("expected no case-sensitive match for pdb")
+	}
+	if _, ok := fuzzyScore("Prod DB", "", false); ok {
+		t.Error("expected no match for an empty pattern")
+	}
+	better := [][2]string{
+		{"Account", "Account old"},          // exact match
+		{"Prod DB", "ProductionDatabase"},   // word boundaries and fewer gaps
+		{"db-prod", "d-x-b"},                // consecutive characters
+		{"MyBank", "Mybank"},                // camel case boundary
+		{"Prod DB", "Xprod db"},             // match at the start
+		{"Deploy Key", "Developer Keyring"}, // shorter gap
+	}
+	patterns := []string{"account", "pdb", "db", "mb", "prod", "dk"}
+	for i, pair := range better {
+		a, okA := fuzzyScore(pair[0], patterns[i], false)
+		b, okB := fuzzyScore(pair[1], patterns[i], false)
+		if !okA || !okB || a <= b {
+			t.Errorf("%q: expected %q (%d, %v) to score higher than %q (%d, %v)", patterns[i], pair[0], a, okA, pair[1], b, okB)
+		}
+	}
+}
+
+// makeFuzzyTestDB returns a database with the entries /Root/Prod DB, /Root/Account old, /Root/Account,
+// /Root/Archive/Prod DB, /Root/Archive/Prod Dump, accessed at the given time, and /Root/Archive/Product Docs.
+func makeFuzzyTestDB(accessed time.Time) *gokeepasslib.Database {
+	archived := []gokeepasslib.Entry{makeTestEntry("Title", "Prod DB"), makeTestEntry("Title", "Prod Dump"), makeTestEntry("Title", "Product Docs")}
+	entries := []gokeepasslib.Entry{makeTestEntry("Title", "Prod DB"), makeTestEntry("Title", "Account old"), makeTestEntry("Title", "Account")}
+	// only Prod Dump has been accessed, the others rank by their order
+	for _, e := range append(archived, entries...) {
+		e.Times.LastAccessTime.Time = time.Time{}
+	}
+	archived[1].Times.LastAccessTime = &w.TimeWrapper{Time: accessed}
+	archive := gokeepasslib.Group{Name: "Archive", Entries: archived}
+	return newTestDB(gokeepasslib.Group{Name: "Root", Groups: []gokeepasslib.Group{archive}, Entries: entries})
+}
+
+func TestFind_Fuzzy(t *testing.T) {
+	f := NewFinder(makeFuzzyTestDB(time.Now()))
+	f.Options.Fuzzy = true
+	tests := []struct{ query, want string }{
+		{"prdb", "/Root/Prod DB,/Root/Archive/Prod DB"},
+		{"prd d", "/Root/Prod DB,/Root/Archive/Prod Dump,/Root/Archive/Prod DB,/Root/Archive/Product Docs"},
+		{"arch/prdb", "/Root/Archive/Prod DB"},
+		{"/Root/acnt", "/Root/Account old,/Root/Account"},
+		{"zzz", ""},
+	}
+	for _, tt := range tests {
+		results, err := f.Find(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got := paths(results); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+	f.Options.Glob = true
+	if _, err := f.Find("prdb"); err == nil {
+		t.Error("expected error for fuzzy and glob search")
+	}
+}
+
+func TestFind_First(t *testing.T) {
+	f := NewFinder(makeFuzzyTestDB(time.Now()))
+	f.Options.First = true
+	tests := []struct {
+		query string
+		fuzzy bool
+		want  string
+	}{
+		{"Account", false, "/Root/Account"},
+		{"Prod", false, "/Root/Prod DB,/Root/Archive/Prod Dump,/Root/Archive/Prod DB,/Root/Archive/Product Docs"},
+		{"prdb", true, "/Root/Prod DB,/Root/Archive/Prod DB"},
+		{"account", true, "/Root/Account"},
+	}
+	for _, tt := range tests {
+		f.Options.Fuzzy = tt.fuzzy
+		results, err := f.Find(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got := paths(results); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package search
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/debug"
+)
+
+// anyDepth is the path segment, which matches any number of groups in regex and glob queries
+const anyDepth = "**"
+
+// nameMatcher matches titles and group names against a pattern. The pattern is compiled once
+// and reused for all names of the search.
+type nameMatcher struct {
+	pattern string
+	re      *regexp.Regexp
+	glob    bool
+	opts    SearchOptions
+}
+
+// newNameMatcher compiles the pattern according to the search options: a regular expression (Regex),
+// which must match the whole name only with ExactMatch, a shell glob (Glob), which always matches
+// the whole name, or a plain string (see matchesName).
+//
+// Parameters:
+//   - pattern: The pattern of a title or group name.
+//   - opts: The search options.
+//
+// Returns:
+//   - *nameMatcher: The matcher.
+//   - error: An error if the pattern is invalid or Regex and Glob are both enabled.
+func newNameMatcher(pattern string, opts SearchOptions) (*nameMatcher, error) {
+	m := &nameMatcher{pattern: pattern, opts: opts}
+	switch {
+	case opts.Regex && opts.Glob:
+		return nil, fmt.Errorf("regex and glob search can not be combined")
+	case opts.Glob:
+		if !opts.CaseSensitive {
+			m.pattern = strings.ToLower(pattern)
+		}
+		if _, err := path.Match(m.pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+		m.glob = true
+		return m, nil
+	case !opts.Regex:
+		return m, nil
+	}
+	expr := pattern
+	if opts.ExactMatch {
+		expr = "^(?:" + expr + ")$"
+	}
+	if !opts.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+	}
+	m.re = re
+	return m, nil
+}
+
+// match reports whether the name matches the pattern.
+//
+// Parameters:
+//   - name: The title or group name.
+//
+// Returns:
+//   - bool: True if the name matches.
+func (m *nameMatcher) match(name string) bool {
+	switch {
+	case m.re != nil:
+		return m.re.MatchString(name)
+	case m.glob:
+		if !m.opts.CaseSensitive {
+			name = strings.ToLower(name)
+		}
+		ok, _ := path.Match(m.pattern, name)
+		return ok
+	}
+	return matchesName(name, m.pattern, m.opts)
+}
+
+// compilePathPattern splits a regex or glob query at "/" and compiles its segments. The segment "**"
+// is returned as nil, it matches any number of groups. A relative query may start at any depth,
+// an absolute query starts with the root group.
+//
+// Parameters:
+//   - query: The query, e.g. "/Root/Prod/**/db-*" or "Prod/db-*".
+//   - opts: The search options.
+//
+// Returns:
+//   - []*nameMatcher: The matchers of the groups and, as last element, of the title.
+//   - error: An error if a segment is an invalid pattern.
+func compilePathPattern(query string, opts SearchOptions) ([]*nameMatcher, error) {
+	parts := strings.Split(strings.Trim(query, "/"), "/")
+	segments := make([]*nameMatcher, 0, len(parts)+1)
+	if !strings.HasPrefix(query, "/") {
+		segments = append(segments, nil)
+	}
+	for _, part := range parts {
+		if part == anyDepth {
+			segments = append(segments, nil)
+			continue
+		}
+		m, err := newNameMatcher(part, opts)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, m)
+	}
+	return segments, nil
+}
+
+// closure adds the states reachable by skipping "**" segments, which also match no group.
+//
+// Parameters:
+//   - segments: The compiled path pattern.
+//   - states: The indexes of the next segment to match, changed in place.
+func closure(segments []*nameMatcher, states []bool) {
+	for i := range segments {
+		if states[i] && segments[i] == nil && i+1 < len(segments) {
+			states[i+1] = true
+		}
+	}
+}
+
+// findByPattern searches the entries, whose path matches a regex or glob query segment by segment.
+//
+// Parameters:
+//   - query: The query, see compilePathPattern.
+//
+// Returns:
+//   - []Result: The matching entries.
+//   - error: An error if the query is invalid.
+func (f *Finder) findByPattern(query string) ([]Result, error) {
+	debug.Log("Searching by pattern: %s", query)
+	segments, err := compilePathPattern(query, f.Options)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]bool, len(segments))
+	states[0] = true
+	var results []Result
+	f.searchGroupForPattern(&f.db.Content.Root.Groups[0], "", segments, states, &results)
+	return results, nil
+}
+
+// searchGroupForPattern matches the group against the states of the path pattern, then its entries
+// and recursively its subgroups.
+//
+// Parameters:
+//   - group: The group to search.
+//   - currentPath: The path of the parent group, empty for the root group.
+//   - segments: The compiled path pattern.
+//   - states: The indexes of the segments, which the group may match.
+//   - results: The slice collecting the matching entries.
+func (f *Finder) searchGroupForPattern(
+	group *gokeepasslib.Group,
+	currentPath string,
+	segments []*nameMatcher,
+	states []bool,
+	results *[]Result,
+) {
+	closure(segments, states)
+	next := make([]bool, len(segments))
+	matched := false
+	last := len(segments) - 1
+	for i, active := range states {
+		if !active {
+			continue
+		}
+		if segments[i] == nil {
+			// ** consumes the group and may consume more
+			next[i] = true
+			matched = true
+		} else if i < last && segments[i].match(group.Name) {
+			next[i+1] = true
+			matched = true
+		}
+	}
+	if !matched {
+		return
+	}
+	groupPath := currentPath + "/" + group.Name
+	closure(segments, next)
+	if next[last] {
+		for i := range group.Entries {
+			entry := group.Entries[i]
+			title := entry.GetTitle()
+			if segments[last] == nil || segments[last].match(title) {
+				*results = append(*results, NewResult(groupPath+"/"+title, &entry))
+			}
+		}
+	}
+	for i := range group.Groups {
+		f.searchGroupForPattern(&group.Groups[i], groupPath, segments, append([]bool(nil), next...), results)
+	}
+}
+
+// allEntries returns all entries of the database.
+//
+// Returns:
+//   - []Result: The entries with their paths.
+//   - error: An error if the search fails.
+func (f *Finder) allEntries() ([]Result, error) {
+	return f.findByPattern("/" + anyDepth)
+}
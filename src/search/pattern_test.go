@@ -0,0 +1,93 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// makePatternTestDB returns a database with the entries
+// /Root/Prod/db-1, /Root/Prod/EU/db-2, /Root/Prod/EU/Web/web-1 and /Root/Test/db-3.
+func makePatternTestDB() *gokeepasslib.Database {
+	web := gokeepasslib.Group{Name: "Web", Entries: []gokeepasslib.Entry{makeTestEntry("Title", "web-1")}}
+	eu := gokeepasslib.Group{Name: "EU", Entries: []gokeepasslib.Entry{makeTestEntry("Title", "db-2")}, Groups: []gokeepasslib.Group{web}}
+	prod := gokeepasslib.Group{Name: "Prod", Entries: []gokeepasslib.Entry{makeTestEntry("Title", "db-1")}, Groups: []gokeepasslib.Group{eu}}
+	test := gokeepasslib.Group{Name: "Test", Entries: []gokeepasslib.Entry{makeTestEntry("Title", "db-3")}}
+	return newTestDB(gokeepasslib.Group{Name: "Root", Groups: []gokeepasslib.Group{prod, test}})
+}
+
+func paths(results []Result) string {
+	var p []string
+	for _, r := range results {
+		p = append(p, r.Path)
+	}
+	return strings.Join(p, ",")
+}
+
+func TestFind_Glob(t *testing.T) {
+	f := NewFinder(makePatternTestDB())
+	f.Options.Glob = true
+	tests := []struct{ query, want string }{
+		{"db-*", "/Root/Prod/db-1,/Root/Prod/EU/db-2,/Root/Test/db-3"},
+		{"DB-?", "/Root/Prod/db-1,/Root/Prod/EU/db-2,/Root/Test/db-3"},
+		{"/Root/Prod/*", "/Root/Prod/db-1"},
+		{"/Root/Prod/**/db-*", "/Root/Prod/db-1,/Root/Prod/EU/db-2"},
+		{"/Root/**", "/Root/Prod/db-1,/Root/Prod/EU/db-2,/Root/Prod/EU/Web/web-1,/Root/Test/db-3"},
+		{"EU/**/*-1", "/Root/Prod/EU/Web/web-1"},
+		{"P*/db-[12]", "/Root/Prod/db-1"},
+		{"**/T*/*", "/Root/Test/db-3"},
+		{"/Prod/*", ""},
+		{"db", ""},
+	}
+	for _, tt := range tests {
+		results, err := f.Find(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got := paths(results); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+	f.Options.CaseSensitive = true
+	if results, _ := f.Find("DB-?"); len(results) != 0 {
+		t.Errorf("expected no case-sensitive match, got %s", paths(results))
+	}
+	if _, err := f.Find("db-[1"); err == nil {
+		t.Error("expected error for invalid glob")
+	}
+}
+
+func TestFind_Regex(t *testing.T) {
+	f := NewFinder(makePatternTestDB())
+	f.Options.Regex = true
+	tests := []struct{ query, want string }{
+		{"db-[23]", "/Root/Prod/EU/db-2,/Root/Test/db-3"},
+		{"^DB", "/Root/Prod/db-1,/Root/Prod/EU/db-2,/Root/Test/db-3"},
+		{"Pro/**/-1$", "/Root/Prod/db-1,/Root/Prod/EU/Web/web-1"},
+		{"/Root/(Prod|Test)/db", "/Root/Prod/db-1,/Root/Test/db-3"},
+	}
+	for _, tt := range tests {
+		results, err := f.Find(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got := paths(results); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+	f.Options.ExactMatch = true
+	if results, _ := f.Find("db"); len(results) != 0 {
+		t.Errorf("expected no exact match, got %s", paths(results))
+	}
+	if results, _ := f.Find("db-\\d"); len(results) != 3 {
+		t.Errorf("expected 3 exact matches, got %s", paths(results))
+	}
+	if _, err := f.Find("db-("); err == nil {
+		t.Error("expected error for invalid regular expression")
+	}
+	f.Options.Glob = true
+	if _, err := f.Find("db"); err == nil {
+		t.Error("expected error for regex and glob")
+	}
+}
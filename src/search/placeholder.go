@@ -0,0 +1,272 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/debug"
+)
+
+// maxPlaceholderDepth is the maximum nesting of placeholders, e.g. a reference to a field,
+// which contains a reference again
+const maxPlaceholderDepth = 10
+
+// placeholderFields maps the field placeholders to the standard fields
+var placeholderFields = map[string]string{
+	"TITLE":    "Title",
+	"USERNAME": "UserName",
+	"PASSWORD": "Password",
+	"URL":      "URL",
+	"NOTES":    "Notes",
+}
+
+// refFields maps the field codes of {REF:X@Y:Z} to the standard fields, I is the UUID and O
+// (search only) any other field
+var refFields = map[byte]string{
+	'T': "Title",
+	'U': "UserName",
+	'P': "Password",
+	'A': "URL",
+	'N': "Notes",
+}
+
+// standardFields are the fields, which are not searched by the O code of {REF:X@O:Z}
+var standardFields = map[string]bool{"Title": true, "UserName": true, "Password": true, "URL": true, "Notes": true}
+
+// placeholderResolver resolves the placeholders of the field values of a database.
+type placeholderResolver struct {
+	db *gokeepasslib.Database
+	// active are the fields being resolved, "<entry address>/<field>", to detect cycles
+	active map[string]bool
+}
+
+// ResolvePlaceholders replaces the KeePass placeholders in all field values of the entry:
+// {TITLE}, {USERNAME}, {PASSWORD}, {URL}, {NOTES}, {S:Field} for a custom field and
+// {REF:X@Y:Z} for the field X (T, U, P, A, N or I for the UUID) of the first entry, whose
+// field Y (T, U, P, A, N, I or O for any other field) contains Z. Placeholders are case-insensitive,
+// missing fields are replaced by an empty string, unknown placeholders and references to missing
+// entries are kept unchanged.
+// The values of the entry are copied before they are changed, the database stays unchanged.
+//
+// Parameters:
+//   - db: The database of the referenced entries.
+//   - entry: The entry, usually a copy of the entry in the database.
+//
+// Returns:
+//   - error: An error if the references form a cycle or are nested deeper than 10 levels.
+func ResolvePlaceholders(db *gokeepasslib.Database, entry *gokeepasslib.Entry) error {
+	values := make([]gokeepasslib.ValueData, len(entry.Values))
+	copy(values, entry.Values)
+	r := &placeholderResolver{db: db, active: map[string]bool{}}
+	for i := range values {
+		if !strings.Contains(values[i].Value.Content, "{") {
+			continue
+		}
+		resolved, err := r.field(entry, values[i].Key, 0)
+		if err != nil {
+			return err
+		}
+		values[i].Value.Content = resolved
+	}
+	entry.Values = values
+	return nil
+}
+
+// field returns the resolved value of a field of the entry.
+//
+// Parameters:
+//   - entry: The entry.
+//   - name: The field name, case-insensitive.
+//   - depth: The nesting depth of the reference.
+//
+// Returns:
+//   - string: The resolved value, empty if the field does not exist.
+//   - error: An error if the references form a cycle or are nested too deep.
+func (r *placeholderResolver) field(entry *gokeepasslib.Entry, name string, depth int) (string, error) {
+	var value *gokeepasslib.ValueData
+	for i := range entry.Values {
+		if strings.EqualFold(entry.Values[i].Key, name) {
+			value = &entry.Values[i]
+			break
+		}
+	}
+	if value == nil {
+		return "", nil
+	}
+	if !strings.Contains(value.Value.Content, "{") {
+		return value.Value.Content, nil
+	}
+	if depth > maxPlaceholderDepth {
+		return "", fmt.Errorf("placeholders of %s nested deeper than %d levels", value.Key, maxPlaceholderDepth)
+	}
+	key := fmt.Sprintf("%p/%s", entry, value.Key)
+	if r.active[key] {
+		return "", fmt.Errorf("placeholder cycle in field %s of %s", value.Key, entry.GetTitle())
+	}
+	r.active[key] = true
+	defer delete(r.active, key)
+	return r.resolve(entry, value.Value.Content, depth)
+}
+
+// resolve replaces the placeholders in the text.
+//
+// Parameters:
+//   - entry: The entry, which the field placeholders refer to.
+//   - text: The text with placeholders.
+//   - depth: The nesting depth of the text.
+//
+// Returns:
+//   - string: The resolved text.
+//   - error: An error if the references form a cycle or are nested too deep.
+func (r *placeholderResolver) resolve(entry *gokeepasslib.Entry, text string, depth int) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(text[:start])
+		value, ok, err := r.placeholder(entry, text[start+1:end], depth)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			b.WriteString(value)
+			text = text[end+1:]
+		} else {
+			// keep the unknown placeholder, a "{" in it may start another one
+			b.WriteByte('{')
+			text = text[start+1:]
+		}
+	}
+	b.WriteString(text)
+	return b.String(), nil
+}
+
+// placeholder returns the value of a placeholder.
+//
+// Parameters:
+//   - entry: The entry, which the field placeholders refer to.
+//   - name: The placeholder without braces, e.g. "USERNAME", "S:Token" or "REF:P@I:46C9B1FF...".
+//   - depth: The nesting depth of the text.
+//
+// Returns:
+//   - string: The value.
+//   - bool: False if the placeholder is unknown or its reference can not be resolved.
+//   - error: An error if the references form a cycle or are nested too deep.
+func (r *placeholderResolver) placeholder(entry *gokeepasslib.Entry, name string, depth int) (string, bool, error) {
+	upper := strings.ToUpper(name)
+	if field, ok := placeholderFields[upper]; ok {
+		value, err := r.field(entry, field, depth+1)
+		return value, err == nil, err
+	}
+	if strings.HasPrefix(upper, "S:") {
+		value, err := r.field(entry, name[2:], depth+1)
+		return value, err == nil, err
+	}
+	if !strings.HasPrefix(upper, "REF:") {
+		return "", false, nil
+	}
+	// REF:X@Y:Z
+	ref := name[4:]
+	if len(ref) < 4 || ref[1] != '@' || ref[3] != ':' {
+		return "", false, nil
+	}
+	wanted, searchIn, text := upper[4], upper[6], ref[4:]
+	target := r.findReference(searchIn, text)
+	if target == nil {
+		debug.Log("Reference {%s} not found", name)
+		return "", false, nil
+	}
+	if wanted == 'I' {
+		return strings.ToUpper(FormatUUID(target.UUID)), true, nil
+	}
+	field, ok := refFields[wanted]
+	if !ok {
+		return "", false, nil
+	}
+	value, err := r.field(target, field, depth+1)
+	return value, err == nil, err
+}
+
+// findReference returns the first entry of the database, whose field contains the text
+// (case-insensitive), or, for the field code I, whose UUID is the text.
+//
+// Parameters:
+//   - searchIn: The field code T, U, P, A, N, I or O.
+//   - text: The text to search for.
+//
+// Returns:
+//   - *gokeepasslib.Entry: The entry in the database, nil if no entry matches.
+func (r *placeholderResolver) findReference(searchIn byte, text string) *gokeepasslib.Entry {
+	if r.db == nil || r.db.Content == nil || r.db.Content.Root == nil {
+		return nil
+	}
+	var uuid gokeepasslib.UUID
+	if searchIn == 'I' {
+		var err error
+		if uuid, err = ParseUUID(text); err != nil {
+			return nil
+		}
+	} else if _, ok := refFields[searchIn]; !ok && searchIn != 'O' {
+		return nil
+	}
+	lower := strings.ToLower(text)
+	matches := func(entry *gokeepasslib.Entry) bool {
+		if searchIn == 'I' {
+			return entry.UUID.Compare(uuid)
+		}
+		for _, v := range entry.Values {
+			if (searchIn == 'O' && !standardFields[v.Key]) || v.Key == refFields[searchIn] {
+				if strings.Contains(strings.ToLower(v.Value.Content), lower) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	var walk func(group *gokeepasslib.Group) *gokeepasslib.Entry
+	walk = func(group *gokeepasslib.Group) *gokeepasslib.Entry {
+		for i := range group.Entries {
+			if matches(&group.Entries[i]) {
+				return &group.Entries[i]
+			}
+		}
+		for i := range group.Groups {
+			if entry := walk(&group.Groups[i]); entry != nil {
+				return entry
+			}
+		}
+		return nil
+	}
+	for i := range r.db.Content.Root.Groups {
+		if entry := walk(&r.db.Content.Root.Groups[i]); entry != nil {
+			return entry
+		}
+	}
+	return nil
+}
+
+// resolve resolves the placeholders of the entry of a result, unless the Raw option is set.
+//
+// Parameters:
+//   - result: The result, its entry is changed in place.
+//
+// Returns:
+//   - error: An error if the placeholders can not be resolved.
+func (f *Finder) resolve(result *Result) error {
+	if f.Options.Raw || result.Entry == nil {
+		return nil
+	}
+	if err := ResolvePlaceholders(f.db, result.Entry); err != nil {
+		return fmt.Errorf("placeholders of %s: %w", result.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// makePlaceholderTestDB returns a database with the entries /Root/Base and /Root/Linked, whose fields
+// refer to Base and to its own fields.
+func makePlaceholderTestDB() (*gokeepasslib.Database, gokeepasslib.UUID) {
+	base := makeTestEntry("Title", "Base", "UserName", "admin", "Password", "basepw", "URL", "https://base.example.com",
+		"Notes", "base notes", "Token", "t0k3n")
+	id := strings.ToUpper(FormatUUID(base.UUID))
+	linked := makeTestEntry("Title", "Linked",
+		"UserName", "{REF:U@I:"+id+"}",
+		"Password", "{REF:P@I:"+id+"}",
+		"URL", "{ref:a@t:bas}/login?user={USERNAME}",
+		"Notes", "{TITLE}: {S:Token} {S:missing}| {REF:N@O:t0k3n} {REF:I@U:admin}",
+		"Token", "{REF:x@T:Base} {UNKNOWN} {REF:P@I:00000000000000000000000000000000} {{TITLE}} {",
+	)
+	root := gokeepasslib.Group{Name: "Root", Entries: []gokeepasslib.Entry{base, linked}}
+	return newTestDB(root), base.UUID
+}
+
+func TestResolvePlaceholders(t *testing.T) {
+	db, id := makePlaceholderTestDB()
+	linked := db.Content.Root.Groups[0].Entries[1]
+	if err := ResolvePlaceholders(db, &linked); err != nil {
+		t.Fatal(err)
+	}
+	result := Result{Entry: &linked}
+	token := "{REF:x@T:Base} {UNKNOWN} {REF:P@I:00000000000000000000000000000000} {Linked} {"
+	want := map[string]string{
+		"UserName": "admin",
+		"Password": "basepw",
+		"URL":      "https://base.example.com/login?user=admin",
+		"Notes":    "Linked: " + token + " | base notes " + strings.ToUpper(FormatUUID(id)),
+		"Token":    token,
+	}
+	for field, value := range want {
+		if got, _ := result.GetField(field); got != value {
+			t.Errorf("%s: got %q, want %q", field, got, value)
+		}
+	}
+	// the database is unchanged
+	if got := db.Content.Root.Groups[0].Entries[1].Values[2].Value.Content; !strings.HasPrefix(got, "{REF:P@I:") {
+		t.Errorf("database changed: %q", got)
+	}
+}
+
+func TestResolvePlaceholders_Cycle(t *testing.T) {
+	db, _ := makePlaceholderTestDB()
+	group := &db.Content.Root.Groups[0]
+	id := strings.ToUpper(FormatUUID(group.Entries[0].UUID))
+	group.Entries[0].Values[2].Value.Content = "{REF:P@I:" + FormatUUID(group.Entries[1].UUID) + "}"
+	group.Entries[1].Values[2].Value.Content = "{REF:P@I:" + id + "}"
+	linked := group.Entries[1]
+	if err := ResolvePlaceholders(db, &linked); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+	self := gokeepasslib.Entry{Values: []gokeepasslib.ValueData{{Key: "Notes", Value: gokeepasslib.V{Content: "{NOTES}"}}}}
+	if err := ResolvePlaceholders(db, &self); err == nil {
+		t.Error("expected cycle error for {NOTES} in Notes")
+	}
+}
+
+func TestResolvePlaceholders_Depth(t *testing.T) {
+	var values []gokeepasslib.ValueData
+	for i := 0; i <= maxPlaceholderDepth+1; i++ {
+		values = append(values, gokeepasslib.ValueData{Key: "F" + string(rune('a'+i)), Value: gokeepasslib.V{Content: "{S:F" + string(rune('a'+i+1)) + "}"}})
+	}
+	entry := gokeepasslib.Entry{Values: values}
+	if err := ResolvePlaceholders(nil, &entry); err == nil || !strings.Contains(err.Error(), "deeper") {
+		t.Errorf("expected depth error, got %v", err)
+	}
+	entry = gokeepasslib.Entry{Values: values[len(values)-5:]}
+	if err := ResolvePlaceholders(nil, &entry); err != nil {
+		t.Errorf("expected success for 5 levels, got %v", err)
+	}
+}
+
+func TestFind_Placeholders(t *testing.T) {
+	db, _ := makePlaceholderTestDB()
+	f := NewFinder(db)
+	results, err := f.Find("Linked")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("got %v, %v", results, err)
+	}
+	if got, _ := results[0].GetField("Password"); got != "basepw" {
+		t.Errorf("got %q, want resolved password", got)
+	}
+	f.Options.Where = []string{"UserName=admin"}
+	if results, _ := f.Find(""); len(results) != 2 {
+		t.Errorf("expected conditions on resolved values, got %v", results)
+	}
+	f.Options.Raw = true
+	results, _ = f.Find("Linked")
+	if len(results) != 0 {
+		t.Errorf("expected no raw match for UserName=admin, got %v", results)
+	}
+	f.Options.Where = nil
+	results, _ = f.Find("Linked")
+	if got, _ := results[0].GetField("Password"); !strings.HasPrefix(got, "{REF:P@I:") {
+		t.Errorf("got %q, want raw password", got)
+	}
+}
+
+func TestFind_PlaceholdersBrokenBystander(t *testing.T) {
+	db, _ := makePlaceholderTestDB()
+	root := &db.Content.Root.Groups[0]
+	root.Entries = append(root.Entries, makeTestEntry("Title", "Bad", "UserName", "admin-bad", "Password", "{PASSWORD}"))
+	f := NewFinder(db)
+
+	f.Options.Where = []string{"UserName=admin"}
+	results, err := f.Find("")
+	if err != nil || paths(results) != "/Root/Base,/Root/Linked" {
+		t.Errorf("expected the broken entry to be skipped, got %v, %v", results, err)
+	}
+	f.Options.Where = []string{"UserName~admin"}
+	if results, err := f.Find(""); err != nil || len(results) != 2 {
+		t.Errorf("expected the broken entry not to match, got %v, %v", results, err)
+	}
+	f.Options.Where = nil
+	if results, err := f.Find("Base"); err != nil || len(results) != 1 {
+		t.Errorf("expected the broken entry not to fail other searches, got %v, %v", results, err)
+	}
+	if _, err := f.Find("Bad"); err == nil || !strings.Contains(err.Error(), "placeholder cycle") {
+		t.Errorf("expected cycle error for the selected entry, got %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package search
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+
+	"kpasscli/src/debug"
+)
+
+// ErrNoTotp is returned if the entry has no TOTP secret.
+var ErrNoTotp = errors.New("entry has no TOTP secret")
+
+// Defaults of RFC 6238, used if the entry does not specify the parameter
+const (
+	defaultTotpDigits = 6
+	defaultTotpPeriod = 30
+)
+
+// Fields of the TOTP settings of KeePassXC before it used otpauth URIs
+const (
+	totpSeedField     = "TOTP Seed"
+	totpSettingsField = "TOTP Settings"
+)
+
+// Fields of the native TOTP settings of KeePass 2.x
+const (
+	timeOtpSecretField    = "TimeOtp-Secret"
+	timeOtpLengthField    = "TimeOtp-Length"
+	timeOtpPeriodField    = "TimeOtp-Period"
+	timeOtpAlgorithmField = "TimeOtp-Algorithm"
+)
+
+// now and sleep are replaced by the tests
+var (
+	now   = time.Now
+	sleep = time.Sleep
+)
+
+// base32NoPadding decodes the secrets, which are stored without padding
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TotpConfig are the parameters of the TOTP token of an entry.
+type TotpConfig struct {
+	// Secret is the shared secret, base32 encoded without padding
+	Secret string
+	// Digits is the length of the token
+	Digits int
+	// Period is the number of seconds a token is valid
+	Period uint
+	// Algorithm is the hash function of the HMAC
+	Algorithm otp.Algorithm
+	// Issuer is the provider of the account, empty if unknown
+	Issuer string
+}
+
+// TotpConfig returns the TOTP parameters of the entry. They are read from the first source found:
+//   - the field fieldName with an otpauth:// URI (secret, digits, period, algorithm, issuer) or a base32 secret,
+//   - the KeePassXC fields "TOTP Seed" and "TOTP Settings" ("period;digits"),
+//   - the KeePass 2.x fields TimeOtp-Secret (-Hex, -Base32, -Base64), TimeOtp-Length, TimeOtp-Period
+//     and TimeOtp-Algorithm.
+//
+// Parameters:
+//   - fieldName: The name of the field with the otpauth URI, usually "otp".
+//
+// Returns:
+//   - *TotpConfig: The TOTP parameters, missing ones are set to the defaults 6 digits, 30 seconds and SHA1.
+//   - error: ErrNoTotp if the entry has none of the fields, or an error if a parameter is invalid.
+func (r *Result) TotpConfig(fieldName string) (*TotpConfig, error) {
+	var config *TotpConfig
+	var err error
+	if value, ferr := r.GetField(fieldName); ferr == nil {
+		config, err = parseOtpField(value)
+	} else if seed, ferr := r.GetField(totpSeedField); ferr == nil {
+		settings, _ := r.GetField(totpSettingsField)
+		config, err = parseTotpSeed(seed, settings)
+	} else if config, err = r.timeOtpConfig(); config == nil && err == nil {
+		return nil, fmt.Errorf("TOTP secret field '%s' not found, nor %q or %s fields: %w",
+			fieldName, totpSeedField, timeOtpSecretField, ErrNoTotp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// GetTotpToken returns the current TOTP token of the entry, see TotpConfig for the fields read.
+//
+// Parameters:
+//   - fieldName: The name of the field with the otpauth URI, usually "otp".
+//
+// Returns:
+//   - string: The token.
+//   - error: ErrNoTotp if the entry has no TOTP secret, or an error if the parameters are invalid.
+func (r *Result) GetTotpToken(fieldName string) (string, error) {
+	token, _, err := r.GetTotpTokenValid(fieldName, 0)
+	return token, err
+}
+
+// GetTotpTokenValid returns a TOTP token of the entry, which is valid for at least minValidity,
+// see TotpConfig.ValidCode.
+//
+// Parameters:
+//   - fieldName: The name of the field with the otpauth URI, usually "otp".
+//   - minValidity: The minimum time the token must stay valid, 0 for the current token.
+//
+// Returns:
+//   - string: The token.
+//   - time.Time: The time the token expires.
+//   - error: ErrNoTotp if the entry has no TOTP secret, or an error if the parameters are invalid.
+func (r *Result) GetTotpTokenValid(fieldName string, minValidity time.Duration) (string, time.Time, error) {
+	config, err := r.TotpConfig(fieldName)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return config.ValidCode(minValidity)
+}
+
+// ValidCode returns the current TOTP token, or, if it expires in less than minValidity, waits
+// for the next period and returns its token.
+//
+// Parameters:
+//   - minValidity: The minimum time the token must stay valid, 0 for the current token.
+//
+// Returns:
+//   - string: The token.
+//   - time.Time: The time the token expires.
+//   - error: An error if minValidity is not shorter than the period or the secret is invalid.
+func (c *TotpConfig) ValidCode(minValidity time.Duration) (string, time.Time, error) {
+	period := time.Duration(c.Period) * time.Second
+	if minValidity >= period {
+		return "", time.Time{}, fmt.Errorf("TOTP minimum validity %s must be shorter than the period %s", minValidity, period)
+	}
+	t := now()
+	expires := c.Expires(t)
+	if remaining := expires.Sub(t); remaining < minValidity {
+		debug.Log("TOTP token expires in %s, waiting for the next one", remaining)
+		sleep(remaining)
+		t, expires = expires, expires.Add(period)
+	}
+	token, err := c.Code(t)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expires, nil
+}
+
+// Expires returns the end of the period of the TOTP token at the time t.
+//
+// Parameters:
+//   - t: The time.
+//
+// Returns:
+//   - time.Time: The time the token expires.
+func (c *TotpConfig) Expires(t time.Time) time.Time {
+	period := int64(c.Period)
+	return time.Unix((t.Unix()/period+1)*period, 0)
+}
+
+// Code returns the TOTP token at the time t.
+//
+// Parameters:
+//   - t: The time.
+//
+// Returns:
+//   - string: The token.
+//   - error: An error if the secret is invalid.
+func (c *TotpConfig) Code(t time.Time) (string, error) {
+	token, err := totp.GenerateCodeCustom(c.Secret, t, totp.ValidateOpts{
+		Period:    c.Period,
+		Digits:    otp.Digits(c.Digits),
+		Algorithm: c.Algorithm,
+	})
+	if err != nil {
+		return "", fmt.Errorf("generating TOTP token: %w", err)
+	}
+	return token, nil
+}
+
+// validate checks the parameters, the secret is normalized to upper case base32 without padding.
+//
+// Returns:
+//   - error: An error if the secret is empty or no valid base32, or the digits or period are out of range.
+func (c *TotpConfig) validate() error {
+	c.Secret = strings.TrimRight(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, c.Secret), "=")
+	if c.Secret == "" {
+		return fmt.Errorf("TOTP secret is empty")
+	}
+	if _, err := base32NoPadding.DecodeString(c.Secret); err != nil {
+		return fmt.Errorf("TOTP secret is not valid base32")
+	}
+	if c.Digits < 6 || c.Digits > 10 {
+		return fmt.Errorf("TOTP digits must be between 6 and 10, got %d", c.Digits)
+	}
+	if c.Period == 0 {
+		return fmt.Errorf("TOTP period must be positive")
+	}
+	return nil
+}
+
+// parseOtpField parses the otp field of KeePassXC, an otpauth URI or a plain base32 secret.
+//
+// Parameters:
+//   - value: The value of the field.
+//
+// Returns:
+//   - *TotpConfig: The TOTP parameters.
+//   - error: An error if the URI is invalid or uses an unsupported type, algorithm or encoder.
+func parseOtpField(value string) (*TotpConfig, error) {
+	value = strings.TrimSpace(value)
+	config := &TotpConfig{Secret: value, Digits: defaultTotpDigits, Period: defaultTotpPeriod, Algorithm: otp.AlgorithmSHA1}
+	if !strings.HasPrefix(strings.ToLower(value), "otpauth://") {
+		return config, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		// the error of url.Parse contains the URI with the secret
+		return nil, fmt.Errorf("invalid otpauth URI")
+	}
+	if !strings.EqualFold(u.Host, "totp") {
+		return nil, fmt.Errorf("unsupported OTP type %q, only totp is supported", u.Host)
+	}
+	q := u.Query()
+	config.Secret = q.Get("secret")
+	if config.Secret == "" {
+		return nil, fmt.Errorf("no 'secret' parameter found in otpauth URI")
+	}
+	if digits := q.Get("digits"); digits != "" {
+		if config.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, fmt.Errorf("invalid TOTP digits %q", digits)
+		}
+	}
+	if period := q.Get("period"); period != "" {
+		if config.Period, err = parsePeriod(period); err != nil {
+			return nil, err
+		}
+	}
+	if algorithm := q.Get("algorithm"); algorithm != "" {
+		if config.Algorithm, err = parseAlgorithm(algorithm); err != nil {
+			return nil, err
+		}
+	}
+	if encoder := q.Get("encoder"); encoder != "" {
+		return nil, fmt.Errorf("unsupported TOTP encoder %q", encoder)
+	}
+	config.Issuer = q.Get("issuer")
+	if label := strings.TrimPrefix(u.Path, "/"); config.Issuer == "" && strings.Contains(label, ":") {
+		config.Issuer = strings.TrimSpace(label[:strings.Index(label, ":")])
+	}
+	return config, nil
+}
+
+// parseTotpSeed parses the TOTP fields of KeePassXC before version 2.6.
+//
+// Parameters:
+//   - seed: The value of the "TOTP Seed" field, the base32 secret.
+//   - settings: The value of the "TOTP Settings" field, "period;digits", empty for the defaults.
+//
+// Returns:
+//   - *TotpConfig: The TOTP parameters.
+//   - error: An error if the settings are invalid or use the Steam encoder.
+func parseTotpSeed(seed, settings string) (*TotpConfig, error) {
+	config := &TotpConfig{Secret: seed, Digits: defaultTotpDigits, Period: defaultTotpPeriod, Algorithm: otp.AlgorithmSHA1}
+	settings = strings.TrimSpace(settings)
+	if settings == "" {
+		return config, nil
+	}
+	parts := strings.Split(settings, ";")
+	var err error
+	if config.Period, err = parsePeriod(parts[0]); err != nil {
+		return nil, err
+	}
+	if len(parts) > 1 {
+		digits := strings.TrimSpace(parts[1])
+		if strings.EqualFold(digits, "S") {
+			return nil, fmt.Errorf("unsupported TOTP encoder Steam")
+		}
+		if config.Digits, err = strconv.Atoi(digits); err != nil {
+			return nil, fmt.Errorf("invalid TOTP digits %q", digits)
+		}
+	}
+	return config, nil
+}
+
+// timeOtpConfig reads the native TOTP fields of KeePass 2.x.
+//
+// Returns:
+//   - *TotpConfig: The TOTP parameters, nil if the entry has no TimeOtp-Secret field.
+//   - error: An error if a field is invalid.
+func (r *Result) timeOtpConfig() (*TotpConfig, error) {
+	config := &TotpConfig{Digits: defaultTotpDigits, Period: defaultTotpPeriod, Algorithm: otp.AlgorithmSHA1}
+	var secret []byte
+	if value, err := r.GetField(timeOtpSecretField); err == nil {
+		secret = []byte(value)
+	} else if value, err := r.GetField(timeOtpSecretField + "-Hex"); err == nil {
+		if secret, err = hex.DecodeString(strings.Join(strings.Fields(value), "")); err != nil {
+			return nil, fmt.Errorf("%s-Hex is not valid hex", timeOtpSecretField)
+		}
+	} else if value, err := r.GetField(timeOtpSecretField + "-Base32"); err == nil {
+		// validated with the other parameters
+		config.Secret = value
+	} else if value, err := r.GetField(timeOtpSecretField + "-Base64"); err == nil {
+		if secret, err = base64.StdEncoding.DecodeString(strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("%s-Base64 is not valid base64", timeOtpSecretField)
+		}
+	} else {
+		return nil, nil
+	}
+	if secret != nil {
+		config.Secret = base32NoPadding.EncodeToString(secret)
+	}
+	if length, err := r.GetField(timeOtpLengthField); err == nil && strings.TrimSpace(length) != "" {
+		if config.Digits, err = strconv.Atoi(strings.TrimSpace(length)); err != nil {
+			return nil, fmt.Errorf("invalid %s %q", timeOtpLengthField, length)
+		}
+	}
+	if period, err := r.GetField(timeOtpPeriodField); err == nil && strings.TrimSpace(period) != "" {
+		if config.Period, err = parsePeriod(period); err != nil {
+			return nil, err
+		}
+	}
+	if algorithm, err := r.GetField(timeOtpAlgorithmField); err == nil && strings.TrimSpace(algorithm) != "" {
+		if config.Algorithm, err = parseAlgorithm(algorithm); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// parsePeriod parses the TOTP period in seconds.
+//
+// Parameters:
+//   - value: The period.
+//
+// Returns:
+//   - uint: The period.
+//   - error: An error if the period is no positive number.
+func parsePeriod(value string) (uint, error) {
+	period, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+	if err != nil || period == 0 {
+		return 0, fmt.Errorf("invalid TOTP period %q", value)
+	}
+	return uint(period), nil
+}
+
+// parseAlgorithm parses the hash algorithm of the HMAC, as in otpauth URIs (SHA1, SHA256, SHA512)
+// or in KeePass 2.x (HMAC-SHA-1, HMAC-SHA-256, HMAC-SHA-512).
+//
+// Parameters:
+//   - value: The algorithm, case-insensitive.
+//
+// Returns:
+//   - otp.Algorithm: The algorithm.
+//   - error: An error if the algorithm is not supported.
+func parseAlgorithm(value string) (otp.Algorithm, error) {
+	name := strings.ReplaceAll(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "HMAC-"), "-", "")
+	switch name {
+	case "SHA1":
+		return otp.AlgorithmSHA1, nil
+	case "SHA256":
+		return otp.AlgorithmSHA256, nil
+	case "SHA512":
+		return otp.AlgorithmSHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported TOTP algorithm %q", value)
+}
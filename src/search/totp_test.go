@@ -0,0 +1,115 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// RFC 6238 test secrets of SHA1, SHA256 and SHA512
+const (
+	rfcSecret1   = "12345678901234567890"
+	rfcSecret256 = "12345678901234567890123456789012"
+	rfcSecret512 = "1234567890123456789012345678901234567890123456789012345678901234"
+)
+
+func totpResult(fields map[string]string) *Result {
+	entry := &gokeepasslib.Entry{}
+	for k, v := range fields {
+		entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: k, Value: gokeepasslib.V{Content: v}})
+	}
+	return &Result{Path: "/Root/Entry", Entry: entry}
+}
+
+func TestTotpConfig_Sources(t *testing.T) {
+	b32 := func(s string) string { return base32NoPadding.EncodeToString([]byte(s)) }
+	at := time.Unix(59, 0)
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+		issuer string
+	}{
+		{"plain secret", map[string]string{"otp": b32(rfcSecret1)}, "287082", ""},
+		{"otpauth defaults", map[string]string{"otp": "otpauth://totp/ACME:bob?secret=" + b32(rfcSecret1)}, "287082", "ACME"},
+		{"otpauth sha256", map[string]string{"otp": "otpauth://totp/bob?secret=" + b32(rfcSecret256) + "&digits=8&algorithm=SHA256&issuer=Bank"}, "46119246", "Bank"},
+		{"otpauth sha512", map[string]string{"otp": "otpauth://totp/bob?secret=" + strings.ToLower(b32(rfcSecret512)) + "&digits=8&algorithm=sha512"}, "90693936", ""},
+		{"otpauth period", map[string]string{"otp": "otpauth://totp/bob?secret=" + b32(rfcSecret1) + "&digits=8&period=60"}, "84755224", ""},
+		{"keepassxc seed", map[string]string{"TOTP Seed": b32(rfcSecret1), "TOTP Settings": "30;8"}, "94287082", ""},
+		{"keepassxc seed defaults", map[string]string{"TOTP Seed": b32(rfcSecret1)}, "287082", ""},
+		{"keepass utf8", map[string]string{"TimeOtp-Secret": rfcSecret256, "TimeOtp-Length": "8", "TimeOtp-Algorithm": "HMAC-SHA-256"}, "46119246", ""},
+		{"keepass hex", map[string]string{"TimeOtp-Secret-Hex": hex.EncodeToString([]byte(rfcSecret512)), "TimeOtp-Length": "8", "TimeOtp-Algorithm": "HMAC-SHA-512"}, "90693936", ""},
+		{"keepass base32", map[string]string{"TimeOtp-Secret-Base32": b32(rfcSecret1), "TimeOtp-Length": "8"}, "94287082", ""},
+		{"keepass base64", map[string]string{"TimeOtp-Secret-Base64": base64.StdEncoding.EncodeToString([]byte(rfcSecret1)), "TimeOtp-Length": "8", "TimeOtp-Period": "60"}, "84755224", ""},
+		{"otp field first", map[string]string{"otp": b32(rfcSecret1), "TimeOtp-Secret": "other"}, "287082", ""},
+	}
+	for _, tt := range tests {
+		config, err := totpResult(tt.fields).TotpConfig("otp")
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got, err := config.Code(at)
+		if err != nil || got != tt.want || config.Issuer != tt.issuer {
+			t.Errorf("%s: got %s, issuer %q, %v, want %s, issuer %q", tt.name, got, config.Issuer, err, tt.want, tt.issuer)
+		}
+	}
+}
+
+func TestTotpConfig_Errors(t *testing.T) {
+	secret := base32NoPadding.EncodeToString([]byte(rfcSecret1))
+	if _, err := totpResult(map[string]string{"Password": "x"}).TotpConfig("otp"); !errors.Is(err, ErrNoTotp) ||
+		!strings.Contains(err.Error(), "TOTP secret field 'otp' not found") {
+		t.Errorf("expected ErrNoTotp, got %v", err)
+	}
+	for _, fields := range []map[string]string{
+		{"otp": "otpauth://hotp/bob?secret=" + secret + "&counter=1"},
+		{"otp": "otpauth://totp/bob?digits=6"},
+		{"otp": "otpauth://totp/bob?secret=" + secret + "&algorithm=MD5"},
+		{"otp": "otpauth://totp/bob?secret=" + secret + "&digits=4"},
+		{"otp": "otpauth://totp/bob?secret=" + secret + "&period=0"},
+		{"otp": "otpauth://totp/bob?secret=" + secret + "&encoder=steam"},
+		{"otp": "INVALID_BASE32!"},
+		{"TOTP Seed": secret, "TOTP Settings": "30;S"},
+		{"TimeOtp-Secret-Hex": "xyz"},
+		{"TimeOtp-Secret": rfcSecret1, "TimeOtp-Algorithm": "HMAC-MD5"},
+	} {
+		if _, err := totpResult(fields).TotpConfig("otp"); err == nil || errors.Is(err, ErrNoTotp) {
+			t.Errorf("%v: expected error, got %v", fields, err)
+		} else if strings.Contains(err.Error(), secret) {
+			t.Errorf("%v: error contains the secret: %v", fields, err)
+		}
+	}
+}
+
+func TestTotpConfig_ValidCode(t *testing.T) {
+	defer func(n func() time.Time, s func(time.Duration)) { now, sleep = n, s }(now, sleep)
+	var slept time.Duration
+	sleep = func(d time.Duration) { slept += d }
+	config, err := totpResult(map[string]string{"TOTP Seed": base32NoPadding.EncodeToString([]byte(rfcSecret1)), "TOTP Settings": "30;8"}).TotpConfig("otp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 seconds left, enough
+	now = func() time.Time { return time.Unix(57, 0) }
+	token, expires, err := config.ValidCode(3 * time.Second)
+	if err != nil || token != "94287082" || expires.Unix() != 60 || slept != 0 {
+		t.Errorf("got %s, %v, slept %s, %v", token, expires, slept, err)
+	}
+	// 1 second left, the token of the next period is returned
+	now = func() time.Time { return time.Unix(59, 0) }
+	token, expires, err = config.ValidCode(3 * time.Second)
+	want, _ := config.Code(time.Unix(60, 0))
+	if err != nil || token != want || token == "94287082" || expires.Unix() != 90 || slept != time.Second {
+		t.Errorf("got %s (want %s), %v, slept %s, %v", token, want, expires, slept, err)
+	}
+	if _, _, err := config.ValidCode(30 * time.Second); err == nil {
+		t.Error("expected error for minimum validity of the whole period")
+	}
+}
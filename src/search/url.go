@@ -0,0 +1,175 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/debug"
+)
+
+// defaultPorts are the ports used, if a URL of the scheme has no port
+var defaultPorts = map[string]string{"http": "80", "https": "443", "ssh": "22", "git": "9418"}
+
+// ParseURL parses a URL of an entry or a credential request. A URL without scheme,
+// e.g. "github.com/org", is parsed as host with path and an empty scheme.
+//
+// Parameters:
+//   - raw: The URL.
+//
+// Returns:
+//   - *url.URL: The parsed URL, the host is lower case.
+//   - error: An error if the URL is invalid or has no host.
+func ParseURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("URL %q has no host", raw)
+	}
+	u.Host = strings.ToLower(u.Host)
+	return u, nil
+}
+
+// urlPort returns the port of the URL, or the default port of its scheme.
+//
+// Parameters:
+//   - u: The URL.
+//
+// Returns:
+//   - string: The port, empty if the URL has neither a port nor a known scheme.
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	return defaultPorts[u.Scheme]
+}
+
+// pathSegments splits the path of a URL into its segments, a trailing ".git" is ignored.
+//
+// Parameters:
+//   - p: The path.
+//
+// Returns:
+//   - []string: The segments without empty ones.
+func pathSegments(p string) []string {
+	var segments []string
+	for _, s := range strings.Split(strings.TrimSuffix(path.Clean("/"+p), ".git"), "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// MatchURL reports how well the URL of an entry matches the target URL.
+// The hosts must be equal, the schemes and ports if both URLs specify them, and the path of the
+// entry URL must be a prefix of the target path (segment by segment). More specific entry URLs,
+// with more path segments or a scheme, get a higher score.
+//
+// Parameters:
+//   - entryURL: The URL of the entry.
+//   - target: The target URL.
+//
+// Returns:
+//   - int: The score of the match, 0 if the URLs do not match.
+func MatchURL(entryURL, target *url.URL) int {
+	if entryURL.Hostname() != target.Hostname() {
+		return 0
+	}
+	score := 1
+	if entryURL.Scheme != "" && target.Scheme != "" {
+		if entryURL.Scheme != target.Scheme {
+			return 0
+		}
+		score++
+	}
+	if entryPort, targetPort := urlPort(entryURL), urlPort(target); entryPort != "" && targetPort != "" && entryPort != targetPort {
+		return 0
+	}
+	entryPath, targetPath := pathSegments(entryURL.Path), pathSegments(target.Path)
+	if len(entryPath) > len(targetPath) {
+		return 0
+	}
+	for i, segment := range entryPath {
+		if segment != targetPath[i] {
+			return 0
+		}
+	}
+	return score + 10*len(entryPath)
+}
+
+// FindByURL returns the entries, whose URL field matches the target URL best (see MatchURL).
+// Entries in the recycle bin are ignored. If userName is not empty, only entries with this
+// user name are considered. The placeholders are resolved unless the Raw option is set.
+//
+// Parameters:
+//   - target: The target URL, e.g. "https://github.com/org/repo.git" or "registry.example.com".
+//   - userName: The user name of the entries, empty for any.
+//
+// Returns:
+//   - []Result: The best matching entries, more than one if they match equally well.
+//   - error: An error if the target URL is invalid.
+func (f *Finder) FindByURL(target, userName string) ([]Result, error) {
+	targetURL, err := ParseURL(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	debug.Log("Searching by URL: %s", targetURL)
+
+	meta := f.db.Content.Meta
+	skipRecycleBin := meta != nil && meta.RecycleBinEnabled.Bool
+	var results []Result
+	best := 0
+	var walk func(group *gokeepasslib.Group, groupPath string)
+	walk = func(group *gokeepasslib.Group, groupPath string) {
+		if skipRecycleBin && meta.RecycleBinUUID.Compare(group.UUID) {
+			return
+		}
+		groupPath += "/" + group.Name
+		for i := range group.Entries {
+			entry := group.Entries[i]
+			result := NewResult(groupPath+"/"+entry.GetTitle(), &entry)
+			if err := f.resolve(&result); err != nil {
+				debug.Log("Skipping %s: %v", result.Path, err)
+				continue
+			}
+			if userName != "" {
+				if name, err := result.GetField("UserName"); err != nil || name != userName {
+					continue
+				}
+			}
+			raw, err := result.GetField("URL")
+			if err != nil || strings.TrimSpace(raw) == "" {
+				continue
+			}
+			entryURL, err := ParseURL(raw)
+			if err != nil {
+				continue
+			}
+			score := MatchURL(entryURL, targetURL)
+			if score == 0 || score < best {
+				continue
+			}
+			if score > best {
+				best, results = score, nil
+			}
+			results = append(results, result)
+		}
+		for i := range group.Groups {
+			walk(&group.Groups[i], groupPath)
+		}
+	}
+	for i := range f.db.Content.Root.Groups {
+		walk(&f.db.Content.Root.Groups[i], "")
+	}
+	return results, nil
+}
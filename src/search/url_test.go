@@ -0,0 +1,104 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+func TestParseURL(t *testing.T) {
+	u, err := ParseURL("GitHub.com/org")
+	if err != nil || u.Scheme != "" || u.Host != "github.com" || u.Path != "/org" {
+		t.Errorf("unexpected URL %#v (err: %v)", u, err)
+	}
+	if _, err := ParseURL("https://"); err == nil {
+		t.Error("expected error for URL without host")
+	}
+}
+
+func TestMatchURL(t *testing.T) {
+	tests := []struct {
+		entry, target string
+		want          int
+	}{
+		{"github.com", "https://github.com/org/repo.git", 1},
+		{"https://github.com", "https://github.com/org/repo.git", 2},
+		{"https://github.com/org", "https://github.com/org/repo.git", 12},
+		{"https://github.com/org/repo.git", "https://github.com/org/repo", 22},
+		{"https://github.com/other", "https://github.com/org/repo.git", 0},
+		{"https://github.com/org/repo", "https://github.com/org", 0},
+		{"http://github.com", "https://github.com", 0},
+		{"https://gitlab.com", "https://github.com", 0},
+		{"https://git.example.com:8443", "https://git.example.com", 0},
+		{"https://git.example.com:443", "https://git.example.com", 2},
+		{"git.example.com:8443", "https://git.example.com:8443/x", 1},
+	}
+	for _, tt := range tests {
+		entryURL, err := ParseURL(tt.entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target, err := ParseURL(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := MatchURL(entryURL, target); got != tt.want {
+			t.Errorf("MatchURL(%s, %s) = %d, want %d", tt.entry, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestFindByURL(t *testing.T) {
+	bin := gokeepasslib.NewGroup()
+	bin.Name = "Recycle Bin"
+	bin.Entries = []gokeepasslib.Entry{makeTestEntry("Title", "Old", "UserName", "alice", "URL", "https://github.com/org")}
+	root := gokeepasslib.NewGroup()
+	root.Name = "Root"
+	root.Entries = []gokeepasslib.Entry{
+		makeTestEntry("Title", "GitHub", "UserName", "alice", "URL", "https://github.com"),
+		makeTestEntry("Title", "GitHub Bob", "UserName", "bob", "URL", "github.com"),
+		makeTestEntry("Title", "Org", "UserName", "alice", "URL", "https://github.com/org"),
+		makeTestEntry("Title", "NoURL", "UserName", "alice", "URL", ""),
+	}
+	root.Groups = []gokeepasslib.Group{bin}
+	db := newTestDB(root)
+	db.Content.Meta = gokeepasslib.NewMetaData()
+	db.Content.Meta.RecycleBinEnabled = w.NewBoolWrapper(true)
+	db.Content.Meta.RecycleBinUUID = bin.UUID
+	f := NewFinder(db)
+
+	tests := []struct {
+		target, user string
+		want         []string
+	}{
+		{"https://github.com/org/repo.git", "", []string{"/Root/Org"}},
+		{"https://github.com/other/repo.git", "alice", []string{"/Root/GitHub"}},
+		{"https://github.com/other/repo.git", "bob", []string{"/Root/GitHub Bob"}},
+		{"https://github.com/other/repo.git", "", []string{"/Root/GitHub"}},
+		{"github.com", "", []string{"/Root/GitHub", "/Root/GitHub Bob"}},
+		{"https://gitlab.com", "", nil},
+	}
+	for _, tt := range tests {
+		results, err := f.FindByURL(tt.target, tt.user)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.target, err)
+		}
+		var paths []string
+		for _, r := range results {
+			paths = append(paths, r.Path)
+		}
+		if len(paths) != len(tt.want) {
+			t.Errorf("%s %s: got %v, want %v", tt.target, tt.user, paths, tt.want)
+			continue
+		}
+		for i := range paths {
+			if paths[i] != tt.want[i] {
+				t.Errorf("%s %s: got %v, want %v", tt.target, tt.user, paths, tt.want)
+			}
+		}
+	}
+	if _, err := f.FindByURL("://", ""); err == nil {
+		t.Error("expected error for invalid URL")
+	}
+}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	"kpasscli/src/debug"
+)
+
+// UUIDPrefix marks a query, which is the UUID of an entry, e.g. "uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60"
+const UUIDPrefix = "uuid:"
+
+// NewResult returns the result of an entry with its path and UUID.
+//
+// Parameters:
+//   - path: The full path of the entry.
+//   - entry: The entry, usually a copy of the entry in the database.
+//
+// Returns:
+//   - Result: The result.
+func NewResult(path string, entry *gokeepasslib.Entry) Result {
+	return Result{Path: path, Entry: entry, UUID: entry.UUID}
+}
+
+// ParseUUID parses the UUID of an entry, either as 32 hex digits (dashes are ignored, as shown by
+// KeePassXC) or as base64 (as stored in the KeePass XML).
+//
+// Parameters:
+//   - s: The UUID, e.g. "6f1c0e7a-9b2d-4c3e-8f5a-1b2c3d4e5f60" or "bxwOepstTD6PWhssPU5fYA==".
+//
+// Returns:
+//   - gokeepasslib.UUID: The UUID.
+//   - error: An error if s is no UUID.
+func ParseUUID(s string) (gokeepasslib.UUID, error) {
+	var u gokeepasslib.UUID
+	s = strings.TrimSpace(s)
+	if b, err := hex.DecodeString(strings.ReplaceAll(s, "-", "")); err == nil && len(b) == len(u) {
+		copy(u[:], b)
+		return u, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil && len(b) == len(u) {
+			copy(u[:], b)
+			return u, nil
+		}
+	}
+	return u, fmt.Errorf("invalid UUID %q: expected 32 hex digits or base64 of 16 bytes", s)
+}
+
+// FormatUUID formats the UUID as 32 lower case hex digits.
+//
+// Parameters:
+//   - u: The UUID.
+//
+// Returns:
+//   - string: The hex digits.
+func FormatUUID(u gokeepasslib.UUID) string {
+	return hex.EncodeToString(u[:])
+}
+
+// ParseRef parses a reference item[:field] to a field of an entry. The field is separated by the last ":",
+// the item uuid:<UUID> without another ":" has no field.
+//
+// Parameters:
+//   - ref: The reference, e.g. "/Prod/DB:UserName".
+//   - defaultField: The field of a reference without field.
+//
+// Returns:
+//   - string: The item.
+//   - string: The field.
+//   - error: An error if the item or the field after ":" is empty.
+func ParseRef(ref, defaultField string) (string, string, error) {
+	item, field := ref, defaultField
+	// the ":" of uuid:<UUID> is part of the item
+	if id, isUUID := strings.CutPrefix(ref, UUIDPrefix); !isUUID || strings.Contains(id, ":") {
+		if i := strings.LastIndex(ref, ":"); i >= 0 {
+			item, field = ref[:i], ref[i+1:]
+		}
+	}
+	if item == "" || field == "" {
+		return "", "", fmt.Errorf("invalid reference %q, expected item:field", ref)
+	}
+	return item, field, nil
+}
+
+// findByUUID finds the entry with the UUID of the query.
+//
+// Parameters:
+//   - query: The query "uuid:<hex or base64>".
+//
+// Returns:
+//   - []Result: The entry, or no result if no entry has the UUID.
+//   - error: An error if the UUID is invalid.
+func (f *Finder) findByUUID(query string) ([]Result, error) {
+	debug.Log("Searching by UUID: %s", query)
+	u, err := ParseUUID(strings.TrimPrefix(query, UUIDPrefix))
+	if err != nil {
+		return nil, err
+	}
+	all, err := f.allEntries()
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range all {
+		if result.UUID.Compare(u) {
+			return []Result{result}, nil
+		}
+	}
+	return nil, nil
+}
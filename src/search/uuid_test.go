@@ -0,0 +1,83 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+func TestParseUUID(t *testing.T) {
+	want := gokeepasslib.UUID{0x6f, 0x1c, 0x0e, 0x7a, 0x9b, 0x2d, 0x4c, 0x3e, 0x8f, 0x5a, 0x1b, 0x2c, 0x3d, 0x4e, 0x5f, 0x60}
+	for _, s := range []string{
+		"6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60",
+		"6F1C0E7A-9B2D-4C3E-8F5A-1B2C3D4E5F60",
+		"bxwOepstTD6PWhssPU5fYA==",
+		"bxwOepstTD6PWhssPU5fYA",
+	} {
+		got, err := ParseUUID(s)
+		if err != nil || got != want {
+			t.Errorf("%s: got %x, %v", s, got, err)
+		}
+	}
+	for _, s := range []string{"", "6f1c0e7a", "zz1c0e7a9b2d4c3e8f5a1b2c3d4e5f60", "bxwOepst"} {
+		if _, err := ParseUUID(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+	if got := FormatUUID(want); got != "6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60" {
+		t.Errorf("FormatUUID: got %s", got)
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct{ ref, item, field string }{
+		{"DB:UserName", "DB", "UserName"},
+		{"DB", "DB", "Password"},
+		{"/Web/a:b:URL", "/Web/a:b", "URL"},
+		{"https://host/x:URL", "https://host/x", "URL"},
+		{"uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60", "uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60", "Password"},
+		{"uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60:UserName", "uuid:6f1c0e7a9b2d4c3e8f5a1b2c3d4e5f60", "UserName"},
+	}
+	for _, tt := range tests {
+		if item, field, err := ParseRef(tt.ref, "Password"); err != nil || item != tt.item || field != tt.field {
+			t.Errorf("ParseRef(%q) = %q, %q, %v, want %q, %q", tt.ref, item, field, err, tt.item, tt.field)
+		}
+	}
+	for _, ref := range []string{"", ":Password", "DB:"} {
+		if _, _, err := ParseRef(ref, "Password"); err == nil {
+			t.Errorf("ParseRef(%q): expected error", ref)
+		}
+	}
+}
+
+func TestFind_UUID(t *testing.T) {
+	db := makePatternTestDB()
+	prod := &db.Content.Root.Groups[0].Groups[0]
+	prod.Groups[0].Entries[0].UUID = gokeepasslib.NewUUID()
+	id := prod.Groups[0].Entries[0].UUID
+
+	f := NewFinder(db)
+	results, err := f.Find("uuid:" + FormatUUID(id))
+	if err != nil || len(results) != 1 || results[0].Path != "/Root/Prod/EU/db-2" || results[0].UUID != id {
+		t.Fatalf("got %v, %v", results, err)
+	}
+	// the UUID is kept when the entry is moved
+	prod.Entries = append(prod.Entries, prod.Groups[0].Entries[0])
+	prod.Groups[0].Entries = nil
+	text, _ := id.MarshalText()
+	results, err = f.Find("uuid:" + string(text))
+	if err != nil || len(results) != 1 || results[0].Path != "/Root/Prod/db-2" {
+		t.Fatalf("got %v, %v", results, err)
+	}
+	// every result has the UUID of its entry
+	results, _ = f.Find("db-2")
+	if len(results) != 1 || results[0].UUID != id {
+		t.Errorf("expected UUID in name search result, got %v", results)
+	}
+	if results, err := f.Find("uuid:" + FormatUUID(gokeepasslib.NewUUID())); err != nil || len(results) != 0 {
+		t.Errorf("expected no result for unknown UUID, got %v, %v", results, err)
+	}
+	if _, err := f.Find("uuid:nope"); err == nil {
+		t.Error("expected error for invalid UUID")
+	}
+}
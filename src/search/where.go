@@ -0,0 +1,173 @@
+package search
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"kpasscli/src/debug"
+)
+
+// fieldCondition is a compiled -where condition on a field of an entry.
+type fieldCondition struct {
+	field    string
+	value    string
+	contains bool
+	m        *nameMatcher
+}
+
+// parseCondition compiles a condition "Field=value", which matches the whole value of the field,
+// or "Field~value", which matches a part of it. The field name is case-insensitive, the value is
+// compared according to CaseSensitive, or is a regular expression or glob with Regex or Glob.
+// With Glob, "Field~value" matches the glob anywhere in the value, like "Field=*value*", and * also
+// matches a "/".
+//
+// Parameters:
+//   - expr: The condition, e.g. "UserName=svc-deploy" or "URL~gitlab".
+//   - opts: The search options.
+//
+// Returns:
+//   - *fieldCondition: The compiled condition.
+//   - error: An error if the condition has no operator or field, or the value is an invalid pattern.
+func parseCondition(expr string, opts SearchOptions) (*fieldCondition, error) {
+	i := strings.IndexAny(expr, "=~")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid condition %q: expected Field=value or Field~value", expr)
+	}
+	c := &fieldCondition{field: strings.TrimSpace(expr[:i]), value: expr[i+1:], contains: expr[i] == '~'}
+	if c.field == "" {
+		return nil, fmt.Errorf("invalid condition %q: missing field name", expr)
+	}
+	if opts.Glob {
+		if _, err := path.Match(c.value, ""); err != nil {
+			return nil, fmt.Errorf("invalid condition %q: invalid glob pattern: %w", expr, err)
+		}
+		// a glob always matches the whole value, its * also matches the / of URLs and paths
+		pattern := globRegexp(c.value)
+		if c.contains {
+			pattern = ".*" + pattern + ".*"
+		}
+		c.value, opts.Glob, opts.Regex = "(?s)"+pattern, false, true
+		c.contains = false
+	}
+	if opts.Regex {
+		opts.ExactMatch = !c.contains
+		m, err := newNameMatcher(c.value, opts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+		}
+		c.m = m
+	} else if !opts.CaseSensitive {
+		c.value = strings.ToLower(c.value)
+	}
+	return c, nil
+}
+
+// globRegexp converts a glob, which is valid for path.Match, to a regular expression.
+// * matches any characters, ? one character and [...] a character class.
+//
+// Parameters:
+//   - glob: The glob.
+//
+// Returns:
+//   - string: The regular expression, not anchored.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			// the class ends at the first unescaped "]", which is not its first character
+			end := i + 1
+			if glob[end] == '^' {
+				end++
+			}
+			for first := true; glob[end] != ']' || first; end++ {
+				if glob[end] == '\\' {
+					end++
+				}
+				first = false
+			}
+			b.WriteString(glob[i : end+1])
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// match reports whether the entry has the field with a matching value.
+//
+// Parameters:
+//   - result: The entry.
+//   - caseSensitive: True to compare a plain value case-sensitive.
+//
+// Returns:
+//   - bool: True if the condition holds, false if it does not or the field is missing.
+func (c *fieldCondition) match(result *Result, caseSensitive bool) bool {
+	value, err := result.GetField(c.field)
+	if err != nil {
+		return false
+	}
+	if c.m != nil {
+		return c.m.match(value)
+	}
+	if !caseSensitive {
+		value = strings.ToLower(value)
+	}
+	if c.contains {
+		return strings.Contains(value, c.value)
+	}
+	return value == c.value
+}
+
+// filterWhere returns the results, which satisfy all -where conditions of the options.
+// The placeholders of each result are resolved before its conditions are checked, a result
+// whose placeholders can not be resolved does not match.
+//
+// Parameters:
+//   - results: The results of the query, their entries are changed in place.
+//
+// Returns:
+//   - []Result: The results matching all conditions, with resolved placeholders.
+//   - error: An error if a condition is invalid.
+func (f *Finder) filterWhere(results []Result) ([]Result, error) {
+	conditions := make([]*fieldCondition, 0, len(f.Options.Where))
+	for _, expr := range f.Options.Where {
+		c, err := parseCondition(expr, f.Options)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	var filtered []Result
+	for i := range results {
+		if err := f.resolve(&results[i]); err != nil {
+			debug.Log("Entry %s does not match the conditions, %v", results[i].Path, err)
+			continue
+		}
+		ok := true
+		for _, c := range conditions {
+			if !c.match(&results[i], f.Options.CaseSensitive) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			filtered = append(filtered, results[i])
+		} else {
+			debug.Log("Entry %s does not match the conditions %v", results[i].Path, f.Options.Where)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// makeWhereTestDB returns a database with the entries /Root/Deploy, /Root/Ops/Deploy and /Root/Ops/Mail.
+func makeWhereTestDB() *gokeepasslib.Database {
+	ops := gokeepasslib.Group{Name: "Ops", Entries: []gokeepasslib.Entry{
+		makeTestEntry("Title", "Deploy", "UserName", "svc-deploy", "URL", "https://gitlab.example.com", "Notes", "staging runner"),
+		makeTestEntry("Title", "Mail", "UserName", "ops", "URL", "https://mail.example.com", "Team", "Ops"),
+	}}
+	root := gokeepasslib.Group{Name: "Root", Groups: []gokeepasslib.Group{ops}, Entries: []gokeepasslib.Entry{
+		makeTestEntry("Title", "Deploy", "UserName", "svc-deploy-old", "URL", "https://github.com"),
+	}}
+	return newTestDB(root)
+}
+
+func TestFind_Where(t *testing.T) {
+	tests := []struct {
+		query string
+		where []string
+		opts  SearchOptions
+		want  string
+	}{
+		{"", []string{"UserName=svc-deploy"}, SearchOptions{}, "/Root/Ops/Deploy"},
+		{"", []string{"username=SVC-DEPLOY"}, SearchOptions{}, "/Root/Ops/Deploy"},
+		{"", []string{"UserName=SVC-DEPLOY"}, SearchOptions{CaseSensitive: true}, ""},
+		{"", []string{"UserName~svc-deploy"}, SearchOptions{}, "/Root/Deploy,/Root/Ops/Deploy"},
+		{"", []string{"URL~gitlab"}, SearchOptions{}, "/Root/Ops/Deploy"},
+		{"", []string{"URL~example.com", "Notes~staging"}, SearchOptions{}, "/Root/Ops/Deploy"},
+		{"", []string{"Team=ops"}, SearchOptions{}, "/Root/Ops/Mail"},
+		{"Deploy", []string{"URL~github"}, SearchOptions{}, "/Root/Deploy"},
+		{"Ops/Deploy", []string{"URL~github"}, SearchOptions{}, ""},
+		{"", []string{"Missing~x"}, SearchOptions{}, ""},
+		{"", []string{"UserName=svc-*"}, SearchOptions{Glob: true}, "/Root/Deploy,/Root/Ops/Deploy"},
+		{"", []string{"URL~gitlab"}, SearchOptions{Glob: true}, "/Root/Ops/Deploy"},
+		{"", []string{"URL=gitlab"}, SearchOptions{Glob: true}, ""},
+		{"", []string{"UserName~deploy-?ld"}, SearchOptions{Glob: true}, "/Root/Deploy"},
+		{"", []string{"URL=git(hub|lab)"}, SearchOptions{Regex: true}, ""},
+		{"", []string{"URL~git(hub|lab)"}, SearchOptions{Regex: true}, "/Root/Deploy,/Root/Ops/Deploy"},
+	}
+	for _, tt := range tests {
+		f := NewFinder(makeWhereTestDB())
+		f.Options = tt.opts
+		f.Options.Where = tt.where
+		results, err := f.Find(tt.query)
+		if err != nil {
+			t.Fatalf("%q %v: %v", tt.query, tt.where, err)
+		}
+		if got := paths(results); got != tt.want {
+			t.Errorf("%q %v: got %q, want %q", tt.query, tt.where, got, tt.want)
+		}
+	}
+}
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob, want string
+	}{
+		{"*gitlab*", ".*gitlab.*"},
+		{"db-?.example.com", `db-.\.example\.com`},
+		{"[^a-c]x[]]", "[^a-c]x[]]"},
+		{`a\*b`, `a\*b`},
+	}
+	for _, tt := range tests {
+		if got := globRegexp(tt.glob); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.glob, got, tt.want)
+		}
+	}
+}
+
+func TestFind_WhereInvalid(t *testing.T) {
+	for _, where := range []string{"UserName", "=value", "URL~db-("} {
+		f := NewFinder(makeWhereTestDB())
+		f.Options.Regex = true
+		f.Options.Where = []string{where}
+		if _, err := f.Find(""); err == nil {
+			t.Errorf("expected error for condition %q", where)
+		}
+	}
+}